@@ -0,0 +1,291 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/option"
+	"github.com/openai/openai-go/v3/responses"
+	"github.com/openai/openai-go/v3/shared"
+)
+
+// BriefingRequest is everything a language model needs to write a briefing.
+// The same instructions and user message are sent to every backend.
+type BriefingRequest struct {
+	Instructions string
+	UserMessage  string
+	Location     Location
+	Schema       *JSONSchema // if set, the answer must be JSON matching it
+}
+
+// BriefingModel is a language model backend that turns a BriefingRequest into briefing text.
+type BriefingModel interface {
+	Name() string
+	Generate(ctx context.Context, req BriefingRequest) (string, error)
+}
+
+// Supported values for the --backend flag.
+const (
+	BackendOpenAI    = "openai"
+	BackendAnthropic = "anthropic"
+	BackendLocal     = "local"
+)
+
+const (
+	defaultAnthropicModel   = "claude-sonnet-4-5"
+	defaultAnthropicBaseURL = "https://api.anthropic.com"
+	defaultLocalModel       = "llama3.1"
+	defaultLocalBaseURL     = "http://localhost:11434/v1"
+)
+
+// NewBriefingModel creates the backend selected by name. An empty model or
+// baseURL selects the backend's default.
+func NewBriefingModel(backend, model, baseURL string) (BriefingModel, error) {
+	switch backend {
+	case BackendOpenAI, "":
+		apiKey := os.Getenv("OPENAI_API_KEY")
+		if apiKey == "" {
+			return nil, fmt.Errorf("OPENAI_API_KEY environment variable not set")
+		}
+		if model == "" {
+			model = openai.ChatModelGPT5
+		}
+		opts := []option.RequestOption{option.WithAPIKey(apiKey)}
+		if baseURL != "" {
+			opts = append(opts, option.WithBaseURL(baseURL))
+		}
+		return &openAIModel{client: openai.NewClient(opts...), model: model}, nil
+
+	case BackendAnthropic:
+		apiKey := os.Getenv("ANTHROPIC_API_KEY")
+		if apiKey == "" {
+			return nil, fmt.Errorf("ANTHROPIC_API_KEY environment variable not set")
+		}
+		if model == "" {
+			model = defaultAnthropicModel
+		}
+		if baseURL == "" {
+			baseURL = defaultAnthropicBaseURL
+		}
+		return &anthropicModel{apiKey: apiKey, model: model, baseURL: strings.TrimRight(baseURL, "/"), client: &http.Client{}}, nil
+
+	case BackendLocal:
+		if model == "" {
+			model = defaultLocalModel
+		}
+		if baseURL == "" {
+			baseURL = defaultLocalBaseURL
+		}
+		// Ollama and llama.cpp ignore the key, but the client insists on one.
+		apiKey := os.Getenv("LOCAL_API_KEY")
+		if apiKey == "" {
+			apiKey = "local"
+		}
+		client := openai.NewClient(option.WithAPIKey(apiKey), option.WithBaseURL(baseURL))
+		return &localModel{client: client, model: model}, nil
+	}
+
+	return nil, fmt.Errorf("unknown backend %q (expected %s, %s or %s)", backend, BackendOpenAI, BackendAnthropic, BackendLocal)
+}
+
+// openAIModel uses the OpenAI Responses API with web search.
+type openAIModel struct {
+	client openai.Client
+	model  string
+}
+
+func (m *openAIModel) Name() string { return "OpenAI " + m.model }
+
+func (m *openAIModel) Generate(ctx context.Context, req BriefingRequest) (string, error) {
+	var text responses.ResponseTextConfigParam
+	if req.Schema != nil {
+		text.Format.OfJSONSchema = &responses.ResponseFormatTextJSONSchemaConfigParam{
+			Name:   req.Schema.Name,
+			Schema: req.Schema.Schema,
+			Strict: openai.Bool(true),
+		}
+	}
+	resp, err := m.client.Responses.New(ctx, responses.ResponseNewParams{
+		Model:        m.model,
+		Instructions: openai.String(req.Instructions),
+		Input: responses.ResponseNewParamsInputUnion{
+			OfString: openai.String(req.UserMessage),
+		},
+		Reasoning: shared.ReasoningParam{
+			Effort: shared.ReasoningEffortMedium,
+		},
+		Text: text,
+		Tools: []responses.ToolUnionParam{
+			{OfWebSearch: &responses.WebSearchToolParam{
+				Type:              responses.WebSearchToolTypeWebSearch,
+				SearchContextSize: responses.WebSearchToolSearchContextSizeHigh,
+				UserLocation: responses.WebSearchToolUserLocationParam{
+					Type:    "approximate",
+					City:    openai.String(req.Location.City),
+					Region:  openai.String(req.Location.Region),
+					Country: openai.String(strings.ToUpper(req.Location.CountryCode)),
+				},
+			}},
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("OpenAI API call failed: %w", err)
+	}
+	return resp.OutputText(), nil
+}
+
+// localModel talks to an OpenAI-compatible chat completions endpoint such as
+// an Ollama or llama.cpp server. Local models have no web search.
+type localModel struct {
+	client openai.Client
+	model  string
+}
+
+func (m *localModel) Name() string { return "local " + m.model }
+
+func (m *localModel) Generate(ctx context.Context, req BriefingRequest) (string, error) {
+	params := openai.ChatCompletionNewParams{
+		Model: m.model,
+		Messages: []openai.ChatCompletionMessageParamUnion{
+			openai.SystemMessage(req.Instructions),
+			openai.UserMessage(req.UserMessage),
+		},
+	}
+	if req.Schema != nil {
+		params.ResponseFormat.OfJSONSchema = &shared.ResponseFormatJSONSchemaParam{
+			JSONSchema: shared.ResponseFormatJSONSchemaJSONSchemaParam{
+				Name:   req.Schema.Name,
+				Schema: req.Schema.Schema,
+				Strict: openai.Bool(true),
+			},
+		}
+	}
+	resp, err := m.client.Chat.Completions.New(ctx, params)
+	if err != nil {
+		return "", fmt.Errorf("local model call failed: %w", err)
+	}
+	if len(resp.Choices) == 0 {
+		return "", fmt.Errorf("local model returned no choices")
+	}
+	return resp.Choices[0].Message.Content, nil
+}
+
+// anthropicModel uses the Anthropic Messages API with the server-side web search tool.
+type anthropicModel struct {
+	apiKey  string
+	model   string
+	baseURL string
+	client  *http.Client
+}
+
+const anthropicMaxTokens = 16000
+
+type anthropicRequest struct {
+	Model     string             `json:"model"`
+	MaxTokens int                `json:"max_tokens"`
+	System    string             `json:"system"`
+	Messages  []anthropicMessage `json:"messages"`
+	Tools     []anthropicTool    `json:"tools,omitempty"`
+}
+
+type anthropicMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type anthropicTool struct {
+	Type         string                 `json:"type"`
+	Name         string                 `json:"name"`
+	MaxUses      int                    `json:"max_uses,omitempty"`
+	UserLocation *anthropicUserLocation `json:"user_location,omitempty"`
+}
+
+type anthropicUserLocation struct {
+	Type    string `json:"type"`
+	City    string `json:"city,omitempty"`
+	Region  string `json:"region,omitempty"`
+	Country string `json:"country,omitempty"`
+}
+
+type anthropicResponse struct {
+	Content []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"content"`
+	StopReason string `json:"stop_reason"`
+}
+
+func (m *anthropicModel) Name() string { return "Anthropic " + m.model }
+
+func (m *anthropicModel) Generate(ctx context.Context, req BriefingRequest) (string, error) {
+	system := req.Instructions
+	if req.Schema != nil {
+		// The request is sent without an output format, so the schema goes into the
+		// instructions and the answer is validated by the caller.
+		schema, err := json.MarshalIndent(req.Schema.Schema, "", "  ")
+		if err != nil {
+			return "", fmt.Errorf("encoding schema: %w", err)
+		}
+		system += "\n\nJSON schema of the answer:\n" + string(schema) + "\n"
+	}
+	body, err := json.Marshal(anthropicRequest{
+		Model:     m.model,
+		MaxTokens: anthropicMaxTokens,
+		System:    system,
+		Messages:  []anthropicMessage{{Role: "user", Content: req.UserMessage}},
+		Tools: []anthropicTool{{
+			Type:    "web_search_20250305",
+			Name:    "web_search",
+			MaxUses: 10,
+			UserLocation: &anthropicUserLocation{
+				Type:    "approximate",
+				City:    req.Location.City,
+				Region:  req.Location.Region,
+				Country: strings.ToUpper(req.Location.CountryCode),
+			},
+		}},
+	})
+	if err != nil {
+		return "", fmt.Errorf("encoding request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", m.baseURL+"/v1/messages", bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("creating request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("x-api-key", m.apiKey)
+	httpReq.Header.Set("anthropic-version", "2023-06-01")
+
+	resp, err := m.client.Do(httpReq)
+	if err != nil {
+		return "", fmt.Errorf("Anthropic API call failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return "", fmt.Errorf("Anthropic API returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+
+	var result anthropicResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("decoding Anthropic response: %w", err)
+	}
+
+	// Web search interleaves tool blocks with text; the briefing is the text blocks joined.
+	var b strings.Builder
+	for _, c := range result.Content {
+		if c.Type == "text" {
+			b.WriteString(c.Text)
+		}
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAnthropicModelGenerate(t *testing.T) {
+	var got anthropicRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/messages" {
+			t.Errorf("path = %q, want /v1/messages", r.URL.Path)
+		}
+		if r.Header.Get("x-api-key") != "test-key" {
+			t.Errorf("x-api-key = %q, want test-key", r.Header.Get("x-api-key"))
+		}
+		json.NewDecoder(r.Body).Decode(&got)
+		w.Write([]byte(`{"content":[{"type":"text","text":"- [[Tagesbriefing]]\n"},{"type":"server_tool_use"},{"type":"text","text":"\t- Wetter"}]}`))
+	}))
+	defer srv.Close()
+
+	t.Setenv("ANTHROPIC_API_KEY", "test-key")
+	model, err := NewBriefingModel(BackendAnthropic, "", srv.URL)
+	if err != nil {
+		t.Fatalf("NewBriefingModel: %v", err)
+	}
+
+	text, err := model.Generate(context.Background(), BriefingRequest{Instructions: "prompt", UserMessage: "weather"})
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	if text != "- [[Tagesbriefing]]\n\t- Wetter" {
+		t.Errorf("Generate = %q", text)
+	}
+	if got.System != "prompt" || len(got.Messages) != 1 || got.Messages[0].Content != "weather" {
+		t.Errorf("request did not carry instructions and user message unchanged: %+v", got)
+	}
+}
+
+func TestLocalModelGenerate(t *testing.T) {
+	var got struct {
+		Model    string `json:"model"`
+		Messages []struct {
+			Role    string `json:"role"`
+			Content string `json:"content"`
+		} `json:"messages"`
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&got)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":"1","object":"chat.completion","model":"llama3.1","choices":[{"index":0,"finish_reason":"stop","message":{"role":"assistant","content":"- [[Tagesbriefing]]"}}]}`))
+	}))
+	defer srv.Close()
+
+	model, err := NewBriefingModel(BackendLocal, "", srv.URL)
+	if err != nil {
+		t.Fatalf("NewBriefingModel: %v", err)
+	}
+
+	text, err := model.Generate(context.Background(), BriefingRequest{Instructions: "prompt", UserMessage: "weather"})
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	if text != "- [[Tagesbriefing]]" {
+		t.Errorf("Generate = %q", text)
+	}
+	if got.Model != defaultLocalModel || len(got.Messages) != 2 || got.Messages[0].Content != "prompt" || got.Messages[1].Content != "weather" {
+		t.Errorf("unexpected request: %+v", got)
+	}
+}
+
+func TestLocalModelStructuredOutput(t *testing.T) {
+	var got struct {
+		ResponseFormat struct {
+			Type       string `json:"type"`
+			JSONSchema struct {
+				Name   string         `json:"name"`
+				Strict bool           `json:"strict"`
+				Schema map[string]any `json:"schema"`
+			} `json:"json_schema"`
+		} `json:"response_format"`
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&got)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":"1","object":"chat.completion","model":"llama3.1","choices":[{"index":0,"finish_reason":"stop","message":{"role":"assistant","content":"{}"}}]}`))
+	}))
+	defer srv.Close()
+
+	model, err := NewBriefingModel(BackendLocal, "", srv.URL)
+	if err != nil {
+		t.Fatalf("NewBriefingModel: %v", err)
+	}
+	if _, err := model.Generate(context.Background(), BriefingRequest{UserMessage: "weather", Schema: &briefingSchema}); err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	rf := got.ResponseFormat
+	if rf.Type != "json_schema" || rf.JSONSchema.Name != "briefing" || !rf.JSONSchema.Strict || rf.JSONSchema.Schema["type"] != "object" {
+		t.Errorf("response_format = %+v", rf)
+	}
+}
+
+func TestNewBriefingModelUnknownBackend(t *testing.T) {
+	if _, err := NewBriefingModel("carrier-pigeon", "", ""); err == nil {
+		t.Error("expected error for unknown backend")
+	}
+}
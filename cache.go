@@ -0,0 +1,245 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Cache sources, each with its own TTL.
+const (
+	SourceForecast = "forecast"
+	SourceMarine   = "marine"
+	SourceEnsemble = "ensemble"
+	SourceGeocode  = "geocode"
+	SourceLLM      = "llm"
+)
+
+// cacheGridDeg is the grid that positions are rounded to for cache keys (about 1 km),
+// so a boat swinging at anchor keeps hitting the same entries.
+const cacheGridDeg = 0.01
+
+// maxStaleAge is how old the last good entry of a source may be to stand in for a
+// failed fetch, unless the source's TTL is longer.
+const maxStaleAge = 24 * time.Hour
+
+var defaultCacheTTLs = map[string]time.Duration{
+	SourceForecast: time.Hour,
+	SourceMarine:   time.Hour,
+	SourceEnsemble: 3 * time.Hour, // ensembles run every 6 or 12 hours
+	SourceGeocode:  30 * 24 * time.Hour,
+	SourceLLM:      6 * time.Hour,
+}
+
+// ResponseCache is a content-addressed on-disk cache for API and model responses.
+// Entries are files named by the SHA-256 of their key and the time bucket of the
+// request; their age is the file's modification time. The last good entry of every
+// key is kept as a fallback for when the network is down.
+type ResponseCache struct {
+	Dir string
+	TTL map[string]time.Duration
+}
+
+// responseCache is used by all fetches; nil disables caching.
+var responseCache *ResponseCache
+
+// NewResponseCache creates a cache below dir with the default TTLs overridden by ttls.
+func NewResponseCache(dir string, ttls map[string]time.Duration) *ResponseCache {
+	merged := make(map[string]time.Duration, len(defaultCacheTTLs))
+	for source, ttl := range defaultCacheTTLs {
+		merged[source] = ttl
+	}
+	for source, ttl := range ttls {
+		merged[source] = ttl
+	}
+	return &ResponseCache{Dir: filepath.Join(dir, "responses"), TTL: merged}
+}
+
+// parseCacheTTLs parses a list like "forecast=30m,llm=12h".
+func parseCacheTTLs(s string) (map[string]time.Duration, error) {
+	ttls := make(map[string]time.Duration)
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		source, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid cache TTL %q (expected source=duration)", part)
+		}
+		source = strings.TrimSpace(source)
+		if _, known := defaultCacheTTLs[source]; !known {
+			return nil, fmt.Errorf("unknown cache source %q", source)
+		}
+		ttl, err := time.ParseDuration(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("cache TTL for %s: %w", source, err)
+		}
+		ttls[source] = ttl
+	}
+	return ttls, nil
+}
+
+// gridKey builds a cache key from a position rounded to the cache grid and
+// any further request parameters.
+func gridKey(lat, lon float64, params ...string) string {
+	key := fmt.Sprintf("%.2f,%.2f", roundToGrid(lat), roundToGrid(lon))
+	if len(params) > 0 {
+		key += "|" + strings.Join(params, "|")
+	}
+	return key
+}
+
+func roundToGrid(v float64) float64 {
+	r := math.Round(v/cacheGridDeg) * cacheGridDeg
+	if r == 0 {
+		return 0 // avoid "-0.00" keys
+	}
+	return r
+}
+
+func (c *ResponseCache) path(source, key string) string {
+	sum := sha256.Sum256([]byte(source + "\n" + key))
+	return filepath.Join(c.Dir, source, hex.EncodeToString(sum[:])+".json")
+}
+
+// Get returns a cached entry and its age. Stale entries are returned too;
+// callers compare the age with Fresh.
+func (c *ResponseCache) Get(source, key string) ([]byte, time.Duration, bool) {
+	p := c.path(source, key)
+	info, err := os.Stat(p)
+	if err != nil {
+		return nil, 0, false
+	}
+	data, err := os.ReadFile(p)
+	if err != nil {
+		return nil, 0, false
+	}
+	return data, time.Since(info.ModTime()), true
+}
+
+// Fresh reports whether an entry of the given age is still within the source's TTL.
+func (c *ResponseCache) Fresh(source string, age time.Duration) bool {
+	return age < c.TTL[source]
+}
+
+// Usable reports whether a stale entry of the given age may stand in for a failed fetch.
+func (c *ResponseCache) Usable(source string, age time.Duration) bool {
+	return age < max(maxStaleAge, c.TTL[source])
+}
+
+// bucketKey adds the time bucket of the source's TTL to a key, so a forecast for
+// 10:00 is not answered with the one cached for 09:00, however fresh that is.
+func (c *ResponseCache) bucketKey(source, key string, now time.Time) string {
+	ttl := c.TTL[source]
+	if ttl <= 0 {
+		return key
+	}
+	return key + "|" + now.UTC().Truncate(ttl).Format(time.RFC3339)
+}
+
+// lastGoodKey is the key of the newest good entry regardless of its time bucket.
+func lastGoodKey(key string) string {
+	return key + "|last"
+}
+
+// Put stores an entry, replacing any previous one atomically.
+func (c *ResponseCache) Put(source, key string, data []byte) error {
+	p := c.path(source, key)
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(p), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), p)
+}
+
+// cachedFetch returns a fresh cache entry of the current time bucket if there is
+// one, otherwise calls fetch and stores its result. If fetch fails, the last good
+// entry is returned with a warning, unless it is older than maxStaleAge.
+func cachedFetch(source, key string, fetch func() ([]byte, error)) ([]byte, error) {
+	c := responseCache
+	if c == nil {
+		return fetch()
+	}
+
+	bucket := c.bucketKey(source, key, time.Now())
+	if cached, age, ok := c.Get(source, bucket); ok && c.Fresh(source, age) {
+		fmt.Fprintf(os.Stderr, "Using cached %s data (%s old)\n", source, age.Round(time.Second))
+		return cached, nil
+	}
+
+	data, err := fetch()
+	if err != nil {
+		cached, age, ok := c.Get(source, lastGoodKey(key))
+		switch {
+		case !ok:
+			return nil, err
+		case !c.Usable(source, age):
+			return nil, fmt.Errorf("%w (cached %s data is %s old, too stale to use)", err, source, age.Round(time.Minute))
+		}
+		fmt.Fprintf(os.Stderr, "Warning: %s fetch failed (%v), using stale cached data from %s ago\n", source, err, age.Round(time.Minute))
+		return cached, nil
+	}
+
+	for _, k := range []string{bucket, lastGoodKey(key)} {
+		if err := c.Put(source, k, data); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: could not cache %s data: %v\n", source, err)
+			break
+		}
+	}
+	c.prune(source)
+	return data, nil
+}
+
+// prune removes the entries of a source that are too old to be used even as a
+// fallback, such as the entries of past time buckets.
+func (c *ResponseCache) prune(source string) {
+	dir := filepath.Join(c.Dir, source)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	for _, e := range entries {
+		if info, err := e.Info(); err == nil && !c.Usable(source, time.Since(info.ModTime())) {
+			os.Remove(filepath.Join(dir, e.Name()))
+		}
+	}
+}
+
+// cachedModel wraps a BriefingModel so that an identical request (same backend,
+// instructions and user message) is answered from the cache, e.g. on a retry.
+type cachedModel struct {
+	BriefingModel
+}
+
+func (m cachedModel) Generate(ctx context.Context, req BriefingRequest) (string, error) {
+	key := strings.Join([]string{m.Name(), req.Instructions, req.UserMessage}, "\x00")
+	if req.Schema != nil {
+		key += "\x00" + req.Schema.Name
+	}
+	data, err := cachedFetch(SourceLLM, key, func() ([]byte, error) {
+		text, err := m.BriefingModel.Generate(ctx, req)
+		return []byte(text), err
+	})
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
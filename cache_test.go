@@ -0,0 +1,88 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCachedFetch(t *testing.T) {
+	responseCache = NewResponseCache(t.TempDir(), nil)
+	defer func() { responseCache = nil }()
+
+	calls := 0
+	fetch := func() ([]byte, error) {
+		calls++
+		return []byte(`{"ok":true}`), nil
+	}
+
+	for range 2 {
+		data, err := cachedFetch(SourceForecast, gridKey(43.2961, 5.3699), fetch)
+		if err != nil || string(data) != `{"ok":true}` {
+			t.Fatalf("cachedFetch = %q, %v", data, err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("fetch called %d times, want 1", calls)
+	}
+
+	// A position a few hundred metres away shares the grid cell.
+	if _, err := cachedFetch(SourceForecast, gridKey(43.2979, 5.3712), fetch); err != nil || calls != 1 {
+		t.Errorf("nearby position missed the cache (calls=%d, err=%v)", calls, err)
+	}
+
+	// The next hour is a new time bucket: the network is tried again, and its
+	// failure falls back to the last good data.
+	key := gridKey(43.2961, 5.3699)
+	age := func(d time.Duration) {
+		t.Helper()
+		old := time.Now().Add(-d)
+		for _, k := range []string{responseCache.bucketKey(SourceForecast, key, time.Now()), lastGoodKey(key)} {
+			if err := os.Chtimes(responseCache.path(SourceForecast, k), old, old); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if responseCache.bucketKey(SourceForecast, key, time.Now()) == responseCache.bucketKey(SourceForecast, key, time.Now().Add(time.Hour)) {
+		t.Error("the next hour has the same cache key")
+	}
+	age(3 * time.Hour)
+	fail := func() ([]byte, error) {
+		calls++
+		return nil, errors.New("no route to host")
+	}
+	data, err := cachedFetch(SourceForecast, key, fail)
+	if err != nil || string(data) != `{"ok":true}` {
+		t.Errorf("stale fallback = %q, %v", data, err)
+	}
+	if calls != 2 {
+		t.Errorf("fetch called %d times, want 2", calls)
+	}
+
+	// Data older than maxStaleAge is not served.
+	age(maxStaleAge + time.Hour)
+	if data, err := cachedFetch(SourceForecast, key, fail); err == nil || !strings.Contains(err.Error(), "too stale") {
+		t.Errorf("fallback to %s old data = %q, %v", maxStaleAge+time.Hour, data, err)
+	}
+}
+
+func TestParseCacheTTLs(t *testing.T) {
+	ttls, err := parseCacheTTLs("forecast=30m, llm=12h")
+	if err != nil {
+		t.Fatalf("parseCacheTTLs: %v", err)
+	}
+	if ttls[SourceForecast] != 30*time.Minute || ttls[SourceLLM] != 12*time.Hour {
+		t.Errorf("parseCacheTTLs = %v", ttls)
+	}
+	if _, err := parseCacheTTLs("tides=1h"); err == nil {
+		t.Error("expected error for unknown source")
+	}
+}
+
+func TestGridKey(t *testing.T) {
+	if got := gridKey(-0.001, 5.369); got != "0.00,5.37" {
+		t.Errorf("gridKey = %q, want 0.00,5.37", got)
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// loadConfigFile reads a KEY=VALUE config file in the same format as config.env,
+// which is also sourced by generate-briefing.sh. Blank lines and # comments are
+// skipped and surrounding quotes are removed from values.
+func loadConfigFile(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	cfg := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("%s:%d: expected KEY=VALUE", path, lineNo)
+		}
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		cfg[strings.TrimSpace(key)] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// configKey maps a flag name to its config file key, e.g. "base-url" to "BASE_URL".
+func configKey(flagName string) string {
+	return strings.ToUpper(strings.ReplaceAll(flagName, "-", "_"))
+}
+
+// applyConfig sets every flag that was not given on the command line from the
+// matching config key. Command-line flags always win over the config file;
+// keys without a matching flag are ignored.
+func applyConfig(fs *flag.FlagSet, cfg map[string]string) error {
+	explicit := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) {
+		explicit[f.Name] = true
+	})
+
+	var firstErr error
+	fs.VisitAll(func(f *flag.Flag) {
+		if explicit[f.Name] || firstErr != nil {
+			return
+		}
+		value, ok := cfg[configKey(f.Name)]
+		if !ok {
+			return
+		}
+		if err := fs.Set(f.Name, value); err != nil {
+			firstErr = fmt.Errorf("config %s: %w", configKey(f.Name), err)
+		}
+	})
+	return firstErr
+}
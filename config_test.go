@@ -0,0 +1,42 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestApplyConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.env")
+	content := "# comment\nLANG=en\nBACKEND=\"anthropic\"\nBASE_URL='http://localhost:8080'\nCONTEXT_DAYS=10\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := loadConfigFile(path)
+	if err != nil {
+		t.Fatalf("loadConfigFile: %v", err)
+	}
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	lang := fs.String("lang", "de", "")
+	backend := fs.String("backend", "openai", "")
+	baseURL := fs.String("base-url", "", "")
+	if err := fs.Parse([]string{"--lang", "fr"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := applyConfig(fs, cfg); err != nil {
+		t.Fatalf("applyConfig: %v", err)
+	}
+
+	if *lang != "fr" {
+		t.Errorf("lang = %q, want fr (command line wins)", *lang)
+	}
+	if *backend != "anthropic" {
+		t.Errorf("backend = %q, want anthropic", *backend)
+	}
+	if *baseURL != "http://localhost:8080" {
+		t.Errorf("base-url = %q, want http://localhost:8080", *baseURL)
+	}
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Model spreads at which the forecast has low confidence. Half of them is medium
+// confidence.
+const (
+	spreadWindKmh  = 15
+	spreadGustKmh  = 20
+	spreadPrecipMM = 5
+)
+
+// Forecast confidence levels.
+const (
+	ConfidenceHigh   = "high"
+	ConfidenceMedium = "medium"
+	ConfidenceLow    = "low"
+)
+
+// spreadParams are the hourly values compared across models and ensemble members.
+var spreadParams = []string{"wind_speed_10m", "wind_gusts_10m", "precipitation"}
+
+// openMeteoMembersResponse is a forecast with several models or an ensemble. Every
+// model or member has its own series, named after the parameter with a suffix
+// ("wind_speed_10m_icon_seamless", "wind_speed_10m_member01").
+type openMeteoMembersResponse struct {
+	Hourly map[string]json.RawMessage `json:"hourly"`
+}
+
+// members decodes the times and, per parameter, one series for each model or member.
+func (r openMeteoMembersResponse) members() ([]string, map[string][][]*float64, error) {
+	var times []string
+	if err := json.Unmarshal(r.Hourly["time"], &times); err != nil {
+		return nil, nil, fmt.Errorf("decoding times: %w", err)
+	}
+	series := make(map[string][][]*float64)
+	keys := make([]string, 0, len(r.Hourly))
+	for key := range r.Hourly {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	for _, key := range keys {
+		for _, p := range spreadParams {
+			if key != p && !strings.HasPrefix(key, p+"_") {
+				continue
+			}
+			var values []*float64
+			if err := json.Unmarshal(r.Hourly[key], &values); err != nil {
+				return nil, nil, fmt.Errorf("decoding %s: %w", key, err)
+			}
+			series[p] = append(series[p], values)
+		}
+	}
+	return times, series, nil
+}
+
+// fetchSpread fetches the wind, gusts and precipitation of the models and the
+// ensemble selected in opts and computes their spread in every hour. It returns a
+// description of each source alongside.
+func fetchSpread(lat, lon float64, opts WeatherOptions) ([]HourlySpread, []string, error) {
+	query := fmt.Sprintf("&hourly=%s&timezone=%s&forecast_hours=%d&wind_speed_unit=%s",
+		strings.Join(spreadParams, ","), opts.timezone(), opts.forecastHours(), opts.Units.windSpeedParam())
+
+	var responses []openMeteoMembersResponse
+	var sources []string
+	if len(opts.Models) > 0 {
+		q := query + "&models=" + strings.Join(opts.Models, ",")
+		url := fmt.Sprintf("%s/v1/forecast?latitude=%f&longitude=%f", opts.openMeteo().ForecastURL, lat, lon) + q
+		resp, err := fetchJSON[openMeteoMembersResponse](SourceForecast, gridKey(lat, lon, q), url)
+		if err != nil {
+			return nil, nil, fmt.Errorf("fetching models: %w", err)
+		}
+		responses = append(responses, resp)
+		sources = append(sources, fmt.Sprintf("%d models (%s)", len(opts.Models), strings.Join(opts.Models, ", ")))
+	}
+	if opts.Ensemble != "" {
+		q := query + "&models=" + opts.Ensemble
+		url := fmt.Sprintf("%s/v1/ensemble?latitude=%f&longitude=%f", opts.openMeteo().EnsembleURL, lat, lon) + q
+		resp, err := fetchJSON[openMeteoMembersResponse](SourceEnsemble, gridKey(lat, lon, q), url)
+		if err != nil {
+			return nil, nil, fmt.Errorf("fetching ensemble: %w", err)
+		}
+		_, series, err := resp.members()
+		if err != nil {
+			return nil, nil, fmt.Errorf("fetching ensemble: %w", err)
+		}
+		responses = append(responses, resp)
+		sources = append(sources, fmt.Sprintf("%s ensemble (%d members)", opts.Ensemble, len(series[spreadParams[0]])))
+	}
+
+	spread, err := combineSpread(responses)
+	if err != nil {
+		return nil, nil, err
+	}
+	return spread, sources, nil
+}
+
+// combineSpread pools the models and members of all responses and computes the
+// spread of every hour any of them covers.
+func combineSpread(responses []openMeteoMembersResponse) ([]HourlySpread, error) {
+	var times []string
+	values := make(map[string]map[string][]float64) // parameter, time, one value per member
+	for _, p := range spreadParams {
+		values[p] = make(map[string][]float64)
+	}
+	for _, resp := range responses {
+		t, series, err := resp.members()
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range spreadParams {
+			for _, s := range series[p] {
+				for i, ts := range t {
+					if v := safeIndex(s, i); v != nil {
+						values[p][ts] = append(values[p][ts], *v)
+					}
+				}
+			}
+		}
+		times = append(times, t...)
+	}
+	slices.Sort(times)
+	times = slices.Compact(times)
+
+	spread := make([]HourlySpread, 0, len(times))
+	for _, t := range times {
+		spread = append(spread, HourlySpread{
+			Time:          t,
+			Wind:          spreadOf(values["wind_speed_10m"][t]),
+			Gusts:         spreadOf(values["wind_gusts_10m"][t]),
+			Precipitation: spreadOf(values["precipitation"][t]),
+		})
+	}
+	return spread, nil
+}
+
+// spreadOf summarises the values of the members, or returns nil if fewer than two
+// reported the hour: a single model cannot disagree with itself.
+func spreadOf(values []float64) *Spread {
+	if len(values) < 2 {
+		return nil
+	}
+	s := Spread{Min: slices.Min(values), Max: slices.Max(values), Members: len(values)}
+	for _, v := range values {
+		s.Mean += v / float64(len(values))
+	}
+	return &s
+}
+
+// Range is the difference between the highest and the lowest member.
+func (s *Spread) Range() float64 {
+	if s == nil {
+		return 0
+	}
+	return s.Max - s.Min
+}
+
+// windRange is the larger of the wind and the gust spread, nil if neither is known.
+func (s HourlySpread) windRange() *float64 {
+	if s.Wind == nil && s.Gusts == nil {
+		return nil
+	}
+	return ptr(max(s.Wind.Range(), s.Gusts.Range()))
+}
+
+// Confidence rates how well the models agree in this hour. u is the unit of the
+// wind speeds.
+func (s HourlySpread) Confidence(u UnitSystem) string {
+	ratio := max(
+		s.Wind.Range()/u.FromKmh(spreadWindKmh),
+		s.Gusts.Range()/u.FromKmh(spreadGustKmh),
+		s.Precipitation.Range()/spreadPrecipMM,
+	)
+	switch {
+	case ratio >= 1:
+		return ConfidenceLow
+	case ratio >= 0.5:
+		return ConfidenceMedium
+	}
+	return ConfidenceHigh
+}
+
+// FormatForecastConfidence renders the hours of each confidence level as ranges and
+// the largest model spread in the hours of low confidence. It is empty unless models
+// or an ensemble were requested.
+func FormatForecastConfidence(w WeatherData) string {
+	if len(w.Spread) == 0 {
+		return ""
+	}
+	u := w.Units
+	hours := make(map[string][]string)
+	var wind, gusts, precip float64 // largest spreads in the hours of low confidence
+	for _, s := range w.Spread {
+		c := s.Confidence(u)
+		hours[c] = append(hours[c], s.Time)
+		if c == ConfidenceLow {
+			wind, gusts, precip = max(wind, s.Wind.Range()), max(gusts, s.Gusts.Range()), max(precip, s.Precipitation.Range())
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString("\n=== FORECAST CONFIDENCE ===\n")
+	b.WriteString(fmt.Sprintf("Compared: %s\n", strings.Join(w.SpreadSources, "; ")))
+	for _, c := range []string{ConfidenceHigh, ConfidenceMedium, ConfidenceLow} {
+		if len(hours[c]) > 0 {
+			b.WriteString(fmt.Sprintf("Confidence %s: %s\n", c, formatHourRanges(hours[c])))
+		}
+	}
+	if len(hours[ConfidenceLow]) == 0 {
+		b.WriteString("The models agree well; the forecast is reliable.\n")
+	} else {
+		b.WriteString(fmt.Sprintf("Low confidence, the models disagree: %s, by up to %.0f %s in wind, %.0f %s in gusts and %.1f mm in precipitation. Plan for the worse models in these hours.\n",
+			formatHourRanges(hours[ConfidenceLow]), wind, u.WindUnit(), gusts, u.WindUnit(), precip))
+	}
+	return b.String()
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// Three models and a two-member ensemble; ICON has no gusts for the second hour.
+const (
+	modelsJSON = `{"hourly": {
+		"time": ["2026-03-15T12:00", "2026-03-15T13:00"],
+		"wind_speed_10m_icon_seamless": [12, 14],
+		"wind_speed_10m_ecmwf_ifs025": [14, 30],
+		"wind_speed_10m_gfs_seamless": [13, 18],
+		"wind_gusts_10m_icon_seamless": [20, null],
+		"wind_gusts_10m_ecmwf_ifs025": [22, 50],
+		"wind_gusts_10m_gfs_seamless": [21, 28],
+		"precipitation_icon_seamless": [0, 0.2],
+		"precipitation_ecmwf_ifs025": [0, 1.4],
+		"precipitation_gfs_seamless": [0.1, 0]
+	}}`
+	ensembleJSON = `{"hourly": {
+		"time": ["2026-03-15T12:00", "2026-03-15T13:00"],
+		"wind_speed_10m": [13, 20],
+		"wind_speed_10m_member01": [12, 24],
+		"wind_gusts_10m": [21, 30],
+		"wind_gusts_10m_member01": [20, 36],
+		"precipitation": [0, 0.5],
+		"precipitation_member01": [0, 0.8]
+	}}`
+)
+
+func decodeMembers(t *testing.T, body string) openMeteoMembersResponse {
+	t.Helper()
+	var resp openMeteoMembersResponse
+	if err := json.Unmarshal([]byte(body), &resp); err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+func TestCombineSpread(t *testing.T) {
+	got, err := combineSpread([]openMeteoMembersResponse{decodeMembers(t, modelsJSON), decodeMembers(t, ensembleJSON)})
+	if err != nil {
+		t.Fatalf("combineSpread: %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("combineSpread returned %d hours, want 2: %+v", len(got), got)
+	}
+
+	calm, windy := got[0], got[1]
+	if calm.Wind == nil || *calm.Wind != (Spread{Min: 12, Max: 14, Mean: 12.8, Members: 5}) {
+		t.Errorf("12:00 wind spread = %+v", calm.Wind)
+	}
+	if windy.Gusts == nil || windy.Gusts.Members != 4 || windy.Gusts.Range() != 22 {
+		t.Errorf("13:00 gust spread = %+v, want 4 members 28–50", windy.Gusts)
+	}
+	if c := calm.Confidence(UnitsMetric); c != ConfidenceHigh {
+		t.Errorf("12:00 confidence = %s, want high", c)
+	}
+	if c := windy.Confidence(UnitsMetric); c != ConfidenceLow {
+		t.Errorf("13:00 confidence = %s, want low", c)
+	}
+
+	// 4.5 km/h between the models is medium confidence in knots as well.
+	medium := HourlySpread{Wind: &Spread{Min: 5, Max: 9.5}}
+	if c := medium.Confidence(UnitsNautical); c != ConfidenceMedium {
+		t.Errorf("4.5 kn spread confidence = %s, want medium", c)
+	}
+}
+
+func TestFormatForecastConfidence(t *testing.T) {
+	if got := FormatForecastConfidence(WeatherData{}); got != "" {
+		t.Errorf("confidence section without a model comparison: %q", got)
+	}
+
+	spread, err := combineSpread([]openMeteoMembersResponse{decodeMembers(t, modelsJSON)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := WeatherData{Units: UnitsMetric, Spread: spread, SpreadSources: []string{"3 models (icon_seamless, ecmwf_ifs025, gfs_seamless)"}}
+	got := FormatForecastConfidence(w)
+	for _, want := range []string{
+		"=== FORECAST CONFIDENCE ===\nCompared: 3 models (icon_seamless, ecmwf_ifs025, gfs_seamless)\n",
+		"Confidence high: 2026-03-15T12:00\n",
+		"Confidence low: 2026-03-15T13:00\n",
+		"Low confidence, the models disagree: 2026-03-15T13:00, by up to 16 km/h in wind, 22 km/h in gusts and 1.4 mm in precipitation.",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("FormatForecastConfidence missing %q in:\n%s", want, got)
+		}
+	}
+
+	hazards := EvaluateHazards(w, DefaultHazardThresholds)
+	want := Hazard{Start: "2026-03-15T13:00", End: "2026-03-15T13:00", Parameter: HazardModelSpread, Value: 22, Unit: "km/h", Severity: SeverityWarning}
+	if len(hazards) != 1 || hazards[0] != want {
+		t.Fatalf("EvaluateHazards = %+v, want %+v", hazards, want)
+	}
+	if got := FormatHazards(hazards, UnitsMetric); !strings.Contains(got, "[warning] 2026-03-15T13:00: forecast models disagree, wind or gusts up to 22 km/h apart (low confidence)") {
+		t.Errorf("FormatHazards = %q", got)
+	}
+
+	// A 16 km/h wind spread is below the 20 km/h for gusts but still low confidence:
+	// the hazard and the confidence section name the same hours, as ranges.
+	w.Spread = nil
+	for i := range 6 {
+		s := HourlySpread{Time: fmt.Sprintf("2026-03-15T%02d:00", 8+i), Wind: &Spread{Min: 10, Max: 12}}
+		if i >= 2 && i <= 4 {
+			s.Wind.Max = 26
+		}
+		w.Spread = append(w.Spread, s)
+	}
+	got = FormatForecastConfidence(w)
+	if want := "Confidence high: 2026-03-15T08:00–09:00, 2026-03-15T13:00\nConfidence low: 2026-03-15T10:00–12:00\n"; !strings.Contains(got, want) {
+		t.Errorf("FormatForecastConfidence = %q, want %q", got, want)
+	}
+	hazards = EvaluateHazards(w, DefaultHazardThresholds)
+	if len(hazards) != 1 || hazards[0].Start != "2026-03-15T10:00" || hazards[0].End != "2026-03-15T12:00" || hazards[0].Value != 16 {
+		t.Errorf("EvaluateHazards = %+v, want 10:00–12:00 with a 16 km/h spread", hazards)
+	}
+}
@@ -7,60 +7,124 @@ import (
 	"strings"
 	"time"
 
-	"github.com/openai/openai-go/v3"
-	"github.com/openai/openai-go/v3/option"
-	"github.com/openai/openai-go/v3/responses"
-	"github.com/openai/openai-go/v3/shared"
+	"sailingnomads-briefing/logseq"
 )
 
-// GenerateBriefing calls OpenAI with weather data, location, and context to produce a daily briefing.
-func GenerateBriefing(loc Location, weather WeatherData, stdinContext, promptText, lang string) (string, error) {
-	apiKey := os.Getenv("OPENAI_API_KEY")
-	if apiKey == "" {
-		return "", fmt.Errorf("OPENAI_API_KEY environment variable not set")
-	}
+// BriefingInput is the data a briefing is written from.
+type BriefingInput struct {
+	Location       Location
+	Weather        WeatherData
+	Hazards        []Hazard
+	Thresholds     HazardThresholds // the limits the hazards were computed with
+	Vessel         VesselProfile
+	Sailing        []SailingDay
+	Passage        *Passage             // planned route, nil for a day at anchor
+	Onboard        *OnboardObservations // instrument data, nil without an NMEA source
+	JournalContext string               // recent journal entries, piped in on stdin
+	Lang           string
+}
 
-	client := openai.NewClient(option.WithAPIKey(apiKey))
+// GenerateBriefing asks the language model for a daily briefing based on weather data, location, and context.
+// A briefing that is not a well-formed Logseq outline is sent back to the model once for
+// correction, and repaired if the corrected answer is still not valid.
+// The computed hazards and sailing windows are always added as blocks below the header,
+// even if the model leaves them out.
+func GenerateBriefing(model BriefingModel, in BriefingInput, promptText string) (string, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
 	defer cancel()
 
-	userMessage := buildUserMessage(loc, weather, stdinContext, lang)
+	userMessage := buildUserMessage(in)
 
 	fmt.Fprintf(os.Stderr, "User Message:\n%s", userMessage)
 
-	resp, err := client.Responses.New(ctx, responses.ResponseNewParams{
-		Model:        openai.ChatModelGPT5,
-		Instructions: openai.String(promptText),
-		Input: responses.ResponseNewParamsInputUnion{
-			OfString: openai.String(userMessage),
-		},
-		Reasoning: shared.ReasoningParam{
-			Effort: shared.ReasoningEffortMedium,
-		},
-		Tools: []responses.ToolUnionParam{
-			{OfWebSearch: &responses.WebSearchToolParam{
-				Type:              responses.WebSearchToolTypeWebSearch,
-				SearchContextSize: responses.WebSearchToolSearchContextSizeHigh,
-				UserLocation: responses.WebSearchToolUserLocationParam{
-					Type:    "approximate",
-					City:    openai.String(loc.City),
-					Region:  openai.String(loc.Region),
-					Country: openai.String(strings.ToUpper(loc.CountryCode)),
-				},
-			}},
-		},
-	})
+	req := BriefingRequest{
+		Instructions: promptText,
+		UserMessage:  userMessage,
+		Location:     in.Location,
+	}
+	text, err := model.Generate(ctx, req)
+	if err != nil {
+		return "", err
+	}
+
+	fmt.Fprintf(os.Stderr, "%s Response:\n%s", model.Name(), text)
+
+	if problems := logseq.ValidateBriefing(text); len(problems) > 0 {
+		text = correctBriefing(ctx, model, req, text, problems, briefingHeader(in))
+	}
+
+	return AddComputedBlocks(text, in), nil
+}
+
+// correctBriefing asks the model once to fix the problems found in a briefing. If the
+// corrected answer is still not valid, or the model fails, the briefing is repaired
+// without it.
+func correctBriefing(ctx context.Context, model BriefingModel, req BriefingRequest, text string, problems []logseq.Problem, h logseq.Header) string {
+	fmt.Fprintf(os.Stderr, "Briefing is not valid Logseq, asking for a correction:\n%s", formatProblems(problems))
+
+	req.UserMessage += correctionMessage(text, problems)
+	corrected, err := model.Generate(ctx, req)
 	if err != nil {
-		return "", fmt.Errorf("OpenAI API call failed: %w", err)
+		fmt.Fprintf(os.Stderr, "Warning: correction failed, repairing the briefing: %v\n", err)
+		return logseq.RepairBriefing(text, h)
+	}
+
+	fmt.Fprintf(os.Stderr, "%s Corrected response:\n%s", model.Name(), corrected)
+
+	problems = logseq.ValidateBriefing(corrected)
+	if len(problems) == 0 {
+		return corrected
+	}
+	fmt.Fprintf(os.Stderr, "Warning: corrected briefing is still not valid, repairing it:\n%s", formatProblems(problems))
+	if strings.TrimSpace(corrected) == "" {
+		corrected = text
+	}
+	return logseq.RepairBriefing(corrected, h)
+}
+
+// correctionMessage is appended to the user message to have the model rewrite its answer.
+func correctionMessage(text string, problems []logseq.Problem) string {
+	var b strings.Builder
+	b.WriteString("\n=== CORRECTION ===\n")
+	b.WriteString("Your previous answer below is not a valid Logseq briefing. Write it again with the same content, ")
+	b.WriteString("as blocks indented with tabs only, without text outside of blocks, ")
+	b.WriteString("starting with the " + logseq.BriefingTag + " block and its position:: and location:: properties.\n")
+	b.WriteString("Problems:\n")
+	b.WriteString(formatProblems(problems))
+	b.WriteString("\n=== PREVIOUS ANSWER ===\n")
+	b.WriteString(text)
+	b.WriteString("\n")
+	return b.String()
+}
+
+func formatProblems(problems []logseq.Problem) string {
+	var b strings.Builder
+	for _, p := range problems {
+		b.WriteString("- " + p.String() + "\n")
 	}
+	return b.String()
+}
 
-	fmt.Fprintf(os.Stderr, "OpenAI API Response:\n%s", resp.OutputText())
+// briefingHeader is the header a repaired briefing gets if the model left it out.
+func briefingHeader(in BriefingInput) logseq.Header {
+	lat, lon := in.Location.Latitude, in.Location.Longitude
+	return logseq.Header{
+		Lat:      lat,
+		Lon:      lon,
+		Location: offlineLocationName(lat, lon, Snapshot{Location: in.Location}, in.JournalContext),
+	}
+}
 
-	return resp.OutputText(), nil
+// AddComputedBlocks inserts the computed sailing windows and hazard warnings below
+// the header of a Logseq briefing.
+func AddComputedBlocks(briefing string, in BriefingInput) string {
+	briefing = InsertSailingBlock(briefing, in.Sailing, in.Lang)
+	return InsertHazardBlock(briefing, in.Hazards, in.Weather.Units, in.Lang)
 }
 
-func buildUserMessage(loc Location, weather WeatherData, stdinContext, lang string) string {
+func buildUserMessage(in BriefingInput) string {
 	var b strings.Builder
+	loc := in.Location
 
 	b.WriteString("=== LOCATION ===\n")
 	b.WriteString(fmt.Sprintf("Coordinates: %.5f, %.5f\n", loc.Latitude, loc.Longitude))
@@ -73,14 +137,32 @@ func buildUserMessage(loc Location, weather WeatherData, stdinContext, lang stri
 	}
 	b.WriteString(fmt.Sprintf("Country: %s (%s)\n", loc.Country, strings.ToUpper(loc.CountryCode)))
 	b.WriteString(fmt.Sprintf("Date: %s\n", time.Now().Format("2006-01-02")))
-	b.WriteString(fmt.Sprintf("Language: %s\n", lang))
+	b.WriteString(fmt.Sprintf("Language: %s\n", in.Lang))
+
+	if in.Onboard != nil {
+		b.WriteString("\n")
+		b.WriteString(FormatOnboardObservations(*in.Onboard, in.Weather.Units))
+	}
 
 	b.WriteString("\n")
-	b.WriteString(FormatWeatherData(weather))
+	b.WriteString(FormatWeatherData(in.Weather, in.Thresholds))
+
+	b.WriteString("\n")
+	b.WriteString(FormatHazards(in.Hazards, in.Weather.Units))
+
+	if len(in.Sailing) > 0 {
+		b.WriteString("\n")
+		b.WriteString(FormatSailingAssessment(in.Sailing, in.Vessel))
+	}
+
+	if in.Passage != nil {
+		b.WriteString("\n")
+		b.WriteString(FormatPassage(*in.Passage))
+	}
 
-	if stdinContext != "" {
+	if in.JournalContext != "" {
 		b.WriteString("\n")
-		b.WriteString(stdinContext)
+		b.WriteString(in.JournalContext)
 	}
 
 	return b.String()
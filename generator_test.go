@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+// scriptedModel answers with its replies in turn and records the requests.
+type scriptedModel struct {
+	replies  []string
+	requests []BriefingRequest
+}
+
+func (m *scriptedModel) Name() string { return "scripted" }
+
+func (m *scriptedModel) Generate(_ context.Context, req BriefingRequest) (string, error) {
+	m.requests = append(m.requests, req)
+	reply := m.replies[0]
+	m.replies = m.replies[1:]
+	return reply, nil
+}
+
+func TestGenerateBriefingCorrection(t *testing.T) {
+	in := BriefingInput{Location: Location{Latitude: 43.508, Longitude: 16.44, City: "Split", Country: "Croatia", DisplayName: "Split, Croatia"}}
+	valid := "- [[Tagesbriefing]]\n\t- position:: 43.50800, 16.44000\n\t  location:: Split, Croatia\n\t- Wetter\n"
+
+	// A valid answer is used as is.
+	model := &scriptedModel{replies: []string{valid}}
+	if got, err := GenerateBriefing(model, in, "prompt"); err != nil || got != valid {
+		t.Errorf("valid answer: got %q, %v", got, err)
+	}
+
+	// An invalid answer is sent back once, with the problems and the answer.
+	spaces := "- [[Tagesbriefing]]\n    - position:: 43.50800, 16.44000\n      location:: Split, Croatia\n    - Wetter\n"
+	model = &scriptedModel{replies: []string{spaces, valid}}
+	if got, err := GenerateBriefing(model, in, "prompt"); err != nil || got != valid {
+		t.Errorf("corrected answer: got %q, %v", got, err)
+	}
+	if len(model.requests) != 2 {
+		t.Fatalf("made %d requests, want 2", len(model.requests))
+	}
+	correction := model.requests[1].UserMessage
+	for _, want := range []string{"=== CORRECTION ===", "indented with spaces instead of tabs", spaces} {
+		if !strings.Contains(correction, want) {
+			t.Errorf("correction message lacks %q", want)
+		}
+	}
+
+	// If the correction is not valid either, it is repaired.
+	model = &scriptedModel{replies: []string{spaces, "- Wetter\n"}}
+	want := "- [[Tagesbriefing]]\n\t- position:: 43.50800, 16.44000\n\t  location:: Split, Croatia\n\t- Wetter\n"
+	if got, err := GenerateBriefing(model, in, "prompt"); err != nil || got != want {
+		t.Errorf("repaired answer: got %q, want %q (%v)", got, want, err)
+	}
+}
@@ -3,7 +3,12 @@ package main
 import (
 	"encoding/json"
 	"fmt"
+	"io"
+	"math"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -30,25 +35,15 @@ func ReverseGeocode(lat, lon float64) (Location, error) {
 		lat, lon,
 	)
 
-	client := &http.Client{Timeout: 10 * time.Second}
-	req, err := http.NewRequest("GET", url, nil)
+	data, err := cachedFetch(SourceGeocode, gridKey(lat, lon), func() ([]byte, error) {
+		return nominatimGet(url)
+	})
 	if err != nil {
-		return Location{}, fmt.Errorf("creating request: %w", err)
-	}
-	req.Header.Set("User-Agent", "sailingnomads-briefing/1.0")
-
-	resp, err := client.Do(req)
-	if err != nil {
-		return Location{}, fmt.Errorf("nominatim request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return Location{}, fmt.Errorf("nominatim returned status %d", resp.StatusCode)
+		return Location{}, err
 	}
 
 	var result nominatimResponse
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+	if err := json.Unmarshal(data, &result); err != nil {
 		return Location{}, fmt.Errorf("decoding nominatim response: %w", err)
 	}
 
@@ -78,3 +73,72 @@ func ReverseGeocode(lat, lon float64) (Location, error) {
 		DisplayName: result.DisplayName,
 	}, nil
 }
+
+type nominatimSearchResult struct {
+	Lat         string `json:"lat"`
+	Lon         string `json:"lon"`
+	DisplayName string `json:"display_name"`
+}
+
+// ForwardGeocode resolves a place name such as "Vis, Croatia" to a position via Nominatim.
+func ForwardGeocode(query string) (Location, error) {
+	searchURL := "https://nominatim.openstreetmap.org/search?format=json&limit=1&accept-language=en&q=" + url.QueryEscape(query)
+
+	data, err := cachedFetch(SourceGeocode, "search|"+strings.ToLower(strings.TrimSpace(query)), func() ([]byte, error) {
+		return nominatimGet(searchURL)
+	})
+	if err != nil {
+		return Location{}, err
+	}
+
+	var results []nominatimSearchResult
+	if err := json.Unmarshal(data, &results); err != nil {
+		return Location{}, fmt.Errorf("decoding nominatim response: %w", err)
+	}
+	if len(results) == 0 {
+		return Location{}, fmt.Errorf("no place found for %q", query)
+	}
+
+	lat, errLat := strconv.ParseFloat(results[0].Lat, 64)
+	lon, errLon := strconv.ParseFloat(results[0].Lon, 64)
+	if errLat != nil || errLon != nil {
+		return Location{}, fmt.Errorf("invalid coordinates for %q: %s, %s", query, results[0].Lat, results[0].Lon)
+	}
+
+	city, _, _ := strings.Cut(results[0].DisplayName, ",")
+	return Location{Latitude: lat, Longitude: lon, City: city, DisplayName: results[0].DisplayName}, nil
+}
+
+// nominatimGet performs a Nominatim request with the User-Agent its usage policy requires.
+func nominatimGet(url string) ([]byte, error) {
+	client := &http.Client{Timeout: 10 * time.Second}
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("User-Agent", userAgent)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("nominatim request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("nominatim returned status %d", resp.StatusCode)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+const earthRadiusKm = 6371.0
+
+// distanceKm returns the great-circle distance between two positions in kilometres.
+func distanceKm(lat1, lon1, lat2, lon2 float64) float64 {
+	rad := math.Pi / 180
+	dLat := (lat2 - lat1) * rad
+	dLon := (lon2 - lon1) * rad
+
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1*rad)*math.Cos(lat2*rad)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusKm * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
+}
@@ -2,8 +2,9 @@ module sailingnomads-briefing
 
 go 1.25.0
 
+require github.com/openai/openai-go/v3 v3.22.0
+
 require (
-	github.com/openai/openai-go/v3 v3.22.0 // indirect
 	github.com/tidwall/gjson v1.18.0 // indirect
 	github.com/tidwall/match v1.1.1 // indirect
 	github.com/tidwall/pretty v1.2.1 // indirect
@@ -0,0 +1,556 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"slices"
+	"time"
+)
+
+// GRIB2 parameters the briefing reads, keyed by discipline, category and number.
+var gribParameters = map[[3]byte]string{
+	{0, 0, 0}:   "TMP",   // temperature, K
+	{0, 1, 8}:   "APCP",  // total precipitation, kg/m² = mm
+	{0, 2, 2}:   "UGRD",  // u component of wind, m/s
+	{0, 2, 3}:   "VGRD",  // v component of wind, m/s
+	{0, 2, 22}:  "GUST",  // wind gust, m/s
+	{0, 3, 1}:   "PRMSL", // pressure reduced to mean sea level, Pa
+	{10, 0, 3}:  "HTSGW", // significant height of combined wind waves and swell, m
+	{10, 0, 10}: "DIRPW", // primary wave direction, degrees
+	{10, 0, 11}: "PERPW", // primary wave mean period, s
+}
+
+// gribTimeUnits are the units of time of code table 4.4.
+var gribTimeUnits = map[byte]time.Duration{
+	0: time.Minute, 1: time.Hour, 2: 24 * time.Hour,
+	10: 3 * time.Hour, 11: 6 * time.Hour, 12: 12 * time.Hour, 13: time.Second,
+}
+
+// GRIBField is one decoded field of a GRIB2 file on a regular latitude/longitude grid.
+type GRIBField struct {
+	Parameter string        // a value of gribParameters, e.g. UGRD
+	RefTime   time.Time     // start of the model run, UTC
+	ValidTime time.Time     // forecast time; the end of the period for accumulations
+	Period    time.Duration // accumulation period of APCP, 0 for instantaneous values
+	Grid      GRIBGrid
+	Values    []float64 // in scanning order; NaN where the bitmap has no value (land)
+}
+
+// GRIBGrid is a regular latitude/longitude grid (template 3.0).
+type GRIBGrid struct {
+	Ni, Nj     int     // points along a parallel and along a meridian
+	Lat1, Lon1 float64 // first grid point
+	Di, Dj     float64 // increments in degrees, always positive
+	Scan       byte    // scanning mode flags
+}
+
+// ReadGRIB2 decodes the fields the briefing uses from a GRIB2 file. Other parameters
+// and upper levels are skipped; so is text before, between or after the messages, as
+// long as it does not contain "GRIB" itself: every "GRIB" must start a valid GRIB2
+// message.
+func ReadGRIB2(r io.Reader) ([]GRIBField, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	var fields []GRIBField
+	for {
+		i := bytes.Index(data, []byte("GRIB"))
+		if i < 0 {
+			break
+		}
+		data = data[i:]
+		if len(data) < 16 {
+			return nil, errors.New("truncated GRIB message")
+		}
+		if data[7] != 2 {
+			return nil, fmt.Errorf("GRIB edition %d is not supported, only GRIB2", data[7])
+		}
+		n := binary.BigEndian.Uint64(data[8:16])
+		if n < 16 || n > uint64(len(data)) {
+			return nil, errors.New("truncated GRIB message")
+		}
+		msg, err := decodeGRIB2Message(data[:n])
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, msg...)
+		data = data[n:]
+	}
+	if len(fields) == 0 {
+		return nil, errors.New("no wind, pressure, precipitation or wave fields in the GRIB data")
+	}
+	return fields, nil
+}
+
+// decodeGRIB2Message decodes the fields of one message. A message may repeat
+// sections 3 to 7 for several fields.
+func decodeGRIB2Message(msg []byte) ([]GRIBField, error) {
+	discipline := msg[6]
+	var (
+		fields  []GRIBField
+		ref     time.Time
+		grid    GRIBGrid
+		gridErr error
+		field   GRIBField
+		known   bool
+		packing []byte // section 5
+		bitmap  []byte // section 6, nil if every point has a value
+	)
+	for pos := 16; pos+4 <= len(msg); {
+		if string(msg[pos:pos+4]) == "7777" {
+			return fields, nil
+		}
+		if pos+5 > len(msg) {
+			break
+		}
+		n := int(binary.BigEndian.Uint32(msg[pos:]))
+		if n < 5 || pos+n > len(msg) {
+			break
+		}
+		sec := msg[pos : pos+n]
+		pos += n
+
+		switch sec[4] {
+		case 1:
+			if len(sec) < 19 {
+				return nil, errors.New("GRIB identification section too short")
+			}
+			ref = time.Date(int(binary.BigEndian.Uint16(sec[12:])), time.Month(sec[14]), int(sec[15]),
+				int(sec[16]), int(sec[17]), int(sec[18]), 0, time.UTC)
+		case 3:
+			grid, gridErr = decodeGRIBGrid(sec)
+		case 4:
+			field, known = decodeGRIBProduct(sec, discipline, ref)
+		case 5:
+			packing = sec
+		case 6:
+			if len(sec) < 6 {
+				return nil, errors.New("GRIB bitmap section too short")
+			}
+			switch sec[5] {
+			case 0:
+				bitmap = sec[6:]
+			case 255:
+				bitmap = nil
+			} // 254 keeps the previous bitmap
+		case 7:
+			if !known {
+				continue
+			}
+			if gridErr != nil {
+				return nil, fmt.Errorf("%s: %w", field.Parameter, gridErr)
+			}
+			values, err := unpackGRIBSimple(packing, bitmap, sec[5:], grid.Ni*grid.Nj)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", field.Parameter, err)
+			}
+			field.Grid, field.Values = grid, values
+			fields = append(fields, field)
+		}
+	}
+	return nil, errors.New("GRIB message without end section")
+}
+
+// maxGRIBPoints bounds the grid size read from a file, a whole globe at 0.1°.
+const maxGRIBPoints = 3601 * 1800
+
+// decodeGRIBGrid reads a grid definition section of template 3.0.
+func decodeGRIBGrid(sec []byte) (GRIBGrid, error) {
+	if len(sec) < 72 {
+		return GRIBGrid{}, errors.New("GRIB grid section too short")
+	}
+	if t := binary.BigEndian.Uint16(sec[12:]); t != 0 {
+		return GRIBGrid{}, fmt.Errorf("grid template 3.%d is not supported, only regular latitude/longitude (3.0)", t)
+	}
+	// Angles are in millionths of a degree unless the basic angle says otherwise.
+	basic, sub := 1.0, 1e6
+	if b, s := binary.BigEndian.Uint32(sec[38:]), binary.BigEndian.Uint32(sec[42:]); b != 0 && b != math.MaxUint32 && s != 0 && s != math.MaxUint32 {
+		basic, sub = float64(b), float64(s)
+	}
+	angle := func(v int) float64 { return float64(v) * basic / sub }
+	g := GRIBGrid{
+		Ni:   int(binary.BigEndian.Uint32(sec[30:])),
+		Nj:   int(binary.BigEndian.Uint32(sec[34:])),
+		Lat1: angle(gribInt32(sec[46:])),
+		Lon1: angle(gribInt32(sec[50:])),
+		Di:   angle(int(binary.BigEndian.Uint32(sec[63:]))),
+		Dj:   angle(int(binary.BigEndian.Uint32(sec[67:]))),
+		Scan: sec[71],
+	}
+	if points := binary.BigEndian.Uint32(sec[6:]); points > maxGRIBPoints || uint64(g.Ni)*uint64(g.Nj) != uint64(points) {
+		return GRIBGrid{}, fmt.Errorf("GRIB grid of %d×%d does not match its %d points", g.Ni, g.Nj, points)
+	}
+	if g.Scan&0x20 != 0 {
+		return GRIBGrid{}, errors.New("GRIB grids scanned along meridians are not supported")
+	}
+	return g, nil
+}
+
+// decodeGRIBProduct reads a product definition section of template 4.0 or 4.8 and
+// reports whether it is a parameter and level the briefing uses.
+func decodeGRIBProduct(sec []byte, discipline byte, ref time.Time) (GRIBField, bool) {
+	if len(sec) < 34 {
+		return GRIBField{}, false
+	}
+	template := binary.BigEndian.Uint16(sec[7:])
+	name, ok := gribParameters[[3]byte{discipline, sec[9], sec[10]}]
+	if !ok || (template != 0 && template != 8) {
+		return GRIBField{}, false
+	}
+	// Surface, mean sea level, or up to 10 m above ground; no upper air.
+	switch level := sec[22]; level {
+	case 101:
+	case 103:
+		if h := float64(gribInt32(sec[24:])) / math.Pow(10, float64(int8(sec[23]))); h > 10 {
+			return GRIBField{}, false
+		}
+	case 1:
+		if name == "TMP" {
+			return GRIBField{}, false // skin temperature, not the air
+		}
+	default:
+		return GRIBField{}, false
+	}
+	unit, ok := gribTimeUnits[sec[17]]
+	if !ok {
+		return GRIBField{}, false
+	}
+	f := GRIBField{Parameter: name, RefTime: ref}
+	f.ValidTime = ref.Add(time.Duration(gribInt32(sec[18:])) * unit)
+	if template == 8 && len(sec) >= 53 {
+		// The forecast time is the start of the period; the value is valid at its end.
+		if periodUnit, ok := gribTimeUnits[sec[48]]; ok {
+			f.Period = time.Duration(binary.BigEndian.Uint32(sec[49:])) * periodUnit
+			f.ValidTime = f.ValidTime.Add(f.Period)
+		}
+	}
+	return f, true
+}
+
+// unpackGRIBSimple decodes data packed with template 5.0, Y = (R + X·2^E) / 10^D,
+// spreading the values over the points marked in the bitmap.
+func unpackGRIBSimple(packing, bitmap, data []byte, points int) ([]float64, error) {
+	if len(packing) < 21 {
+		return nil, errors.New("missing GRIB data representation section")
+	}
+	if t := binary.BigEndian.Uint16(packing[9:]); t != 0 {
+		return nil, fmt.Errorf("data representation template 5.%d is not supported, only simple packing (5.0); convert the file with wgrib2 -set_grib_type simple", t)
+	}
+	count := int(binary.BigEndian.Uint32(packing[5:]))
+	ref := float64(math.Float32frombits(binary.BigEndian.Uint32(packing[11:])))
+	binScale := math.Pow(2, float64(gribInt16(packing[15:])))
+	decScale := math.Pow(10, float64(gribInt16(packing[17:])))
+	bits := int(packing[19])
+	if count > points || bitmap == nil && count != points {
+		return nil, fmt.Errorf("GRIB field has %d values for %d grid points", count, points)
+	}
+	if bits > 32 || len(data)*8 < count*bits {
+		return nil, errors.New("truncated GRIB data section")
+	}
+
+	packed := make([]float64, count)
+	for i := range packed {
+		var x uint64
+		for b := i * bits; b < (i+1)*bits; b++ {
+			x = x<<1 | uint64(data[b/8]>>(7-b%8)&1)
+		}
+		packed[i] = (ref + float64(x)*binScale) / decScale
+	}
+	if bitmap == nil {
+		return packed, nil
+	}
+	if len(bitmap)*8 < points {
+		return nil, errors.New("truncated GRIB bitmap")
+	}
+	values := make([]float64, points)
+	next := 0
+	for i := range values {
+		if bitmap[i/8]>>(7-i%8)&1 == 0 || next >= len(packed) {
+			values[i] = math.NaN()
+			continue
+		}
+		values[i] = packed[next]
+		next++
+	}
+	return values, nil
+}
+
+// gribInt16 and gribInt32 read the sign-and-magnitude integers of GRIB2.
+func gribInt16(b []byte) int {
+	v := int(binary.BigEndian.Uint16(b) & 0x7fff)
+	if b[0]&0x80 != 0 {
+		return -v
+	}
+	return v
+}
+
+func gribInt32(b []byte) int {
+	v := int(binary.BigEndian.Uint32(b) & 0x7fffffff)
+	if b[0]&0x80 != 0 {
+		return -v
+	}
+	return v
+}
+
+// At interpolates the field bilinearly at a position. Grid points without a value
+// are left out; ok is false outside the grid or if none of the four has a value.
+func (f GRIBField) At(lat, lon float64) (float64, bool) {
+	g := f.Grid
+	if g.Ni < 1 || g.Nj < 1 || g.Di <= 0 || g.Dj <= 0 {
+		return 0, false
+	}
+	di := math.Mod(lon-g.Lon1+720, 360) / g.Di // 0x80: points run westwards
+	if g.Scan&0x80 != 0 {
+		di = math.Mod(g.Lon1-lon+720, 360) / g.Di
+	}
+	dj := (g.Lat1 - lat) / g.Dj // 0x40: points run northwards
+	if g.Scan&0x40 != 0 {
+		dj = (lat - g.Lat1) / g.Dj
+	}
+	const eps = 1e-9
+	if di < -eps || dj < -eps || di > float64(g.Ni-1)+eps || dj > float64(g.Nj-1)+eps {
+		return 0, false
+	}
+
+	i0, j0 := min(int(math.Floor(di+eps)), g.Ni-1), min(int(math.Floor(dj+eps)), g.Nj-1)
+	fi, fj := max(di-float64(i0), 0), max(dj-float64(j0), 0)
+	var sum, weights float64
+	for _, c := range []struct {
+		i, j int
+		w    float64
+	}{
+		{i0, j0, (1 - fi) * (1 - fj)},
+		{i0 + 1, j0, fi * (1 - fj)},
+		{i0, j0 + 1, (1 - fi) * fj},
+		{i0 + 1, j0 + 1, fi * fj},
+	} {
+		if c.w == 0 || c.i >= g.Ni || c.j >= g.Nj {
+			continue
+		}
+		if v := f.Values[c.j*g.Ni+c.i]; !math.IsNaN(v) {
+			sum += v * c.w
+			weights += c.w
+		}
+	}
+	if weights == 0 {
+		return 0, false
+	}
+	return sum / weights, true
+}
+
+// gribPoint is a field's value at the position.
+type gribPoint struct {
+	time   time.Time
+	value  float64
+	period time.Duration
+}
+
+// gribSeries holds the values of every parameter at the position, sorted by time.
+type gribSeries map[string][]gribPoint
+
+// at interpolates a parameter linearly in time. It is nil outside the forecast
+// period of the file or if the parameter is missing.
+func (s gribSeries) at(param string, t time.Time) *float64 {
+	points := s[param]
+	i, found := slices.BinarySearchFunc(points, t, func(p gribPoint, t time.Time) int { return p.time.Compare(t) })
+	switch {
+	case found:
+		return ptr(points[i].value)
+	case i == 0 || i == len(points):
+		return nil
+	}
+	a, b := points[i-1], points[i]
+	f := float64(t.Sub(a.time)) / float64(b.time.Sub(a.time))
+	return ptr(a.value + f*(b.value-a.value))
+}
+
+// precipitation is the precipitation of the hour ending at t, from the shortest
+// accumulation period that contains it.
+func (s gribSeries) precipitation(t time.Time) *float64 {
+	var best *gribPoint
+	for i, p := range s["APCP"] {
+		if p.period <= 0 || p.time.Before(t) || !p.time.Add(-p.period).Before(t) {
+			continue
+		}
+		if best == nil || p.period < best.period {
+			best = &s["APCP"][i]
+		}
+	}
+	if best == nil {
+		return nil
+	}
+	return ptr(max(best.value, 0) * float64(time.Hour) / float64(best.period))
+}
+
+// wind is the wind speed in m/s and the direction it blows from.
+func (s gribSeries) wind(t time.Time) (speed, dir *float64) {
+	u, v := s.at("UGRD", t), s.at("VGRD", t)
+	if u == nil || v == nil {
+		return nil, nil
+	}
+	return ptr(math.Hypot(*u, *v)), ptr(math.Mod(math.Atan2(-*u, -*v)*180/math.Pi+360, 360))
+}
+
+// LoadGRIBWeather reads a GRIB2 file and builds the weather data at a position from
+// it, like FetchWeather does from Open-Meteo: current conditions at now, an hourly
+// forecast interpolated between the time steps of the file, daily summaries and the
+// pressure of the hours before now. Values the file does not have are nil.
+func LoadGRIBWeather(path string, lat, lon float64, now time.Time, opts WeatherOptions) (WeatherData, error) {
+	fields, err := readGRIBFile(path)
+	if err != nil {
+		return WeatherData{}, err
+	}
+	return gribWeather(fields, lat, lon, now, opts)
+}
+
+// readGRIBFile reads and decodes all fields of a GRIB2 file.
+func readGRIBFile(path string) ([]GRIBField, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	fields, err := ReadGRIB2(f)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+	return fields, nil
+}
+
+func gribWeather(fields []GRIBField, lat, lon float64, now time.Time, opts WeatherOptions) (WeatherData, error) {
+	series := make(gribSeries)
+	for _, f := range fields {
+		if v, ok := f.At(lat, lon); ok {
+			series[f.Parameter] = append(series[f.Parameter], gribPoint{time: f.ValidTime, value: v, period: f.Period})
+		}
+	}
+	for param, points := range series {
+		slices.SortStableFunc(points, func(a, b gribPoint) int { return a.time.Compare(b.time) })
+		// A field in several messages (e.g. of two runs) keeps the first one read.
+		series[param] = slices.CompactFunc(points, func(a, b gribPoint) bool { return a.time.Equal(b.time) && a.period == b.period })
+	}
+	if len(series["UGRD"]) == 0 || len(series["VGRD"]) == 0 {
+		return WeatherData{}, fmt.Errorf("the GRIB file has no 10 m wind at %.3f, %.3f", lat, lon)
+	}
+
+	tz, err := opts.location()
+	if err != nil {
+		return WeatherData{}, err
+	}
+	u := opts.Units
+	windSpeed := func(ms *float64) *float64 { return scaled(ms, u.FromKmh(3.6)) }
+	kelvin := func(k *float64) *float64 {
+		if k == nil {
+			return nil
+		}
+		return ptr(*k - 273.15)
+	}
+
+	w := WeatherData{Timezone: tz.String(), Units: u}
+	speed, dir := series.wind(now)
+	w.Current = CurrentWeather{
+		Temperature:   kelvin(series.at("TMP", now)),
+		WindSpeed:     windSpeed(speed),
+		WindDirection: dir,
+		WindGusts:     windSpeed(series.at("GUST", now)),
+		Pressure:      scaled(series.at("PRMSL", now), 0.01),
+		Precipitation: series.precipitation(now.Truncate(time.Hour).Add(time.Hour)),
+	}
+	w.Marine = MarineData{
+		WaveHeight:    series.at("HTSGW", now),
+		WaveDirection: series.at("DIRPW", now),
+		WavePeriod:    series.at("PERPW", now),
+	}
+
+	first, last := series["UGRD"][0].time, series["UGRD"][len(series["UGRD"])-1].time
+	thisHour := now.Truncate(time.Hour)
+	end := thisHour.Add(time.Duration(opts.forecastHours()) * time.Hour)
+	days := make(map[string]*DailyForecast)
+	var dates []string
+	dayWind := make(map[string][2]float64) // summed u and v for the dominant direction
+
+	start := first.Truncate(time.Hour)
+	if start.Before(first) {
+		start = start.Add(time.Hour)
+	}
+	for t := start; !t.After(last); t = t.Add(time.Hour) {
+		local := t.In(tz).Format(openMeteoTime)
+		if t.Before(thisHour) {
+			if p := series.at("PRMSL", t); p != nil {
+				w.PressureHistory = append(w.PressureHistory, PressureReading{Time: local, Pressure: *p / 100})
+			}
+			continue
+		}
+		speed, dir := series.wind(t)
+		h := HourlyForecast{
+			Time:          local,
+			Temperature:   kelvin(series.at("TMP", t)),
+			WindSpeed:     windSpeed(speed),
+			WindGusts:     windSpeed(series.at("GUST", t)),
+			WindDirection: dir,
+			Precipitation: series.precipitation(t),
+			Pressure:      scaled(series.at("PRMSL", t), 0.01),
+		}
+		m := HourlyMarine{
+			Time:          local,
+			WaveHeight:    series.at("HTSGW", t),
+			WaveDirection: series.at("DIRPW", t),
+			WavePeriod:    series.at("PERPW", t),
+		}
+
+		date := dateOf(local)
+		d, ok := days[date]
+		if !ok {
+			d = &DailyForecast{Date: date}
+			days[date] = d
+			dates = append(dates, date)
+		}
+		d.TempMax, d.TempMin = maxOf(d.TempMax, h.Temperature), minOf(d.TempMin, h.Temperature)
+		d.WindSpeedMax, d.WindGustsMax = maxOf(d.WindSpeedMax, h.WindSpeed), maxOf(d.WindGustsMax, h.WindGusts)
+		if h.Precipitation != nil {
+			sum := *h.Precipitation
+			if d.PrecipitationSum != nil {
+				sum += *d.PrecipitationSum
+			}
+			d.PrecipitationSum = &sum
+		}
+		if ug, vg := series.at("UGRD", t), series.at("VGRD", t); ug != nil && vg != nil {
+			uv := dayWind[date]
+			uv[0], uv[1] = uv[0]+*ug, uv[1]+*vg
+			dayWind[date] = uv
+			d.WindDirection = ptr(math.Mod(math.Atan2(-uv[0], -uv[1])*180/math.Pi+360, 360))
+		}
+
+		if t.Before(end) {
+			w.Hourly = append(w.Hourly, h)
+			if m.WaveHeight != nil || m.WavePeriod != nil {
+				w.HourlyMarine = append(w.HourlyMarine, m)
+			}
+		}
+	}
+	for _, date := range dates {
+		w.Daily = append(w.Daily, *days[date])
+	}
+	return w, nil
+}
+
+// maxOf and minOf combine optional values, ignoring missing ones.
+func maxOf(a, b *float64) *float64 {
+	if a == nil || (b != nil && *b > *a) {
+		return b
+	}
+	return a
+}
+
+func minOf(a, b *float64) *float64 {
+	if a == nil || (b != nil && *b < *a) {
+		return b
+	}
+	return a
+}
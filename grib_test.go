@@ -0,0 +1,249 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testGRIBField is a field for encodeGRIB2 on a 3×3 grid from 44°N 15°E to 43°N 16°E
+// in 0.5° steps, scanned from north-west to south-east.
+type testGRIBField struct {
+	discipline, category, number byte
+	level                        byte      // type of first fixed surface
+	height                       int       // value of the surface, e.g. 10 m
+	hours                        int       // forecast time
+	period                       int       // accumulation hours, template 4.8 if > 0
+	values                       []float64 // NaN is left out by a bitmap
+}
+
+// encodeGRIB2 writes one GRIB2 message per field with simple packing and a reference
+// time of 2026-03-15 00:00 UTC.
+func encodeGRIB2(fields []testGRIBField) []byte {
+	section := func(num byte, body []byte) []byte {
+		s := binary.BigEndian.AppendUint32(nil, uint32(5+len(body)))
+		return append(append(s, num), body...)
+	}
+	var out bytes.Buffer
+	out.WriteString("mail headers and other text before the attachment\n")
+	for _, f := range fields {
+		ident := make([]byte, 16)
+		binary.BigEndian.PutUint16(ident[7:], 2026) // octets 13-14
+		ident[9], ident[10] = 3, 15                 // March 15th, 00:00:00
+
+		grid := make([]byte, 67)
+		binary.BigEndian.PutUint32(grid[1:], 9)     // number of points
+		binary.BigEndian.PutUint32(grid[25:], 3)    // Ni
+		binary.BigEndian.PutUint32(grid[29:], 3)    // Nj
+		binary.BigEndian.PutUint32(grid[41:], 44e6) // La1
+		binary.BigEndian.PutUint32(grid[45:], 15e6) // Lo1
+		binary.BigEndian.PutUint32(grid[50:], 43e6) // La2
+		binary.BigEndian.PutUint32(grid[54:], 16e6) // Lo2
+		binary.BigEndian.PutUint32(grid[58:], 5e5)  // Di
+		binary.BigEndian.PutUint32(grid[62:], 5e5)  // Dj
+		grid[66] = 0                                // west to east, north to south
+
+		product := make([]byte, 29)
+		product[4], product[5] = f.category, f.number
+		product[12] = 1 // hours
+		start := f.hours
+		if f.period > 0 {
+			binary.BigEndian.PutUint16(product[2:], 8)
+			start -= f.period
+			product = append(product, make([]byte, 24)...)
+			product[36], product[41], product[43] = 1, 1, 1 // one time range of accumulation in hours
+			binary.BigEndian.PutUint32(product[44:], uint32(f.period))
+		}
+		binary.BigEndian.PutUint32(product[13:], uint32(start))
+		product[17] = f.level
+		binary.BigEndian.PutUint32(product[19:], uint32(f.height))
+
+		// Two decimals, 16 bits from the minimum.
+		var present []float64
+		bitmap := make([]byte, 2)
+		for i, v := range f.values {
+			if !math.IsNaN(v) {
+				present = append(present, v)
+				bitmap[i/8] |= 0x80 >> (i % 8)
+			}
+		}
+		minimum := math.Inf(1)
+		for _, v := range present {
+			minimum = min(minimum, v*100)
+		}
+		packing := make([]byte, 16)
+		binary.BigEndian.PutUint32(packing[0:], uint32(len(present)))
+		binary.BigEndian.PutUint32(packing[6:], math.Float32bits(float32(minimum)))
+		binary.BigEndian.PutUint16(packing[12:], 2) // D
+		packing[14] = 16
+		var data []byte
+		for _, v := range present {
+			data = binary.BigEndian.AppendUint16(data, uint16(math.Round(v*100-minimum)))
+		}
+
+		var msg []byte
+		msg = append(msg, section(1, ident)...)
+		msg = append(msg, section(3, grid)...)
+		msg = append(msg, section(4, product)...)
+		msg = append(msg, section(5, packing)...)
+		if len(present) < len(f.values) {
+			msg = append(msg, section(6, append([]byte{0}, bitmap...))...)
+		} else {
+			msg = append(msg, section(6, []byte{255})...)
+		}
+		msg = append(msg, section(7, data)...)
+		msg = append(msg, "7777"...)
+
+		out.WriteString("GRIB\x00\x00")
+		out.WriteByte(f.discipline)
+		out.WriteByte(2)
+		binary.Write(&out, binary.BigEndian, uint64(16+len(msg)))
+		out.Write(msg)
+	}
+	return out.Bytes()
+}
+
+// uniform is a field with the same value at every grid point.
+func uniform(v float64) []float64 {
+	return []float64{v, v, v, v, v, v, v, v, v}
+}
+
+// testGRIB is a 12-hour forecast in 6-hour steps: a southerly of 4 m/s freshening to
+// 15 m/s, pressure falling 1 hPa per hour, rain in the second period and waves
+// everywhere but on the land in the north-east corner.
+func testGRIB() []byte {
+	var fields []testGRIBField
+	for _, step := range []struct {
+		hours      int
+		v, prmsl   float64
+		wave, rain float64
+	}{
+		{0, 4, 101500, 0.5, 0},
+		{6, 10, 100900, 1.0, 3},
+		{12, 15, 100300, 2.5, 12},
+	} {
+		fields = append(fields,
+			testGRIBField{0, 2, 2, 103, 10, step.hours, 0, uniform(0)},
+			testGRIBField{0, 2, 3, 103, 10, step.hours, 0, uniform(step.v)},
+			testGRIBField{0, 2, 3, 100, 85000, step.hours, 0, uniform(30)}, // 850 hPa, skipped
+			testGRIBField{0, 3, 1, 101, 0, step.hours, 0, uniform(step.prmsl)},
+			testGRIBField{10, 0, 3, 1, 0, step.hours, 0, []float64{step.wave, step.wave, math.NaN(), step.wave, step.wave, step.wave, step.wave, step.wave, step.wave}},
+		)
+		if step.hours > 0 {
+			fields = append(fields, testGRIBField{0, 1, 8, 1, 0, step.hours, 6, uniform(step.rain)})
+		}
+	}
+	return encodeGRIB2(fields)
+}
+
+func TestReadGRIB2(t *testing.T) {
+	fields, err := ReadGRIB2(bytes.NewReader(testGRIB()))
+	if err != nil {
+		t.Fatalf("ReadGRIB2: %v", err)
+	}
+	if len(fields) != 14 {
+		t.Fatalf("ReadGRIB2 returned %d fields, want 14 without the 850 hPa wind", len(fields))
+	}
+
+	wave := fields[3]
+	if wave.Parameter != "HTSGW" || !wave.ValidTime.Equal(time.Date(2026, 3, 15, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("field 3 = %s at %s", wave.Parameter, wave.ValidTime)
+	}
+	if wave.Grid != (GRIBGrid{Ni: 3, Nj: 3, Lat1: 44, Lon1: 15, Di: 0.5, Dj: 0.5}) {
+		t.Errorf("grid = %+v", wave.Grid)
+	}
+	if !math.IsNaN(wave.Values[2]) || math.Abs(wave.Values[3]-0.5) > 1e-6 {
+		t.Errorf("wave values = %v, want NaN on land", wave.Values)
+	}
+	// Next to land only the sea points count.
+	if v, ok := wave.At(43.9, 15.9); !ok || math.Abs(v-0.5) > 1e-6 {
+		t.Errorf("wave height near land = %v, %v", v, ok)
+	}
+	if _, ok := wave.At(45, 15.5); ok {
+		t.Error("wave height outside the grid")
+	}
+
+	rain := fields[13]
+	if rain.Parameter != "APCP" || rain.Period != 6*time.Hour || !rain.ValidTime.Equal(time.Date(2026, 3, 15, 12, 0, 0, 0, time.UTC)) {
+		t.Errorf("rain = %s over %s until %s", rain.Parameter, rain.Period, rain.ValidTime)
+	}
+
+	if _, err := ReadGRIB2(strings.NewReader("GRIB\x00\x00\x00\x01" + strings.Repeat("\x00", 20))); err == nil || !strings.Contains(err.Error(), "edition 1") {
+		t.Errorf("GRIB1: err = %v", err)
+	}
+	// A length that does not even cover the indicator section.
+	for _, n := range []byte{0, 8} {
+		header := "GRIB\x00\x00\x00\x02" + strings.Repeat("\x00", 7) + string(n)
+		if _, err := ReadGRIB2(strings.NewReader(header + strings.Repeat("\x00", 20))); err == nil || !strings.Contains(err.Error(), "truncated") {
+			t.Errorf("message length %d: err = %v", n, err)
+		}
+	}
+	// A constant field (0 bits) claiming far more values than the grid has points.
+	packing := make([]byte, 21)
+	binary.BigEndian.PutUint32(packing[5:], math.MaxUint32)
+	if _, err := unpackGRIBSimple(packing, nil, nil, 9); err == nil {
+		t.Error("4294967295 values for 9 points: no error")
+	}
+}
+
+func TestLoadGRIBWeather(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "saildocs.grb2")
+	if err := os.WriteFile(path, testGRIB(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2026, 3, 15, 3, 30, 0, 0, time.UTC)
+	w, err := LoadGRIBWeather(path, 43.5, 15.5, now, WeatherOptions{Units: UnitsNautical, ForecastHours: 6})
+	if err != nil {
+		t.Fatalf("LoadGRIBWeather: %v", err)
+	}
+
+	approx := func(name string, got *float64, want float64) {
+		t.Helper()
+		if got == nil {
+			t.Errorf("%s = nil, want %.2f", name, want)
+		} else if math.Abs(*got-want) > 0.01 {
+			t.Errorf("%s = %.2f, want %.2f", name, *got, want)
+		}
+	}
+	// 7.5 m/s from the south at 03:30 is 14.6 kn.
+	approx("current wind", w.Current.WindSpeed, 7.5*3.6/kmhPerKnot)
+	approx("current wind direction", w.Current.WindDirection, 180)
+	approx("current pressure", w.Current.Pressure, 1011.5)
+	approx("current waves", w.Marine.WaveHeight, 0.79)
+	if w.Current.WindGusts != nil || w.Current.Temperature != nil {
+		t.Errorf("values missing in the file are not nil: %+v", w.Current)
+	}
+
+	if len(w.PressureHistory) != 3 || w.PressureHistory[0] != (PressureReading{"2026-03-15T00:00", 1015}) {
+		t.Errorf("PressureHistory = %+v", w.PressureHistory)
+	}
+	if len(w.Hourly) != 6 || w.Hourly[0].Time != "2026-03-15T03:00" || w.Hourly[5].Time != "2026-03-15T08:00" {
+		t.Fatalf("Hourly = %+v", w.Hourly)
+	}
+	approx("rain 03:00", w.Hourly[0].Precipitation, 0.5)
+	approx("rain 07:00", w.Hourly[4].Precipitation, 2)
+	approx("waves 08:00", w.HourlyMarine[5].WaveHeight, 1.5)
+	if len(w.Daily) != 1 {
+		t.Fatalf("Daily = %+v", w.Daily)
+	}
+	approx("daily wind", w.Daily[0].WindSpeedMax, 15*3.6/kmhPerKnot)
+	approx("daily rain", w.Daily[0].PrecipitationSum, 14) // from the hour ending at 03:00
+
+	// The rest of the pipeline works on it unchanged.
+	hazards := EvaluateHazards(w, DefaultHazardThresholds)
+	if len(hazards) == 0 || hazards[0].Parameter != HazardWind || hazards[0].Start != "2026-03-15T05:00" {
+		t.Errorf("hazards = %+v, want wind from 05:00", hazards)
+	}
+	if out := FormatWeatherData(w, DefaultHazardThresholds); !strings.Contains(out, "Pressure: 1012 hPa") || !strings.Contains(out, "Wind: 14.6 kn (Bft 4 Moderate breeze) from S (180°)") {
+		t.Errorf("FormatWeatherData:\n%s", out)
+	}
+
+	if _, err := LoadGRIBWeather(path, 50, 15.5, now, WeatherOptions{}); err == nil {
+		t.Error("position outside the GRIB area: no error")
+	}
+}
@@ -0,0 +1,448 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+)
+
+// Severity ranks a hazard.
+type Severity int
+
+const (
+	SeverityWarning Severity = iota + 1
+	SeverityDanger
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityWarning:
+		return "warning"
+	case SeverityDanger:
+		return "danger"
+	}
+	return "none"
+}
+
+// Hazard parameters.
+const (
+	HazardWind         = "wind"
+	HazardGusts        = "gusts"
+	HazardThunderstorm = "thunderstorm"
+	HazardCAPE         = "cape"
+	HazardLiftedIndex  = "lifted_index"
+	HazardVisibility   = "visibility"
+	HazardWaveHeight   = "wave_height"
+	HazardPressureFall = "pressure_fall"
+	HazardModelSpread  = "model_spread"
+)
+
+// hazardParameters lists every parameter the rule engine checks.
+var hazardParameters = []string{
+	HazardWind, HazardGusts, HazardThunderstorm, HazardCAPE, HazardLiftedIndex,
+	HazardVisibility, HazardWaveHeight, HazardPressureFall, HazardModelSpread,
+}
+
+// Hazard is a threshold crossing found in the weather data.
+type Hazard struct {
+	Start     string // "now", an Open-Meteo hour ("2026-03-15T14:00") or a date
+	End       string // last hour or date of the window, same format as Start
+	Parameter string
+	Value     float64 // worst value in the window
+	Unit      string
+	Severity  Severity
+}
+
+// HazardThresholds are the warning limits of the rule engine. A value at or beyond
+// the limit is a warning; dangerThresholdFactor times as far is a danger.
+type HazardThresholds struct {
+	WindKmh         float64
+	GustKmh         float64
+	WaveM           float64
+	CAPE            float64 // J/kg
+	LiftedIndex     float64 // K; lower values are worse
+	VisibilityM     float64 // lower values are worse
+	PressureFallHPa float64 // fall within pressureTendencyHours
+}
+
+// DefaultHazardThresholds match the limits in prompt.md. Gusts matter more than the
+// mean wind: a day can look fine on average and still bring dangerous squalls.
+var DefaultHazardThresholds = HazardThresholds{
+	WindKmh:         30,
+	GustKmh:         45, // ~24 kn, Beaufort 6
+	WaveM:           2,
+	CAPE:            1000,
+	LiftedIndex:     -2,
+	VisibilityM:     1000,
+	PressureFallHPa: pressureFallStrong,
+}
+
+const dangerThresholdFactor = 1.5
+
+// hazardFlags registers the hazard threshold flags on fs.
+func hazardFlags(fs *flag.FlagSet) *HazardThresholds {
+	th := DefaultHazardThresholds
+	fs.Float64Var(&th.WindKmh, "hazard-wind", th.WindKmh, "Warn when the mean wind reaches this speed (km/h)")
+	fs.Float64Var(&th.GustKmh, "hazard-gust", th.GustKmh, "Warn when gusts reach this speed (km/h)")
+	fs.Float64Var(&th.WaveM, "hazard-wave", th.WaveM, "Warn when the wave height reaches this height (m)")
+	fs.Float64Var(&th.CAPE, "hazard-cape", th.CAPE, "Warn of thunderstorm potential when CAPE reaches this value (J/kg)")
+	fs.Float64Var(&th.LiftedIndex, "hazard-lifted-index", th.LiftedIndex, "Warn of unstable air when the lifted index drops below this value")
+	fs.Float64Var(&th.VisibilityM, "hazard-visibility", th.VisibilityM, "Warn when visibility drops below this distance (m)")
+	fs.Float64Var(&th.PressureFallHPa, "hazard-pressure-fall", th.PressureFallHPa, "Warn of strong wind when the pressure falls this much (hPa) in 3 hours")
+	return &th
+}
+
+// hazardSample is one value of a parameter at a point in time.
+type hazardSample struct {
+	time  string
+	value *float64 // nil if not reported
+}
+
+// hazardRule extracts a series from the weather data and classifies each value.
+type hazardRule struct {
+	parameter string
+	unit      string
+	samples   func(w WeatherData) [][]hazardSample // one series per data source
+	classify  func(v float64) Severity
+	lowIsBad  bool // the worst value in a window is the lowest one
+}
+
+func above(limit float64) func(float64) Severity {
+	return func(v float64) Severity {
+		switch {
+		case v >= limit*dangerThresholdFactor:
+			return SeverityDanger
+		case v >= limit:
+			return SeverityWarning
+		}
+		return 0
+	}
+}
+
+// below classifies values under a limit. Negative limits (lifted index) scale away
+// from zero just like positive ones.
+func below(limit float64) func(float64) Severity {
+	return func(v float64) Severity {
+		danger := limit / dangerThresholdFactor
+		if limit < 0 {
+			danger = limit * dangerThresholdFactor
+		}
+		switch {
+		case v <= danger:
+			return SeverityDanger
+		case v < limit:
+			return SeverityWarning
+		}
+		return 0
+	}
+}
+
+// windSamples builds the series for a wind parameter: now, the hourly forecast, and
+// the daily maximum for days the hourly forecast does not cover.
+func windSamples(current func(CurrentWeather) *float64, hourly func(HourlyForecast) *float64, daily func(DailyForecast) *float64) func(WeatherData) [][]hazardSample {
+	return func(w WeatherData) [][]hazardSample {
+		series := [][]hazardSample{
+			{{time: "now", value: current(w.Current)}},
+			hourlySamples(w, hourly),
+		}
+		var days []hazardSample
+		for _, d := range dailyBeyondHourly(w) {
+			days = append(days, hazardSample{time: d.Date, value: daily(d)})
+		}
+		return append(series, days)
+	}
+}
+
+func hourlySamples(w WeatherData, value func(HourlyForecast) *float64) []hazardSample {
+	samples := make([]hazardSample, 0, len(w.Hourly))
+	for _, h := range w.Hourly {
+		samples = append(samples, hazardSample{time: h.Time, value: value(h)})
+	}
+	return samples
+}
+
+func dailyBeyondHourly(w WeatherData) []DailyForecast {
+	hourlyDates := make(map[string]bool)
+	for _, h := range w.Hourly {
+		hourlyDates[dateOf(h.Time)] = true
+	}
+	var days []DailyForecast
+	for _, d := range w.Daily {
+		if !hourlyDates[d.Date] {
+			days = append(days, d)
+		}
+	}
+	return days
+}
+
+func hazardRules(w WeatherData, th HazardThresholds) []hazardRule {
+	u := w.Units
+	return []hazardRule{
+		{
+			parameter: HazardWind,
+			unit:      u.WindUnit(),
+			samples: windSamples(
+				func(c CurrentWeather) *float64 { return c.WindSpeed },
+				func(h HourlyForecast) *float64 { return h.WindSpeed },
+				func(d DailyForecast) *float64 { return d.WindSpeedMax },
+			),
+			classify: above(u.FromKmh(th.WindKmh)),
+		},
+		{
+			parameter: HazardGusts,
+			unit:      u.WindUnit(),
+			samples: windSamples(
+				func(c CurrentWeather) *float64 { return c.WindGusts },
+				func(h HourlyForecast) *float64 { return h.WindGusts },
+				func(d DailyForecast) *float64 { return d.WindGustsMax },
+			),
+			classify: above(u.FromKmh(th.GustKmh)),
+		},
+		{
+			parameter: HazardThunderstorm,
+			samples: func(w WeatherData) [][]hazardSample {
+				code := func(c *int) *float64 {
+					if c == nil {
+						return nil
+					}
+					return ptr(float64(*c))
+				}
+				var days []hazardSample
+				for _, d := range dailyBeyondHourly(w) {
+					days = append(days, hazardSample{time: d.Date, value: code(d.WeatherCode)})
+				}
+				return [][]hazardSample{
+					{{time: "now", value: code(w.Current.WeatherCode)}},
+					hourlySamples(w, func(h HourlyForecast) *float64 { return code(h.WeatherCode) }),
+					days,
+				}
+			},
+			classify: func(v float64) Severity {
+				if isThunderstormCode(int(v)) {
+					return SeverityDanger
+				}
+				return 0
+			},
+		},
+		{
+			parameter: HazardCAPE,
+			unit:      "J/kg",
+			samples: func(w WeatherData) [][]hazardSample {
+				return [][]hazardSample{hourlySamples(w, func(h HourlyForecast) *float64 { return h.CAPE })}
+			},
+			classify: above(th.CAPE),
+		},
+		{
+			parameter: HazardLiftedIndex,
+			unit:      "K",
+			samples: func(w WeatherData) [][]hazardSample {
+				return [][]hazardSample{hourlySamples(w, func(h HourlyForecast) *float64 { return h.LiftedIndex })}
+			},
+			classify: below(th.LiftedIndex),
+			lowIsBad: true,
+		},
+		{
+			parameter: HazardVisibility,
+			unit:      "m",
+			samples: func(w WeatherData) [][]hazardSample {
+				return [][]hazardSample{hourlySamples(w, func(h HourlyForecast) *float64 { return h.Visibility })}
+			},
+			classify: below(th.VisibilityM),
+			lowIsBad: true,
+		},
+		{
+			parameter: HazardWaveHeight,
+			unit:      "m",
+			samples: func(w WeatherData) [][]hazardSample {
+				hourly := make([]hazardSample, 0, len(w.HourlyMarine))
+				for _, m := range w.HourlyMarine {
+					hourly = append(hourly, hazardSample{time: m.Time, value: m.WaveHeight})
+				}
+				return [][]hazardSample{{{time: "now", value: w.Marine.WaveHeight}}, hourly}
+			},
+			classify: above(th.WaveM),
+		},
+		{
+			parameter: HazardPressureFall,
+			unit:      "hPa",
+			samples: func(w WeatherData) [][]hazardSample {
+				falls := pressureFalls(pressureSeries(w))
+				var hourly []hazardSample
+				for _, h := range w.Hourly {
+					if fall, ok := falls[h.Time]; ok {
+						hourly = append(hourly, hazardSample{time: h.Time, value: ptr(fall)})
+					}
+				}
+				return [][]hazardSample{hourly}
+			},
+			classify: func(v float64) Severity {
+				switch {
+				case v >= th.PressureFallHPa && v > pressureQuickly:
+					return SeverityDanger // falling very rapidly: storm likely
+				case v >= th.PressureFallHPa:
+					return SeverityWarning
+				}
+				return 0
+			},
+		},
+		{
+			parameter: HazardModelSpread,
+			unit:      u.WindUnit(),
+			samples: func(w WeatherData) [][]hazardSample {
+				// Exactly the hours of low confidence in FORECAST CONFIDENCE, with
+				// the wind spread, 0 if the models only disagree on precipitation.
+				hourly := make([]hazardSample, 0, len(w.Spread))
+				for _, s := range w.Spread {
+					var v *float64
+					if s.Confidence(u) == ConfidenceLow {
+						v = ptr(optValue(s.windRange()))
+					}
+					hourly = append(hourly, hazardSample{time: s.Time, value: v})
+				}
+				return [][]hazardSample{hourly}
+			},
+			classify: func(float64) Severity {
+				return SeverityWarning // the forecast is unreliable, not the weather dangerous
+			},
+		},
+	}
+}
+
+// isThunderstormCode reports whether a WMO weather code is a thunderstorm (95–99).
+func isThunderstormCode(code int) bool {
+	return code >= 95 && code <= 99
+}
+
+// EvaluateHazards runs every rule over the current conditions, the hourly and daily
+// forecast and the marine data. Consecutive samples that cross a threshold are merged
+// into one hazard with the worst value and highest severity of the window.
+func EvaluateHazards(w WeatherData, th HazardThresholds) []Hazard {
+	var hazards []Hazard
+	for _, rule := range hazardRules(w, th) {
+		for _, series := range rule.samples(w) {
+			var open *Hazard
+			for _, s := range series {
+				// A missing value ends a window: it is unknown, not harmless.
+				if s.value == nil || rule.classify(*s.value) == 0 {
+					if open != nil {
+						hazards = append(hazards, *open)
+						open = nil
+					}
+					continue
+				}
+				v := *s.value
+				if open == nil {
+					open = &Hazard{Start: s.time, Parameter: rule.parameter, Value: v, Unit: rule.unit}
+				}
+				open.End = s.time
+				open.Severity = max(open.Severity, rule.classify(v))
+				if (rule.lowIsBad && v < open.Value) || (!rule.lowIsBad && v > open.Value) {
+					open.Value = v
+				}
+			}
+			if open != nil {
+				hazards = append(hazards, *open)
+			}
+		}
+	}
+	return hazards
+}
+
+// Window renders the hazard's time span, e.g. "2026-03-15T13:00–16:00".
+func (h Hazard) Window() string {
+	if h.End == "" || h.End == h.Start {
+		return h.Start
+	}
+	if dateOf(h.Start) == dateOf(h.End) && strings.Contains(h.End, "T") {
+		return h.Start + "–" + hourOf(h.End)
+	}
+	return h.Start + "–" + h.End
+}
+
+// describe renders the parameter and value, e.g. "gusts 55 km/h".
+func (h Hazard) describe(u UnitSystem) string {
+	switch h.Parameter {
+	case HazardWind, HazardGusts:
+		return fmt.Sprintf("%s %s", h.Parameter, u.formatWind(h.Value, 0))
+	case HazardThunderstorm:
+		return weatherCodeToText(int(h.Value))
+	case HazardWaveHeight:
+		return "wave height " + u.formatHeight(h.Value)
+	case HazardLiftedIndex:
+		return fmt.Sprintf("lifted index %.1f %s (unstable air)", h.Value, h.Unit)
+	case HazardCAPE:
+		return fmt.Sprintf("CAPE %.0f %s (thunderstorm potential)", h.Value, h.Unit)
+	case HazardVisibility:
+		return fmt.Sprintf("visibility %.0f %s", h.Value, h.Unit)
+	case HazardPressureFall:
+		// The fall is at least the threshold, or it would be no hazard.
+		return fmt.Sprintf("pressure falling %.1f %s in %d h (%s)", h.Value, h.Unit, pressureTendencyHours, stormWarning(h.Value, 0))
+	case HazardModelSpread:
+		if h.Value == 0 {
+			return "forecast models disagree (low confidence)"
+		}
+		return fmt.Sprintf("forecast models disagree, wind or gusts up to %.0f %s apart (low confidence)", h.Value, h.Unit)
+	}
+	return fmt.Sprintf("%s %.1f %s", h.Parameter, h.Value, h.Unit)
+}
+
+// FormatHazards renders the hazards as a section of the user message.
+func FormatHazards(hazards []Hazard, u UnitSystem) string {
+	var b strings.Builder
+	b.WriteString("=== HAZARD WARNINGS (computed, must be included) ===\n")
+	if len(hazards) == 0 {
+		b.WriteString("None\n")
+		return b.String()
+	}
+	for _, h := range hazards {
+		b.WriteString(fmt.Sprintf("[%s] %s: %s\n", h.Severity, h.Window(), h.describe(u)))
+	}
+	return b.String()
+}
+
+// hazardBlockTitle is the warning marker prompt.md asks the model to use.
+var hazardBlockTitle = map[string]string{
+	"de": "⚠️ WARNUNG",
+	"en": "⚠️ WARNING",
+}
+
+// InsertHazardBlock adds a Logseq warning block listing the hazards right after the
+// briefing's header block, so the warnings appear even if the model left them out.
+func InsertHazardBlock(briefing string, hazards []Hazard, u UnitSystem, lang string) string {
+	if len(hazards) == 0 {
+		return briefing
+	}
+	title, ok := hazardBlockTitle[lang]
+	if !ok {
+		title = hazardBlockTitle["en"]
+	}
+
+	return insertAfterHeader(briefing, func(indent string) string {
+		var b strings.Builder
+		b.WriteString(fmt.Sprintf("%s- %s\n", indent, title))
+		for _, h := range hazards {
+			marker := "⚠️"
+			if h.Severity == SeverityDanger {
+				marker = "⛔"
+			}
+			b.WriteString(fmt.Sprintf("%s\t- %s %s: %s\n", indent, marker, h.Window(), h.describe(u)))
+		}
+		return b.String()
+	})
+}
+
+// insertAfterHeader places a block directly below the [[Tagesbriefing]] header block
+// (after its location:: property line), as a child of the briefing. Without a header
+// the block goes on top as a top-level block. block renders itself at the given indent.
+func insertAfterHeader(briefing string, block func(indent string) string) string {
+	lines := strings.SplitAfter(briefing, "\n")
+	for i, line := range lines {
+		if strings.HasPrefix(strings.TrimSpace(line), "location::") {
+			if !strings.HasSuffix(line, "\n") {
+				lines[i] += "\n"
+			}
+			return strings.Join(lines[:i+1], "") + block("\t") + strings.Join(lines[i+1:], "")
+		}
+	}
+	return block("") + briefing
+}
@@ -0,0 +1,94 @@
+package main
+
+import "testing"
+
+func TestEvaluateHazards(t *testing.T) {
+	data := WeatherData{
+		Current: CurrentWeather{WindSpeed: ptr(20.0), WindGusts: ptr(50.0), WeatherCode: ptr(2)},
+		Hourly: []HourlyForecast{
+			{Time: "2026-03-15T12:00", WindSpeed: ptr(18.0), WindGusts: ptr(30.0), Visibility: ptr(20000.0)},
+			{Time: "2026-03-15T13:00", WindSpeed: ptr(20.0), WindGusts: ptr(48.0), Visibility: ptr(20000.0), CAPE: ptr(1200.0)},
+			{Time: "2026-03-15T14:00", WindSpeed: ptr(22.0), WindGusts: ptr(70.0), Visibility: ptr(20000.0), LiftedIndex: ptr(-3.0), WeatherCode: ptr(95)},
+			{Time: "2026-03-15T15:00", WindSpeed: ptr(15.0), WindGusts: ptr(35.0), Visibility: ptr(800.0)},
+		},
+		Daily: []DailyForecast{
+			{Date: "2026-03-15", WindGustsMax: ptr(70.0), WeatherCode: ptr(95)},
+			{Date: "2026-03-17", WindSpeedMax: ptr(32.0), WindGustsMax: ptr(40.0)},
+		},
+		Marine:       MarineData{WaveHeight: ptr(1.2)},
+		HourlyMarine: []HourlyMarine{{Time: "2026-03-15T14:00", WaveHeight: ptr(2.4)}},
+	}
+
+	got := EvaluateHazards(data, DefaultHazardThresholds)
+	want := []Hazard{
+		{Start: "2026-03-17", End: "2026-03-17", Parameter: HazardWind, Value: 32, Unit: "km/h", Severity: SeverityWarning},
+		{Start: "now", End: "now", Parameter: HazardGusts, Value: 50, Unit: "km/h", Severity: SeverityWarning},
+		{Start: "2026-03-15T13:00", End: "2026-03-15T14:00", Parameter: HazardGusts, Value: 70, Unit: "km/h", Severity: SeverityDanger},
+		{Start: "2026-03-15T14:00", End: "2026-03-15T14:00", Parameter: HazardThunderstorm, Value: 95, Severity: SeverityDanger},
+		{Start: "2026-03-15T13:00", End: "2026-03-15T13:00", Parameter: HazardCAPE, Value: 1200, Unit: "J/kg", Severity: SeverityWarning},
+		{Start: "2026-03-15T14:00", End: "2026-03-15T14:00", Parameter: HazardLiftedIndex, Value: -3, Unit: "K", Severity: SeverityDanger},
+		{Start: "2026-03-15T15:00", End: "2026-03-15T15:00", Parameter: HazardVisibility, Value: 800, Unit: "m", Severity: SeverityWarning},
+		{Start: "2026-03-15T14:00", End: "2026-03-15T14:00", Parameter: HazardWaveHeight, Value: 2.4, Unit: "m", Severity: SeverityWarning},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("EvaluateHazards returned %d hazards, want %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("hazard %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestEvaluateHazardsNautical(t *testing.T) {
+	// 26 kn is 48 km/h: above the default gust limit even though the number is smaller.
+	data := WeatherData{Units: UnitsNautical, Current: CurrentWeather{WindGusts: ptr(26.0)}}
+	got := EvaluateHazards(data, DefaultHazardThresholds)
+	if len(got) != 1 || got[0].Parameter != HazardGusts || got[0].Unit != "kn" {
+		t.Fatalf("EvaluateHazards = %+v, want one gust hazard in knots", got)
+	}
+	if got := got[0].describe(UnitsNautical); got != "gusts 26 kn (Bft 6 Strong breeze)" {
+		t.Errorf("describe = %q", got)
+	}
+}
+
+func TestEvaluateHazardsMissingValues(t *testing.T) {
+	// A missing hour splits the gust window, a missing visibility is not fog.
+	data := WeatherData{
+		Hourly: []HourlyForecast{
+			{Time: "2026-03-15T12:00", WindGusts: ptr(50.0)},
+			{Time: "2026-03-15T13:00"},
+			{Time: "2026-03-15T14:00", WindGusts: ptr(52.0), Visibility: ptr(20000.0)},
+		},
+	}
+	got := EvaluateHazards(data, DefaultHazardThresholds)
+	want := []Hazard{
+		{Start: "2026-03-15T12:00", End: "2026-03-15T12:00", Parameter: HazardGusts, Value: 50, Unit: "km/h", Severity: SeverityWarning},
+		{Start: "2026-03-15T14:00", End: "2026-03-15T14:00", Parameter: HazardGusts, Value: 52, Unit: "km/h", Severity: SeverityWarning},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("EvaluateHazards returned %d hazards, want %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("hazard %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestInsertHazardBlock(t *testing.T) {
+	briefing := "- [[Tagesbriefing]]\n\t- position:: 43.5, 16.4\n\t  location:: Split, Croatia\n\t- Standort\n"
+	hazards := []Hazard{{Start: "2026-03-15T13:00", End: "2026-03-15T16:00", Parameter: HazardGusts, Value: 70, Unit: "km/h", Severity: SeverityDanger}}
+
+	got := InsertHazardBlock(briefing, hazards, UnitsMetric, "de")
+	want := "- [[Tagesbriefing]]\n\t- position:: 43.5, 16.4\n\t  location:: Split, Croatia\n" +
+		"\t- ⚠️ WARNUNG\n\t\t- ⛔ 2026-03-15T13:00–16:00: gusts 70 km/h (38 kn, Bft 8 Gale)\n" +
+		"\t- Standort\n"
+	if got != want {
+		t.Errorf("InsertHazardBlock =\n%s\nwant\n%s", got, want)
+	}
+
+	if got := InsertHazardBlock("Some text", hazards, UnitsMetric, "en"); !contains(got, "- ⚠️ WARNING\n\t- ⛔") {
+		t.Errorf("InsertHazardBlock without header = %q", got)
+	}
+}
@@ -0,0 +1,280 @@
+// Package logseq reads and writes the journal pages of a Logseq graph: the
+// sailing log the daily briefing is built from and written to.
+package logseq
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PositionProperty is the block property holding the boat's position, written as
+// "current_position:: 47.13826/8.60032" or "current-position:: 43.5, 16.4".
+const PositionProperty = "current-position"
+
+// fileDateFormat is how Logseq names journal files, e.g. 2026_03_15.md.
+const fileDateFormat = "2006_01_02"
+
+// Block is a Logseq outline block.
+type Block struct {
+	// Content is the text of the block with the bullet and indentation removed;
+	// continuation lines are joined with "\n".
+	Content    string
+	Properties map[string]string // keys normalized with NormalizeKey
+	Children   []*Block
+}
+
+// Property returns the value of a block property, matching keys like Logseq does
+// (case-insensitive, "_" and "-" are the same).
+func (b *Block) Property(key string) (string, bool) {
+	v, ok := b.Properties[NormalizeKey(key)]
+	return v, ok
+}
+
+// Text returns the content of the block without its property lines.
+func (b *Block) Text() string {
+	var lines []string
+	for _, line := range strings.Split(b.Content, "\n") {
+		if _, _, ok := parseProperty(line); !ok {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Page is a parsed journal page.
+type Page struct {
+	Properties map[string]string // page properties before the first block
+	Blocks     []*Block
+}
+
+// Walk calls fn for every block in document order until fn returns false.
+func (p *Page) Walk(fn func(*Block) bool) {
+	var walk func([]*Block) bool
+	walk = func(blocks []*Block) bool {
+		for _, b := range blocks {
+			if !fn(b) || !walk(b.Children) {
+				return false
+			}
+		}
+		return true
+	}
+	walk(p.Blocks)
+}
+
+// NormalizeKey normalizes a property key: Logseq treats current_position and
+// Current-Position as the same property.
+func NormalizeKey(key string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(key), "_", "-"))
+}
+
+// Parse parses the markdown of a page into its outline. Lines starting with "- "
+// (after tabs or spaces) open a block, deeper indentation nests it below the
+// previous shallower block, and other lines continue the current block.
+func Parse(text string) *Page {
+	page := &Page{Properties: make(map[string]string)}
+
+	type open struct {
+		indent int
+		block  *Block
+	}
+	var stack []open
+	var lines []string // lines of the current block
+
+	flush := func() {
+		if len(stack) == 0 {
+			return
+		}
+		b := stack[len(stack)-1].block
+		b.Content = strings.Join(lines, "\n")
+		for _, line := range lines {
+			if k, v, ok := parseProperty(line); ok {
+				b.Properties[k] = v
+			}
+		}
+		lines = nil
+	}
+
+	for _, line := range strings.Split(strings.TrimRight(text, "\n"), "\n") {
+		trimmed := strings.TrimLeft(line, " \t")
+		indent := indentWidth(line[:len(line)-len(trimmed)])
+
+		if trimmed == "-" || strings.HasPrefix(trimmed, "- ") {
+			flush()
+			b := &Block{Properties: make(map[string]string)}
+			for len(stack) > 0 && stack[len(stack)-1].indent >= indent {
+				stack = stack[:len(stack)-1]
+			}
+			if len(stack) == 0 {
+				page.Blocks = append(page.Blocks, b)
+			} else {
+				parent := stack[len(stack)-1].block
+				parent.Children = append(parent.Children, b)
+			}
+			stack = append(stack, open{indent, b})
+			lines = []string{strings.TrimPrefix(strings.TrimPrefix(trimmed, "-"), " ")}
+			continue
+		}
+
+		if len(stack) == 0 {
+			if k, v, ok := parseProperty(trimmed); ok {
+				page.Properties[k] = v
+			}
+			continue
+		}
+		lines = append(lines, trimmed)
+	}
+	flush()
+	return page
+}
+
+// indentWidth counts a tab as one level and two spaces as one level.
+func indentWidth(ws string) int {
+	width := 0
+	for _, r := range ws {
+		if r == '\t' {
+			width += 2
+		} else {
+			width++
+		}
+	}
+	return width
+}
+
+func parseProperty(line string) (string, string, bool) {
+	key, value, ok := strings.Cut(strings.TrimSpace(line), ":: ")
+	if !ok {
+		key, ok = strings.CutSuffix(strings.TrimSpace(line), "::")
+	}
+	if !ok || key == "" || strings.ContainsAny(key, " \t") {
+		return "", "", false
+	}
+	return NormalizeKey(key), strings.TrimSpace(value), true
+}
+
+// ParsePosition parses "lat,lon" or "lat/lon", the formats used for current_position::.
+func ParsePosition(s string) (float64, float64, bool) {
+	sep := ","
+	if strings.Contains(s, "/") {
+		sep = "/"
+	}
+	latStr, lonStr, ok := strings.Cut(s, sep)
+	if !ok {
+		return 0, 0, false
+	}
+	lat, errLat := strconv.ParseFloat(strings.TrimSpace(latStr), 64)
+	lon, errLon := strconv.ParseFloat(strings.TrimSpace(lonStr), 64)
+	if errLat != nil || errLon != nil || math.Abs(lat) > 90 || math.Abs(lon) > 180 {
+		return 0, 0, false
+	}
+	return lat, lon, true
+}
+
+// Journal is the journals directory of a Logseq graph.
+type Journal struct {
+	Dir string
+}
+
+// Open opens the journal of the graph in dir, which must contain a journals/
+// subdirectory.
+func Open(dir string) (*Journal, error) {
+	journals := filepath.Join(dir, "journals")
+	info, err := os.Stat(journals)
+	if err != nil {
+		return nil, fmt.Errorf("journals directory not found at %s", journals)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", journals)
+	}
+	return &Journal{Dir: journals}, nil
+}
+
+// PagePath is the file of the journal page for the date.
+func (j *Journal) PagePath(date time.Time) string {
+	return filepath.Join(j.Dir, date.Format(fileDateFormat)+".md")
+}
+
+// ReadPage reads the raw markdown of the page for the date. A missing page is
+// reported as an error matching fs.ErrNotExist.
+func (j *Journal) ReadPage(date time.Time) (string, error) {
+	data, err := os.ReadFile(j.PagePath(date))
+	return string(data), err
+}
+
+// Position is the boat's position as noted in the journal.
+type Position struct {
+	Lat, Lon float64
+	Date     time.Time // date of the page it was found on
+}
+
+// ErrNoPosition is returned by LatestPosition when no page has a position.
+var ErrNoPosition = errors.New("no current_position:: found in the journal")
+
+// LatestPosition looks for the first current_position:: property on the pages
+// from today back to maxDays ago, newest first.
+func (j *Journal) LatestPosition(today time.Time, maxDays int) (Position, error) {
+	for daysAgo := 0; daysAgo <= maxDays; daysAgo++ {
+		date := today.AddDate(0, 0, -daysAgo)
+		text, err := j.ReadPage(date)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return Position{}, err
+		}
+
+		page := Parse(text)
+		found := false
+		var pos Position
+		check := func(value string) {
+			if lat, lon, ok := ParsePosition(value); ok {
+				pos, found = Position{Lat: lat, Lon: lon, Date: date}, true
+			}
+		}
+		if v, ok := page.Properties[PositionProperty]; ok {
+			check(v)
+		}
+		if !found {
+			page.Walk(func(b *Block) bool {
+				if v, ok := b.Property(PositionProperty); ok {
+					check(v)
+				}
+				return !found
+			})
+		}
+		if found {
+			return pos, nil
+		}
+	}
+	return Position{}, fmt.Errorf("%w in the last %d days", ErrNoPosition, maxDays)
+}
+
+// Context gathers the pages from today back to days ago, newest first, as context
+// for the language model. It returns the context and the number of pages found.
+func (j *Journal) Context(today time.Time, days int) (string, int, error) {
+	var b strings.Builder
+	b.WriteString("=== RECENT JOURNAL ENTRIES ===\n\n")
+
+	count := 0
+	for daysAgo := 0; daysAgo <= days; daysAgo++ {
+		date := today.AddDate(0, 0, -daysAgo)
+		text, err := j.ReadPage(date)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return "", count, err
+		}
+		b.WriteString(fmt.Sprintf("--- %s ---\n", date.Format("2006-01-02")))
+		b.WriteString(strings.TrimRight(text, "\n"))
+		b.WriteString("\n\n")
+		count++
+	}
+	return b.String(), count, nil
+}
@@ -0,0 +1,128 @@
+package logseq
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const samplePage = `title:: Day 12
+- Logbook
+	- Left Split at 08:00
+	  current_position:: 43.29600/5.36900
+		- Nested note
+- [[Tagesbriefing]]
+	- position:: 43.5, 16.4
+	  location:: Split, Croatia
+`
+
+func TestParse(t *testing.T) {
+	page := Parse(samplePage)
+	if page.Properties["title"] != "Day 12" {
+		t.Errorf("page properties = %v", page.Properties)
+	}
+	if len(page.Blocks) != 2 {
+		t.Fatalf("got %d top-level blocks, want 2", len(page.Blocks))
+	}
+
+	log := page.Blocks[0]
+	if log.Content != "Logbook" || len(log.Children) != 1 {
+		t.Fatalf("first block = %q with %d children", log.Content, len(log.Children))
+	}
+	entry := log.Children[0]
+	if entry.Content != "Left Split at 08:00\ncurrent_position:: 43.29600/5.36900" {
+		t.Errorf("entry content = %q", entry.Content)
+	}
+	if v, ok := entry.Property("Current-Position"); !ok || v != "43.29600/5.36900" {
+		t.Errorf("current_position = %q, %v", v, ok)
+	}
+	if entry.Text() != "Left Split at 08:00" {
+		t.Errorf("entry text = %q", entry.Text())
+	}
+	if len(entry.Children) != 1 || entry.Children[0].Content != "Nested note" {
+		t.Errorf("nested block missing: %+v", entry.Children)
+	}
+
+	header := page.Blocks[1].Children[0]
+	if header.Properties["position"] != "43.5, 16.4" || header.Properties["location"] != "Split, Croatia" {
+		t.Errorf("header properties = %v", header.Properties)
+	}
+
+	var n int
+	page.Walk(func(*Block) bool { n++; return true })
+	if n != 5 {
+		t.Errorf("Walk visited %d blocks, want 5", n)
+	}
+}
+
+func TestParsePosition(t *testing.T) {
+	for _, s := range []string{"43.5,16.4", "43.5 / 16.4", "43.5, 16.4"} {
+		if lat, lon, ok := ParsePosition(s); !ok || lat != 43.5 || lon != 16.4 {
+			t.Errorf("ParsePosition(%q) = %v, %v, %v", s, lat, lon, ok)
+		}
+	}
+	for _, s := range []string{"Vis, Croatia", "95,10", "43.5"} {
+		if _, _, ok := ParsePosition(s); ok {
+			t.Errorf("ParsePosition(%q) accepted", s)
+		}
+	}
+}
+
+func newJournal(t *testing.T, pages map[string]string) *Journal {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "journals"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for name, text := range pages {
+		if err := os.WriteFile(filepath.Join(dir, "journals", name), []byte(text), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	j, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return j
+}
+
+func TestLatestPosition(t *testing.T) {
+	today := time.Date(2026, 3, 15, 6, 0, 0, 0, time.Local)
+	j := newJournal(t, map[string]string{
+		"2026_03_15.md": "- Nothing about the position\n",
+		"2026_03_14.md": samplePage,
+		"2026_03_10.md": "- current-position:: 45.0, 13.0\n",
+	})
+
+	pos, err := j.LatestPosition(today, 30)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pos.Lat != 43.296 || pos.Lon != 5.369 || pos.Date.Format("2006-01-02") != "2026-03-14" {
+		t.Errorf("LatestPosition = %+v", pos)
+	}
+
+	if _, err := j.LatestPosition(today, 0); !errors.Is(err, ErrNoPosition) {
+		t.Errorf("LatestPosition over today only = %v, want ErrNoPosition", err)
+	}
+}
+
+func TestContext(t *testing.T) {
+	today := time.Date(2026, 3, 15, 6, 0, 0, 0, time.Local)
+	j := newJournal(t, map[string]string{
+		"2026_03_15.md": "- Today\n",
+		"2026_03_13.md": "- Two days ago\n\n",
+		"2026_03_01.md": "- Too old\n",
+	})
+
+	got, n, err := j.Context(today, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "=== RECENT JOURNAL ENTRIES ===\n\n--- 2026-03-15 ---\n- Today\n\n--- 2026-03-13 ---\n- Two days ago\n\n"
+	if n != 2 || got != want {
+		t.Errorf("Context = %d pages\n%q\nwant\n%q", n, got, want)
+	}
+}
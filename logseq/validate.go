@@ -0,0 +1,198 @@
+package logseq
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Header is the position and place for the header block of a briefing.
+type Header struct {
+	Lat, Lon float64
+	Location string
+}
+
+// Problem is a formatting problem in a generated briefing.
+type Problem struct {
+	Line    int // 1-based, 0 for the briefing as a whole
+	Message string
+}
+
+func (p Problem) String() string {
+	if p.Line == 0 {
+		return p.Message
+	}
+	return fmt.Sprintf("line %d: %s", p.Line, p.Message)
+}
+
+// outlineLine is a line of generated text classified as a block, a continuation
+// of the block above it, or a stray paragraph outside of any block.
+type outlineLine struct {
+	number  int
+	level   int
+	text    string
+	block   bool
+	stray   bool
+	spaces  bool // indented with spaces instead of tabs
+	skipped int  // levels skipped relative to the block above
+}
+
+// classify splits generated text into outline lines. Indentation with spaces is
+// converted to levels using the smallest space indent of a block as one level.
+func classify(text string) (lines []outlineLine, fenced bool) {
+	raw := strings.Split(strings.TrimSpace(text), "\n")
+	if len(raw) > 0 && strings.HasPrefix(raw[0], "```") {
+		fenced = true
+		raw = raw[1:]
+		if n := len(raw); n > 0 && strings.TrimSpace(raw[n-1]) == "```" {
+			raw = raw[:n-1]
+		}
+	}
+
+	unit := 0
+	for _, line := range raw {
+		trimmed := strings.TrimLeft(line, " \t")
+		ws := line[:len(line)-len(trimmed)]
+		if isBlockLine(trimmed) && ws != "" && strings.Trim(ws, " ") == "" && (unit == 0 || len(ws) < unit) {
+			unit = len(ws)
+		}
+	}
+	if unit == 0 {
+		unit = 2
+	}
+
+	prevLevel := -1
+	for i, line := range raw {
+		trimmed := strings.TrimLeft(line, " \t")
+		if trimmed == "" {
+			continue
+		}
+		ws := line[:len(line)-len(trimmed)]
+		tabs := strings.Count(ws, "\t")
+		spaces := len(ws) - tabs
+		l := outlineLine{number: i + 1, text: strings.TrimRight(trimmed, " \t")}
+		if fenced {
+			l.number++
+		}
+
+		switch {
+		case isBlockLine(trimmed):
+			l.block = true
+			l.level = tabs + spaces/unit
+			l.spaces = spaces > 0
+			l.text = strings.TrimPrefix(strings.TrimPrefix(l.text, "-"), " ")
+			if l.level > prevLevel+1 {
+				l.skipped = l.level - prevLevel - 1
+				l.level = prevLevel + 1
+			}
+			prevLevel = l.level
+		case prevLevel >= 0 && (ws != "" || isPropertyLine(trimmed)):
+			l.level = prevLevel
+		default:
+			l.stray = true
+			l.text = strings.TrimSpace(strings.TrimLeft(l.text, "#"))
+		}
+		lines = append(lines, l)
+	}
+	return lines, fenced
+}
+
+func isBlockLine(trimmed string) bool {
+	return trimmed == "-" || strings.HasPrefix(trimmed, "- ")
+}
+
+func isPropertyLine(trimmed string) bool {
+	_, _, ok := parseProperty(trimmed)
+	return ok
+}
+
+// ValidateBriefing checks that generated text is a well-formed Logseq briefing:
+// tab-indented blocks only, no level skipped, no text outside of blocks, and a
+// [[Tagesbriefing]] header block with position:: and location:: first, with every
+// other block below it.
+func ValidateBriefing(text string) []Problem {
+	lines, fenced := classify(text)
+	var problems []Problem
+	if fenced {
+		problems = append(problems, Problem{Message: "the briefing is wrapped in a code fence"})
+	}
+	topLevel := 0
+	for _, l := range lines {
+		if l.block && l.level == 0 {
+			topLevel++
+		}
+		switch {
+		case l.block && l.level == 0 && topLevel > 1:
+			// ReplaceBriefing only replaces the header's subtree, so a block beside
+			// it would be left behind on every rerun.
+			problems = append(problems, Problem{l.number, "top-level block after the header, indent it under the header"})
+		case l.stray:
+			problems = append(problems, Problem{l.number, "text outside of a block"})
+		case l.block && l.spaces:
+			problems = append(problems, Problem{l.number, "indented with spaces instead of tabs"})
+		}
+		if l.skipped > 0 {
+			problems = append(problems, Problem{l.number, "indented more than one level below the block above"})
+		}
+	}
+
+	page := Parse(text)
+	switch {
+	case len(page.Blocks) == 0 || !strings.HasPrefix(page.Blocks[0].Content, BriefingTag):
+		problems = append(problems, Problem{Message: "the briefing does not start with the " + BriefingTag + " header block"})
+	case !IsBriefing(page.Blocks[0]):
+		problems = append(problems, Problem{Message: "the header block has no child with position:: and location::"})
+	}
+	return problems
+}
+
+// RepairBriefing rewrites generated text into a well-formed briefing: code fences
+// are removed, indentation is converted to tabs and skipped levels are closed, text
+// before the header is dropped and other stray paragraphs become blocks, top-level
+// blocks after the header are moved below it, and a missing header or header
+// properties are added from h.
+func RepairBriefing(text string, h Header) string {
+	lines, _ := classify(text)
+
+	// Drop the model's preamble before the header block.
+	for i, l := range lines {
+		if l.block && l.level == 0 && strings.HasPrefix(l.text, BriefingTag) {
+			lines = lines[i:]
+			break
+		}
+	}
+	hasHeader := len(lines) > 0 && lines[0].block && strings.HasPrefix(lines[0].text, BriefingTag)
+
+	var b strings.Builder
+	shift := 0
+	if !hasHeader {
+		b.WriteString("- " + BriefingTag + "\n")
+		shift = 1 // everything becomes a child of the new header
+	}
+
+	level := 0
+	moved := 0 // 1 from the first top-level block after the header on
+	for i, l := range lines {
+		if hasHeader && i > 0 && l.block && l.level == 0 {
+			moved = 1
+		}
+		switch {
+		case l.stray:
+			// A stray paragraph becomes a block directly below the header.
+			level = 1 - shift
+			b.WriteString(strings.Repeat("\t", level+shift) + "- " + l.text + "\n")
+		case l.block:
+			level = l.level + moved
+			b.WriteString(strings.Repeat("\t", level+shift) + "- " + l.text + "\n")
+		default:
+			b.WriteString(strings.Repeat("\t", level+shift) + "  " + l.text + "\n")
+		}
+	}
+
+	repaired := b.String()
+	if page := Parse(repaired); !IsBriefing(page.Blocks[0]) {
+		header, rest, _ := strings.Cut(repaired, "\n")
+		props := fmt.Sprintf("\t- position:: %.5f, %.5f\n\t  location:: %s\n", h.Lat, h.Lon, h.Location)
+		repaired = header + "\n" + props + rest
+	}
+	return repaired
+}
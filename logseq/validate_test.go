@@ -0,0 +1,75 @@
+package logseq
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateBriefing(t *testing.T) {
+	if problems := ValidateBriefing(briefing("Split", "Wetter")); len(problems) != 0 {
+		t.Errorf("valid briefing has problems: %v", problems)
+	}
+
+	tests := []struct {
+		name, text, want string
+	}{
+		{"spaces", "- [[Tagesbriefing]]\n  - position:: 43.5, 16.4\n    location:: Split\n", "line 2: indented with spaces instead of tabs"},
+		{"stray", briefing("Split", "Wetter") + "Viel Spass!\n", "line 5: text outside of a block"},
+		{"skipped level", briefing("Split", "Wetter") + "\t\t\t- Wind\n", "line 5: indented more than one level below the block above"},
+		{"no header", "- Wetter\n\t- Wind\n", "does not start with the [[Tagesbriefing]] header block"},
+		{"no properties", "- [[Tagesbriefing]]\n\t- Wetter\n", "header block has no child with position:: and location::"},
+		{"sibling", briefing("Split", "Wetter") + "- Ausblick\n", "line 5: top-level block after the header, indent it under the header"},
+		{"fence", "```\n" + briefing("Split", "Wetter") + "```\n", "wrapped in a code fence"},
+	}
+	for _, tt := range tests {
+		var found []string
+		for _, p := range ValidateBriefing(tt.text) {
+			found = append(found, p.String())
+		}
+		if !strings.Contains(strings.Join(found, "\n"), tt.want) {
+			t.Errorf("%s: problems %q, want %q", tt.name, found, tt.want)
+		}
+	}
+}
+
+func TestRepairBriefing(t *testing.T) {
+	h := Header{Lat: 43.508, Lon: 16.44, Location: "Split, Croatia"}
+	tests := []struct {
+		name, text, want string
+	}{
+		{
+			"spaces and preamble",
+			"```markdown\nHier ist das Briefing:\n\n- [[Tagesbriefing]]\n    - position:: 43.5, 16.4\n      location:: Split\n    - Wetter\n        - Wind NW 12 kn\n```\n",
+			"- [[Tagesbriefing]]\n\t- position:: 43.5, 16.4\n\t  location:: Split\n\t- Wetter\n\t\t- Wind NW 12 kn\n",
+		},
+		{
+			"stray paragraphs and skipped level",
+			"- [[Tagesbriefing]]\n\t- position:: 43.5, 16.4\n\t  location:: Split\n## Wetter\n\t\t\t- Wind NW 12 kn\nGute Fahrt!\n",
+			"- [[Tagesbriefing]]\n\t- position:: 43.5, 16.4\n\t  location:: Split\n\t- Wetter\n\t\t- Wind NW 12 kn\n\t- Gute Fahrt!\n",
+		},
+		{
+			"missing header",
+			"- Wetter\n\t- Wind NW 12 kn\n",
+			"- [[Tagesbriefing]]\n\t- position:: 43.50800, 16.44000\n\t  location:: Split, Croatia\n\t- Wetter\n\t\t- Wind NW 12 kn\n",
+		},
+		{
+			"top-level blocks after the header",
+			"- [[Tagesbriefing]]\n\t- position:: 43.5, 16.4\n\t  location:: Split\n- Wetter\n\t- Wind NW 12 kn\n\t  Böen 20 kn\n- Ausblick\n",
+			"- [[Tagesbriefing]]\n\t- position:: 43.5, 16.4\n\t  location:: Split\n\t- Wetter\n\t\t- Wind NW 12 kn\n\t\t  Böen 20 kn\n\t- Ausblick\n",
+		},
+		{
+			"missing properties",
+			"- [[Tagesbriefing]]\n\t- Wetter\n",
+			"- [[Tagesbriefing]]\n\t- position:: 43.50800, 16.44000\n\t  location:: Split, Croatia\n\t- Wetter\n",
+		},
+	}
+	for _, tt := range tests {
+		got := RepairBriefing(tt.text, h)
+		if got != tt.want {
+			t.Errorf("%s: repaired =\n%s\nwant\n%s", tt.name, got, tt.want)
+		}
+		if problems := ValidateBriefing(got); len(problems) != 0 {
+			t.Errorf("%s: repaired briefing has problems: %v", tt.name, problems)
+		}
+	}
+}
@@ -0,0 +1,211 @@
+package logseq
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// BriefingTag opens the header block of a daily briefing.
+const BriefingTag = "[[Tagesbriefing]]"
+
+// versionProperty marks a collapsed child block holding a replaced briefing.
+const versionProperty = "replaced"
+
+const (
+	writeAttempts   = 3
+	writeRetryDelay = time.Second
+)
+
+// ErrConflict is returned when the page keeps changing while we write it, e.g.
+// because it is being edited in Logseq.
+var ErrConflict = errors.New("journal page was modified while writing")
+
+// IsBriefing reports whether a top-level block is a daily briefing: a
+// [[Tagesbriefing]] block whose header child has position:: and location::.
+func IsBriefing(b *Block) bool {
+	if !strings.HasPrefix(b.Content, BriefingTag) {
+		return false
+	}
+	for _, c := range b.Children {
+		_, hasPosition := c.Property("position")
+		_, hasLocation := c.Property("location")
+		if hasPosition && hasLocation {
+			return true
+		}
+	}
+	return false
+}
+
+// WriteBriefing puts the briefing on the page for the date. An existing briefing
+// is replaced in place and kept, with up to keepVersions older versions, as
+// collapsed child blocks of the new one; without one the briefing is appended.
+// The page is written to a temporary file and renamed over the original, and the
+// write is retried if the page changed since it was read.
+func (j *Journal) WriteBriefing(date time.Time, briefing string, keepVersions int) error {
+	for attempt := 1; ; attempt++ {
+		err := j.writeBriefing(date, briefing, keepVersions, time.Now())
+		if !errors.Is(err, ErrConflict) || attempt == writeAttempts {
+			return err
+		}
+		time.Sleep(writeRetryDelay)
+	}
+}
+
+func (j *Journal) writeBriefing(date time.Time, briefing string, keepVersions int, now time.Time) error {
+	path := j.PagePath(date)
+	var modTime time.Time
+	info, err := os.Stat(path)
+	switch {
+	case err == nil:
+		modTime = info.ModTime()
+	case !errors.Is(err, fs.ErrNotExist):
+		return err
+	}
+
+	existing, err := j.ReadPage(date)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return writeAtomic(path, []byte(ReplaceBriefing(existing, briefing, keepVersions, now)), modTime)
+}
+
+// ReplaceBriefing returns the page text with the briefing in place of the existing
+// briefing blocks, which become collapsed previous versions of it, newest first.
+// Without an existing briefing, the briefing is appended after an empty line.
+func ReplaceBriefing(page, briefing string, keepVersions int, now time.Time) string {
+	briefing = strings.TrimRight(briefing, "\n") + "\n"
+	spans := splitTopLevel(page)
+
+	first := -1
+	var versions []string
+	for i := len(spans) - 1; i >= 0; i-- {
+		parsed := Parse(spans[i])
+		if len(parsed.Blocks) != 1 || !IsBriefing(parsed.Blocks[0]) {
+			continue
+		}
+		content, older := splitVersions(spans[i])
+		versions = append(versions, versionBlock(content, now))
+		versions = append(versions, older...)
+		first = i
+		spans[i] = ""
+	}
+
+	if first < 0 {
+		if strings.TrimSpace(page) == "" {
+			return briefing
+		}
+		return strings.TrimRight(page, "\n") + "\n\n" + briefing
+	}
+
+	if len(versions) > keepVersions {
+		versions = versions[:max(keepVersions, 0)]
+	}
+	spans[first] = briefing + strings.Join(versions, "")
+	return strings.Join(spans, "")
+}
+
+// splitTopLevel splits page text into runs of lines that each start with a
+// top-level block. Text before the first block forms its own run.
+func splitTopLevel(text string) []string {
+	var spans []string
+	var cur strings.Builder
+	for _, line := range strings.SplitAfter(text, "\n") {
+		if line == "" {
+			continue
+		}
+		if (strings.HasPrefix(line, "- ") || strings.TrimRight(line, "\n") == "-") && cur.Len() > 0 {
+			spans = append(spans, cur.String())
+			cur.Reset()
+		}
+		cur.WriteString(line)
+	}
+	if cur.Len() > 0 {
+		spans = append(spans, cur.String())
+	}
+	if len(spans) > 0 && !strings.HasSuffix(spans[len(spans)-1], "\n") {
+		spans[len(spans)-1] += "\n"
+	}
+	return spans
+}
+
+// splitVersions separates the child blocks of a briefing into its content and its
+// earlier previous-version blocks. The briefing's own first line is dropped.
+func splitVersions(span string) (string, []string) {
+	var children []string
+	for _, line := range strings.SplitAfter(span, "\n")[1:] {
+		if strings.HasPrefix(line, "\t- ") || strings.TrimRight(line, "\n") == "\t-" || len(children) == 0 {
+			children = append(children, line)
+			continue
+		}
+		children[len(children)-1] += line
+	}
+
+	var content strings.Builder
+	var versions []string
+	for _, child := range children {
+		if b := Parse(child).Blocks; len(b) == 1 {
+			if _, ok := b[0].Property(versionProperty); ok {
+				versions = append(versions, child)
+				continue
+			}
+		}
+		content.WriteString(child)
+	}
+	return content.String(), versions
+}
+
+// versionBlock renders replaced briefing content as a collapsed child block.
+func versionBlock(content string, replaced time.Time) string {
+	var b strings.Builder
+	b.WriteString("\t- previous version\n")
+	b.WriteString(fmt.Sprintf("\t  %s:: %s\n", versionProperty, replaced.Format("2006-01-02T15:04")))
+	b.WriteString("\t  collapsed:: true\n")
+	for _, line := range strings.SplitAfter(content, "\n") {
+		if strings.TrimSpace(line) != "" {
+			b.WriteString("\t" + line)
+		}
+	}
+	return b.String()
+}
+
+// writeAtomic writes data to a temporary file next to path and renames it over
+// path. It returns ErrConflict if path was modified (or created) after modTime,
+// the modification time when it was read; a zero modTime means it did not exist.
+func writeAtomic(path string, data []byte, modTime time.Time) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return err
+	}
+
+	info, err := os.Stat(path)
+	switch {
+	case err == nil && !info.ModTime().Equal(modTime):
+		return ErrConflict
+	case err != nil && !errors.Is(err, fs.ErrNotExist):
+		return err
+	case err != nil && !modTime.IsZero():
+		return ErrConflict // deleted in the meantime
+	}
+	return os.Rename(tmp.Name(), path)
+}
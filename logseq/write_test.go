@@ -0,0 +1,95 @@
+package logseq
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func briefing(place, text string) string {
+	return "- [[Tagesbriefing]]\n\t- position:: 43.5, 16.4\n\t  location:: " + place + "\n\t- " + text + "\n"
+}
+
+func TestReplaceBriefing(t *testing.T) {
+	now := time.Date(2026, 3, 15, 7, 10, 0, 0, time.UTC)
+	log := "- Logbook\n\t- current_position:: 43.5/16.4\n"
+
+	// No briefing yet: append.
+	page := ReplaceBriefing(log, briefing("Split", "first"), 2, now)
+	if want := log + "\n" + briefing("Split", "first"); page != want {
+		t.Fatalf("first write =\n%s\nwant\n%s", page, want)
+	}
+
+	// A second run replaces it and keeps the first as a collapsed child.
+	page = ReplaceBriefing(page+"- Later note\n", briefing("Split", "second"), 2, now)
+	want := log + "\n" + briefing("Split", "second") +
+		"\t- previous version\n\t  replaced:: 2026-03-15T07:10\n\t  collapsed:: true\n" +
+		"\t\t- position:: 43.5, 16.4\n\t\t  location:: Split\n\t\t- first\n" +
+		"- Later note\n"
+	if page != want {
+		t.Fatalf("second write =\n%s\nwant\n%s", page, want)
+	}
+	if n := strings.Count(page, BriefingTag); n != 1 {
+		t.Errorf("page has %d briefings, want 1", n)
+	}
+
+	// Versions are kept newest first and capped.
+	page = ReplaceBriefing(page, briefing("Split", "third"), 2, now)
+	page = ReplaceBriefing(page, briefing("Split", "fourth"), 2, now)
+	if strings.Count(page, "previous version") != 2 || !strings.Contains(page, "\t\t- third\n") || strings.Contains(page, "- first") {
+		t.Errorf("after four writes:\n%s", page)
+	}
+	if strings.Index(page, "- third") > strings.Index(page, "- second") {
+		t.Errorf("versions not newest first:\n%s", page)
+	}
+
+	// Duplicates from earlier appends are folded into one briefing.
+	dup := briefing("Split", "a") + "\n" + briefing("Split", "b")
+	page = ReplaceBriefing(dup, briefing("Split", "c"), 5, now)
+	if strings.Count(page, BriefingTag) != 1 || strings.Count(page, "previous version") != 2 {
+		t.Errorf("duplicates not merged:\n%s", page)
+	}
+
+	// A plain [[Tagesbriefing]] reference is not a briefing.
+	note := "- Read the [[Tagesbriefing]]\n"
+	if page := ReplaceBriefing(note, briefing("Split", "x"), 2, now); !strings.HasPrefix(page, note) {
+		t.Errorf("note was replaced:\n%s", page)
+	}
+}
+
+func TestWriteBriefingConflict(t *testing.T) {
+	today := time.Date(2026, 3, 15, 6, 0, 0, 0, time.Local)
+	j := newJournal(t, map[string]string{"2026_03_15.md": "- Logbook\n"})
+	path := j.PagePath(today)
+
+	if err := j.WriteBriefing(today, briefing("Split", "first"), 3); err != nil {
+		t.Fatal(err)
+	}
+	if err := j.WriteBriefing(today, briefing("Split", "second"), 3); err != nil {
+		t.Fatal(err)
+	}
+	text, _ := j.ReadPage(today)
+	if strings.Count(text, BriefingTag) != 1 || !strings.Contains(text, "- second") {
+		t.Errorf("page after two writes:\n%s", text)
+	}
+
+	// The page changes after it was read.
+	info, _ := os.Stat(path)
+	if err := os.WriteFile(path, []byte("- Edited in Logseq\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	os.Chtimes(path, info.ModTime().Add(time.Second), info.ModTime().Add(time.Second))
+	if err := writeAtomic(path, []byte("overwritten"), info.ModTime()); !errors.Is(err, ErrConflict) {
+		t.Errorf("writeAtomic over a changed page = %v, want ErrConflict", err)
+	}
+	if text, _ := j.ReadPage(today); text != "- Edited in Logseq\n" {
+		t.Errorf("conflicting write changed the page: %q", text)
+	}
+
+	entries, _ := os.ReadDir(j.Dir)
+	if len(entries) != 1 {
+		t.Errorf("temporary files left behind: %v", entries)
+	}
+}
@@ -1,30 +1,290 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
+	"time"
 )
 
 func main() {
-	lat := flag.Float64("lat", 0, "Latitude of the current position (required)")
-	lon := flag.Float64("lon", 0, "Longitude of the current position (required)")
-	lang := flag.String("lang", "de", "Language for the briefing (e.g. de, en, fr)")
-	promptPath := flag.String("prompt", "", "Path to the system prompt markdown file (default: prompt.md next to binary)")
-	flag.Parse()
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "run":
+			runJournal(os.Args[2:])
+			return
+		case "plan":
+			runPlan(os.Args[2:])
+			return
+		case "watch":
+			runWatch(os.Args[2:])
+			return
+		case "grib":
+			runGRIB(os.Args[2:])
+			return
+		}
+	}
+	runBriefing(os.Args[1:])
+}
 
-	if *lat == 0 && *lon == 0 {
-		fmt.Fprintln(os.Stderr, "Error: --lat and --lon are required")
-		fmt.Fprintln(os.Stderr, "Usage: briefing --lat <latitude> --lon <longitude> [--lang <language>] [--prompt <prompt.md>]")
+// commonFlags are shared by the briefing and all subcommands.
+type commonFlags struct {
+	configPath string
+	cacheDir   string
+	cacheTTL   string
+	noCache    bool
+	unitsName  string
+	units      UnitSystem
+
+	weatherNames stringList
+	openMeteoURL string
+	metNorwayURL string
+	weather      []WeatherProvider
+}
+
+func registerCommonFlags(fs *flag.FlagSet) *commonFlags {
+	c := &commonFlags{}
+	fs.StringVar(&c.configPath, "config", "", "Path to a KEY=VALUE config file (e.g. config.env); flags override it")
+	fs.StringVar(&c.cacheDir, "cache-dir", defaultCacheDir(), "Directory for cached weather, location and model responses")
+	fs.StringVar(&c.cacheTTL, "cache-ttl", "", "Per-source cache TTLs, e.g. forecast=30m,marine=1h,geocode=720h,llm=6h")
+	fs.BoolVar(&c.noCache, "no-cache", false, "Disable the response cache")
+	fs.StringVar(&c.unitsName, "units", string(UnitsMetric), "Units for wind and waves: metric (km/h, m), nautical (kn, m) or imperial (mph, ft)")
+	c.weatherNames = stringList{ProviderOpenMeteo}
+	fs.Var(&c.weatherNames, "weather", "Weather providers in order of preference: open-meteo, met-no or file:<GRIB2 file>; later ones fill in missing values")
+	fs.StringVar(&c.openMeteoURL, "open-meteo-url", "", "Base URL of a self-hosted Open-Meteo instance serving all its APIs (default: the public API hosts)")
+	fs.StringVar(&c.metNorwayURL, "metno-url", metNorwayURL, "Base URL of the MET Norway weather API")
+	return c
+}
+
+// parse parses the command line, applies the config file and sets up the response
+// cache. It exits on errors.
+func (c *commonFlags) parse(fs *flag.FlagSet, args []string) {
+	fs.Parse(args)
+
+	if c.configPath != "" {
+		cfg, err := loadConfigFile(c.configPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading config file: %v\n", err)
+			os.Exit(1)
+		}
+		if err := applyConfig(fs, cfg); err != nil {
+			fmt.Fprintf(os.Stderr, "Error applying config: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	units, err := ParseUnitSystem(c.unitsName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
+	c.units = units
 
-	promptFile := resolvePromptPath(*promptPath)
-	promptText, err := os.ReadFile(promptFile)
+	if c.weather, err = ParseWeatherProviders(c.weatherNames, c.openMeteoURL, c.metNorwayURL); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if !c.noCache {
+		ttls, err := parseCacheTTLs(c.cacheTTL)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		responseCache = NewResponseCache(c.cacheDir, ttls)
+	}
+}
+
+// stringList is a comma-separated flag value.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(s string) error {
+	var values []string
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			values = append(values, part)
+		}
+	}
+	*l = values
+	return nil
+}
+
+// briefingOptions are the flags of the briefing, shared by the default command and run.
+type briefingOptions struct {
+	lang       string
+	promptPath string
+	backend    string
+	modelName  string
+	baseURL    string
+	route      string
+	speed      float64
+	depart     string
+	offline    bool
+	structured bool
+	notifyPath string
+	notify     NotifyConfig
+	nmea       string
+	nmeaListen time.Duration
+	signalK    string
+	skToken    string
+	skPublish  bool
+	models     stringList
+	ensemble   string
+	grib       string
+	onboard    *OnboardObservations // nil without --nmea/--signalk or when they could not be read
+	th         *HazardThresholds
+	vessel     *VesselProfile
+	common     *commonFlags
+}
+
+func registerBriefingFlags(fs *flag.FlagSet) *briefingOptions {
+	o := &briefingOptions{}
+	fs.StringVar(&o.lang, "lang", "de", "Language for the briefing (e.g. de, en, fr)")
+	fs.StringVar(&o.promptPath, "prompt", "", "Path to the system prompt markdown file (default: prompt.md next to binary)")
+	fs.StringVar(&o.backend, "backend", BackendOpenAI, "Language model backend: openai, anthropic or local (Ollama/llama.cpp)")
+	fs.StringVar(&o.modelName, "model", "", "Model name for the backend (default depends on the backend)")
+	fs.StringVar(&o.baseURL, "base-url", "", "API base URL for the backend (default depends on the backend)")
+	fs.StringVar(&o.route, "route", "", "Planned route as a GPX file or inline waypoints \"lat,lon;name=lat,lon;...\"")
+	fs.Float64Var(&o.speed, "speed", 5, "Expected average speed along the route (kn)")
+	fs.StringVar(&o.depart, "depart", "", "Departure time on the route as YYYY-MM-DDTHH:MM local time (default: now)")
+	fs.BoolVar(&o.structured, "structured", false, "Ask the model for JSON matching the briefing schema and render it with the Logseq template")
+	fs.BoolVar(&o.offline, "offline", false, "Build a briefing from cached weather and the journal context, without network or language model")
+	fs.StringVar(&o.notifyPath, "notify", "", "JSON file with the channels (SMTP, Matrix, webhooks) to send the briefing and warnings to")
+	fs.StringVar(&o.nmea, "nmea", "", "NMEA 0183 source for the GPS fix and instrument data: tcp://host:port or udp://:port")
+	fs.DurationVar(&o.nmeaListen, "nmea-listen", 5*time.Second, "How long to read from the NMEA source")
+	fs.StringVar(&o.signalK, "signalk", "", "Signal K server URL for the position, instrument data and vessel name, e.g. http://localhost:3000")
+	fs.StringVar(&o.skToken, "signalk-token", "", "Signal K access token")
+	fs.BoolVar(&o.skPublish, "signalk-publish", false, "Publish the hazard warnings as Signal K notifications")
+	fs.Var(&o.models, "models", "Forecast models to compare for the forecast confidence, e.g. icon_seamless,ecmwf_ifs025,gfs_seamless,meteofrance_seamless")
+	fs.StringVar(&o.ensemble, "ensemble", "", "Ensemble model for the member spread, e.g. ecmwf_ifs025, icon_seamless or gfs025")
+	fs.StringVar(&o.grib, "grib", "", "GRIB2 file to read the weather from instead of Open-Meteo, e.g. a Saildocs download; short for --weather file:<path>")
+	o.th = hazardFlags(fs)
+	o.vessel = vesselFlags(fs)
+	o.common = registerCommonFlags(fs)
+	return o
+}
+
+// parse parses the command line like commonFlags.parse and loads the notifier
+// configuration. It exits on errors.
+func (o *briefingOptions) parse(fs *flag.FlagSet, args []string) {
+	o.common.parse(fs, args)
+	if o.grib != "" {
+		// --grib is short for --weather file:<path>.
+		o.common.weather = []WeatherProvider{&GRIBFile{Path: o.grib}}
+	}
+	o.notify = loadNotify(o.notifyPath, o.common.cacheDir)
+}
+
+// gribFile returns the first GRIB file among the weather providers, or nil.
+func (c *commonFlags) gribFile() *GRIBFile {
+	for _, p := range c.weather {
+		if g, ok := p.(*GRIBFile); ok {
+			return g
+		}
+	}
+	return nil
+}
+
+// loadNotify loads the notifier configuration; an empty path means no channels.
+// Dead letters go to the cache directory unless configured otherwise. It exits on errors.
+func loadNotify(path, cacheDir string) NotifyConfig {
+	if path == "" {
+		return NotifyConfig{}
+	}
+	cfg, err := LoadNotifyConfig(path)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error reading prompt file %s: %v\n", promptFile, err)
+		fmt.Fprintf(os.Stderr, "Error reading notifier config: %v\n", err)
+		os.Exit(1)
+	}
+	if cfg.DeadLetterDir == "" {
+		cfg.DeadLetterDir = filepath.Join(cacheDir, "dead-letter")
+	}
+	return cfg
+}
+
+// readOnboard reads the GPS fix and instrument data from the NMEA source, or else
+// from the Signal K server. The briefing does without them if neither can be read.
+func (o *briefingOptions) readOnboard() {
+	if o.nmea != "" {
+		fmt.Fprintf(os.Stderr, "Reading NMEA data from %s...\n", o.nmea)
+		obs, err := CollectNMEA(o.nmea, o.nmeaListen)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		} else {
+			o.onboard = &obs
+		}
+	}
+	if o.onboard == nil && o.signalK != "" {
+		fmt.Fprintf(os.Stderr, "Reading Signal K data from %s...\n", o.signalK)
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		vessel, err := NewSignalKClient(o.signalK, o.skToken).Self(ctx)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+			return
+		}
+		if o.vessel.Name == "" {
+			o.vessel.Name = vessel.Name
+		}
+		o.onboard = &vessel.Observations
+	}
+	if o.onboard != nil && o.onboard.Fix != nil {
+		fmt.Fprintf(os.Stderr, "GPS position: %.5f, %.5f\n", o.onboard.Fix.Lat, o.onboard.Fix.Lon)
+	}
+}
+
+// deliver sends the briefing to the notifier channels and the hazard warnings to
+// Signal K. A failed channel does not fail the briefing; its message is kept in the
+// dead-letter directory.
+func (o *briefingOptions) deliver(briefing string, in BriefingInput) {
+	if o.skPublish && o.signalK != "" {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if err := NewSignalKClient(o.signalK, o.skToken).PublishHazards(ctx, in.Hazards, in.Weather.Units); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: publishing to Signal K failed: %v\n", err)
+		}
+	}
+	if len(o.notify.Channels) == 0 {
+		return
+	}
+	if err := Deliver(context.Background(), o.notify, NewOutput(briefing, in, time.Now()), o.lang); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: delivery failed, see %s:\n%v\n", o.notify.DeadLetterDir, err)
+	}
+}
+
+// runBriefing generates the daily briefing for a position.
+func runBriefing(args []string) {
+	fs := flag.NewFlagSet("briefing", flag.ExitOnError)
+	lat := fs.Float64("lat", 0, "Latitude of the current position (required)")
+	lon := fs.Float64("lon", 0, "Longitude of the current position (required)")
+	format := fs.String("format", FormatLogseq, "Output format: "+strings.Join(OutputFormats, ", "))
+	opts := registerBriefingFlags(fs)
+	opts.parse(fs, args)
+
+	opts.readOnboard()
+	if *lat == 0 && *lon == 0 && opts.onboard != nil && opts.onboard.Fix != nil {
+		*lat, *lon = opts.onboard.Fix.Lat, opts.onboard.Fix.Lon
+	}
+	if *lat == 0 && *lon == 0 {
+		fmt.Fprintln(os.Stderr, "Error: --lat and --lon, or --nmea or --signalk with a GPS fix are required")
+		fmt.Fprintln(os.Stderr, "Usage: briefing --lat <latitude> --lon <longitude> [--nmea tcp://<host>:<port> | --signalk <url>] [--lang <language>] [--prompt <prompt.md>] [--backend openai|anthropic|local] [--route <route.gpx|waypoints> [--speed <kn>]] [--format logseq|obsidian|markdown|html|json] [--config <config.env>] [--offline]")
+		fmt.Fprintln(os.Stderr, "       briefing run --saillog <dir> [briefing flags]")
+		fmt.Fprintln(os.Stderr, "       briefing plan --lat <latitude> --lon <longitude> --to <lat,lon|place> [--speed <kn>]")
+		fmt.Fprintln(os.Stderr, "       briefing watch --lat <latitude> --lon <longitude> [--interval <duration>] [--notify <notify.json>]")
+		os.Exit(1)
+	}
+	if !slices.Contains(OutputFormats, *format) {
+		fmt.Fprintf(os.Stderr, "Error: unknown format %q (expected one of %s)\n", *format, strings.Join(OutputFormats, ", "))
 		os.Exit(1)
 	}
 
@@ -34,30 +294,167 @@ func main() {
 		os.Exit(1)
 	}
 
-	fmt.Fprintln(os.Stderr, "Reverse geocoding position...")
-	loc, err := ReverseGeocode(*lat, *lon)
+	var briefing string
+	var in BriefingInput
+	if opts.offline {
+		briefing, in = opts.offlineBriefing(*lat, *lon, stdinContext)
+	} else if briefing, in, err = opts.briefing(*lat, *lon, stdinContext); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	out, err := RenderOutput(NewOutput(briefing, in, time.Now()), *format)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error geocoding: %v\n", err)
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
+	fmt.Print(out)
+
+	opts.deliver(briefing, in)
+}
+
+// offlineBriefing builds the briefing from the last weather snapshot. It also returns
+// the data the briefing is based on.
+func (o *briefingOptions) offlineBriefing(lat, lon float64, journalContext string) (string, BriefingInput) {
+	fmt.Fprintln(os.Stderr, "Offline mode: using cached weather data...")
+	snap, err := loadSnapshot(o.common.cacheDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+	now := time.Now()
+	if g := o.common.gribFile(); g != nil {
+		o.gribSnapshot(&snap, g, lat, lon)
+	}
+	in := BriefingInput{
+		Location:       Location{Latitude: lat, Longitude: lon},
+		Thresholds:     *o.th,
+		Vessel:         *o.vessel,
+		Onboard:        o.onboard,
+		JournalContext: journalContext,
+		Lang:           o.lang,
+	}
+	if !snap.FetchedAt.IsZero() {
+		in.Weather = trimPastForecast(snap.Weather, now)
+		in.Hazards = EvaluateHazards(in.Weather, *o.th)
+	}
+	return BuildOfflineBriefing(lat, lon, snap, journalContext, o.lang, *o.th, *o.vessel, now), in
+}
+
+// gribSnapshot replaces the weather of the snapshot with the GRIB file, dated by the
+// file's modification time. The place name is kept if the snapshot is from nearby.
+func (o *briefingOptions) gribSnapshot(snap *Snapshot, g *GRIBFile, lat, lon float64) {
+	info, err := os.Stat(g.Path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		return
+	}
+	weather, err := mergeWeather(lat, lon, WeatherOptions{Units: o.common.units, Providers: []WeatherProvider{g}})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		return
+	}
+	if distanceKm(lat, lon, snap.Location.Latitude, snap.Location.Longitude) > offlineLocationRadiusKm {
+		snap.Location = Location{Latitude: lat, Longitude: lon}
+	}
+	snap.FetchedAt, snap.Weather = info.ModTime(), weather
+}
+
+// briefing fetches location and weather and has the language model write the briefing.
+// It also returns the data the briefing was written from.
+func (o *briefingOptions) briefing(lat, lon float64, journalContext string) (string, BriefingInput, error) {
+	var waypoints []Waypoint
+	if o.route != "" {
+		var err error
+		if waypoints, err = loadRoute(o.route); err != nil {
+			return "", BriefingInput{}, fmt.Errorf("reading route: %w", err)
+		}
+		if o.speed <= 0 {
+			return "", BriefingInput{}, errors.New("--speed must be positive")
+		}
+	}
+
+	promptFile := resolvePromptPath(o.promptPath)
+	promptText, err := os.ReadFile(promptFile)
+	if err != nil {
+		return "", BriefingInput{}, fmt.Errorf("reading prompt file %s: %w", promptFile, err)
+	}
+
+	model, err := NewBriefingModel(o.backend, o.modelName, o.baseURL)
+	if err != nil {
+		return "", BriefingInput{}, fmt.Errorf("setting up language model: %w", err)
+	}
+	if responseCache != nil {
+		model = cachedModel{model}
+	}
+
+	fmt.Fprintln(os.Stderr, "Reverse geocoding position...")
+	loc, err := ReverseGeocode(lat, lon)
+	if err != nil && o.common.gribFile() != nil {
+		// Offshore the GRIB file may be all there is; the briefing does without a place name.
+		fmt.Fprintf(os.Stderr, "Warning: geocoding: %v\n", err)
+		loc, err = Location{Latitude: lat, Longitude: lon, DisplayName: fmt.Sprintf("%.5f, %.5f", lat, lon)}, nil
+	}
+	if err != nil {
+		return "", BriefingInput{}, fmt.Errorf("geocoding: %w", err)
+	}
 	fmt.Fprintf(os.Stderr, "Location: %s\n", loc.DisplayName)
 
 	fmt.Fprintln(os.Stderr, "Fetching weather data...")
-	weather, err := FetchWeather(*lat, *lon)
+	weather, err := FetchWeather(lat, lon, WeatherOptions{Units: o.common.units, Models: o.models, Ensemble: o.ensemble, Providers: o.common.weather})
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error fetching weather: %v\n", err)
-		os.Exit(1)
+		return "", BriefingInput{}, fmt.Errorf("fetching weather: %w", err)
 	}
-	fmt.Fprintf(os.Stderr, "Weather: %.1f°C, %s\n", weather.Current.Temperature, weatherCodeToText(weather.Current.WeatherCode))
+	fmt.Fprintf(os.Stderr, "Weather: %s, %s\n", optf("%.1f°C", weather.Current.Temperature), optCondition(weather.Current.WeatherCode))
 
-	fmt.Fprintln(os.Stderr, "Generating briefing via OpenAI...")
-	briefing, err := GenerateBriefing(loc, weather, stdinContext, string(promptText), *lang)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error generating briefing: %v\n", err)
-		os.Exit(1)
+	var passage *Passage
+	if len(waypoints) > 0 {
+		p, err := planPassage(waypoints, o.speed, o.depart, weather.Timezone, o.common.units, o.common.weather)
+		if err != nil {
+			return "", BriefingInput{}, fmt.Errorf("planning passage: %w", err)
+		}
+		fmt.Fprintf(os.Stderr, "Passage: %.1f nm, %d forecast points\n", p.DistanceNm(), len(p.Points))
+		passage = &p
+	}
+
+	if err := saveSnapshot(o.common.cacheDir, Snapshot{FetchedAt: time.Now(), Location: loc, Weather: weather}); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not save weather snapshot: %v\n", err)
+	}
+
+	fmt.Fprintf(os.Stderr, "Generating briefing via %s...\n", model.Name())
+	hazards := EvaluateHazards(weather, *o.th)
+	fmt.Fprintf(os.Stderr, "Hazards: %d\n", len(hazards))
+	sailing := SummarizeSailing(AssessSailing(weather, *o.vessel))
+
+	in := BriefingInput{
+		Location:       loc,
+		Weather:        weather,
+		Hazards:        hazards,
+		Thresholds:     *o.th,
+		Vessel:         *o.vessel,
+		Sailing:        sailing,
+		Passage:        passage,
+		Onboard:        o.onboard,
+		JournalContext: journalContext,
+		Lang:           o.lang,
 	}
 
-	fmt.Print(briefing)
+	if o.structured {
+		doc, err := GenerateStructuredBriefing(model, in, string(promptText))
+		if err != nil {
+			return "", BriefingInput{}, fmt.Errorf("generating briefing: %w", err)
+		}
+		text, err := RenderStructuredBriefing(doc)
+		if err != nil {
+			return "", BriefingInput{}, err
+		}
+		return AddComputedBlocks(text, in), in, nil
+	}
+
+	briefing, err := GenerateBriefing(model, in, string(promptText))
+	if err != nil {
+		return "", BriefingInput{}, fmt.Errorf("generating briefing: %w", err)
+	}
+	return briefing, in, nil
 }
 
 // resolvePromptPath finds the prompt.md file, checking the explicit path first,
@@ -1,6 +1,8 @@
 package main
 
 import (
+	"encoding/json"
+	"strings"
 	"testing"
 )
 
@@ -54,30 +56,30 @@ func TestFormatWeatherData(t *testing.T) {
 	data := WeatherData{
 		Timezone: "Europe/Berlin",
 		Current: CurrentWeather{
-			Temperature:   22.5,
-			WindSpeed:     15.0,
-			WindDirection: 180,
-			WeatherCode:   1,
-			Humidity:      65,
-			Pressure:      1013,
-			CloudCover:    30,
-			Precipitation: 0,
+			Temperature:   ptr(22.5),
+			WindSpeed:     ptr(15.0),
+			WindDirection: ptr(180.0),
+			WeatherCode:   ptr(1),
+			Humidity:      ptr(65),
+			Pressure:      ptr(1013.0),
+			CloudCover:    ptr(30),
+			Precipitation: ptr(0.0),
 		},
 		Daily: []DailyForecast{
 			{
 				Date:              "2026-03-15",
-				TempMax:           25,
-				TempMin:           15,
-				PrecipitationSum:  0,
-				PrecipitationProb: 10,
-				WindSpeedMax:      20,
-				WindDirection:     180,
-				WeatherCode:       1,
+				TempMax:           ptr(25.0),
+				TempMin:           ptr(15.0),
+				PrecipitationSum:  ptr(0.0),
+				PrecipitationProb: ptr(10),
+				WindSpeedMax:      ptr(20.0),
+				WindDirection:     ptr(180.0),
+				WeatherCode:       ptr(1),
 			},
 		},
 	}
 
-	result := FormatWeatherData(data)
+	result := FormatWeatherData(data, DefaultHazardThresholds)
 
 	if result == "" {
 		t.Error("FormatWeatherData returned empty string")
@@ -98,6 +100,60 @@ func TestFormatWeatherData(t *testing.T) {
 	}
 }
 
+func TestFormatWeatherDataMissingValues(t *testing.T) {
+	data := WeatherData{
+		Timezone: "UTC",
+		Current:  CurrentWeather{Temperature: ptr(18.0), WindSpeed: ptr(0.0), WindDirection: ptr(90.0)},
+		Hourly: []HourlyForecast{
+			{Time: "2026-03-15T12:00", WindSpeed: ptr(10.0), WindGusts: ptr(14.0), WindDirection: ptr(90.0), WeatherCode: ptr(1)},
+			{Time: "2026-03-15T13:00"},
+		},
+		Marine:       MarineData{WaveHeight: ptr(0.0), WavePeriod: ptr(0.0)},
+		HourlyMarine: []HourlyMarine{{Time: "2026-03-15T12:00", WaveHeight: ptr(0.0)}},
+	}
+
+	result := FormatWeatherData(data, DefaultHazardThresholds)
+	for _, check := range []string{
+		"Wind: 0.0 km/h",
+		"gusts n/a",
+		"Humidity: n/a",
+		"=== CURRENT MARINE CONDITIONS ===", // a flat calm is still a reported sea state
+		"Wave height: 0.0m",
+		"Hourly forecast: 2 hours, missing temperature (all), wind (1 of 2: 2026-03-15T13:00)",
+		"Do not read them as zero",
+	} {
+		if !strings.Contains(result, check) {
+			t.Errorf("FormatWeatherData output missing %q in:\n%s", check, result)
+		}
+	}
+
+	data.Marine, data.HourlyMarine = MarineData{}, nil
+	result = FormatWeatherData(data, DefaultHazardThresholds)
+	if strings.Contains(result, "MARINE") {
+		t.Errorf("marine section without marine data:\n%s", result)
+	}
+	if !strings.Contains(result, "Marine data: not available for this position, the sea state is unknown (not calm)") {
+		t.Errorf("data quality does not report the missing marine data:\n%s", result)
+	}
+}
+
+func TestDecodeNulls(t *testing.T) {
+	var resp openMeteoWeatherResponse
+	body := `{"hourly": {"time": ["2026-03-15T12:00", "2026-03-15T13:00"], "wind_speed_10m": [0, null], "weather_code": [null, 3]}}`
+	if err := json.Unmarshal([]byte(body), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if v := safeIndex(resp.Hourly.WindSpeed10m, 0); v == nil || *v != 0 {
+		t.Errorf("wind at 12:00 = %v, want a reported 0", v)
+	}
+	if v := safeIndex(resp.Hourly.WindSpeed10m, 1); v != nil {
+		t.Errorf("wind at 13:00 = %v, want nil for null", *v)
+	}
+	if v := safeIndex(resp.Hourly.WeatherCode, 0); v != nil {
+		t.Errorf("weather code at 12:00 = %v, want nil for null", *v)
+	}
+}
+
 func TestResolvePromptPath(t *testing.T) {
 	// Explicit path always wins
 	got := resolvePromptPath("/some/explicit/path.md")
@@ -107,25 +163,21 @@ func TestResolvePromptPath(t *testing.T) {
 }
 
 func TestSafeIndex(t *testing.T) {
-	s := []float64{1.0, 2.0, 3.0}
-	if got := safeIndex(s, 0); got != 1.0 {
+	s := []*float64{ptr(1.0), nil, ptr(0.0)}
+	if got := safeIndex(s, 0); got == nil || *got != 1.0 {
 		t.Errorf("safeIndex(s, 0) = %v, want 1.0", got)
 	}
-	if got := safeIndex(s, 5); got != 0 {
-		t.Errorf("safeIndex(s, 5) = %v, want 0", got)
+	if got := safeIndex(s, 1); got != nil {
+		t.Errorf("safeIndex(s, 1) = %v, want nil for a JSON null", *got)
 	}
-	if got := safeIndex(nil, 0); got != 0 {
-		t.Errorf("safeIndex(nil, 0) = %v, want 0", got)
+	if got := safeIndex(s, 2); got == nil || *got != 0 {
+		t.Errorf("safeIndex(s, 2) = %v, want 0, a reported value", got)
 	}
-}
-
-func TestSafeIndexInt(t *testing.T) {
-	s := []int{10, 20, 30}
-	if got := safeIndexInt(s, 1); got != 20 {
-		t.Errorf("safeIndexInt(s, 1) = %v, want 20", got)
+	if got := safeIndex(s, 5); got != nil {
+		t.Errorf("safeIndex(s, 5) = %v, want nil", *got)
 	}
-	if got := safeIndexInt(s, 10); got != 0 {
-		t.Errorf("safeIndexInt(s, 10) = %v, want 0", got)
+	if got := safeIndex([]*int(nil), 0); got != nil {
+		t.Errorf("safeIndex(nil, 0) = %v, want nil", *got)
 	}
 }
 
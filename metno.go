@@ -0,0 +1,261 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// metNorwayURL is the default base URL of the MET Norway weather API.
+const metNorwayURL = "https://api.met.no/weatherapi"
+
+// MetNorway is the locationforecast API of the Norwegian Meteorological Institute.
+// It has hourly values for about two and a half days and no sea state.
+type MetNorway struct {
+	BaseURL string
+}
+
+// NewMetNorway returns the MET Norway provider at baseURL, or at the public API if
+// baseURL is empty.
+func NewMetNorway(baseURL string) *MetNorway {
+	if baseURL == "" {
+		baseURL = metNorwayURL
+	}
+	return &MetNorway{BaseURL: strings.TrimSuffix(baseURL, "/")}
+}
+
+func (*MetNorway) Name() string { return "MET Norway" }
+
+// metNorwayResponse is the part of a locationforecast/2.0/complete response the
+// briefing uses. All times are UTC; wind is in m/s.
+type metNorwayResponse struct {
+	Properties struct {
+		Timeseries []struct {
+			Time time.Time `json:"time"`
+			Data struct {
+				Instant struct {
+					Details metNorwayDetails `json:"details"`
+				} `json:"instant"`
+				Next1Hours *struct {
+					Summary struct {
+						SymbolCode string `json:"symbol_code"`
+					} `json:"summary"`
+					Details metNorwayDetails `json:"details"`
+				} `json:"next_1_hours"`
+			} `json:"data"`
+		} `json:"timeseries"`
+	} `json:"properties"`
+}
+
+type metNorwayDetails struct {
+	AirPressureAtSeaLevel      *float64 `json:"air_pressure_at_sea_level"`
+	AirTemperature             *float64 `json:"air_temperature"`
+	CloudAreaFraction          *float64 `json:"cloud_area_fraction"`
+	RelativeHumidity           *float64 `json:"relative_humidity"`
+	WindFromDirection          *float64 `json:"wind_from_direction"`
+	WindSpeed                  *float64 `json:"wind_speed"`
+	WindSpeedOfGust            *float64 `json:"wind_speed_of_gust"`
+	PrecipitationAmount        *float64 `json:"precipitation_amount"`
+	ProbabilityOfPrecipitation *float64 `json:"probability_of_precipitation"`
+}
+
+// metNorwaySymbols maps the MET Norway weather symbols, without their _day, _night
+// and _polartwilight suffixes, to WMO weather codes. Sleet has no code of its own
+// and counts as rain; the freezing rain codes 66 and 67 would suggest icing.
+var metNorwaySymbols = map[string]int{
+	"clearsky": 0, "fair": 1, "partlycloudy": 2, "cloudy": 3, "fog": 45,
+	"lightrain": 61, "rain": 63, "heavyrain": 65,
+	"lightsleet": 61, "sleet": 63, "heavysleet": 65,
+	"lightsnow": 71, "snow": 73, "heavysnow": 75,
+	"lightrainshowers": 80, "rainshowers": 81, "heavyrainshowers": 82,
+	"lightsleetshowers": 85, "sleetshowers": 85, "heavysleetshowers": 86,
+	"lightsnowshowers": 85, "snowshowers": 86, "heavysnowshowers": 86,
+}
+
+// metNorwayCode returns the WMO weather code of a MET Norway symbol.
+func metNorwayCode(symbol string) *int {
+	if symbol == "" {
+		return nil
+	}
+	symbol, _, _ = strings.Cut(symbol, "_")
+	if strings.Contains(symbol, "thunder") {
+		return ptr(95)
+	}
+	if code, ok := metNorwaySymbols[symbol]; ok {
+		return ptr(code)
+	}
+	return nil
+}
+
+func (m *MetNorway) fetch(lat, lon float64) (metNorwayResponse, error) {
+	// The API asks for no more than four decimals.
+	url := fmt.Sprintf("%s/locationforecast/2.0/complete?lat=%.4f&lon=%.4f", m.BaseURL, lat, lon)
+	return fetchJSON[metNorwayResponse](SourceForecast, gridKey(lat, lon, "metno"), url)
+}
+
+// nauticalTimezone is the nautical time zone of a longitude, whole hours from UTC in
+// 15° bands, as an IANA name. MET Norway answers in UTC and has no timezone for the
+// position, so it stands in for the local one.
+func nauticalTimezone(lon float64) string {
+	offset := int(math.Round(lon / 15))
+	switch {
+	case offset == 0:
+		return "UTC"
+	case offset > 0:
+		return fmt.Sprintf("Etc/GMT-%d", offset) // the Etc zones count westwards
+	default:
+		return fmt.Sprintf("Etc/GMT+%d", -offset)
+	}
+}
+
+// Forecast returns the hourly forecast as far as it is hourly and daily summaries of
+// those hours, in the nautical time zone of the position unless opts has a timezone.
+// There is no pressure history.
+func (m *MetNorway) Forecast(lat, lon float64, opts WeatherOptions) (WeatherData, error) {
+	resp, err := m.fetch(lat, lon)
+	if err != nil {
+		return WeatherData{}, err
+	}
+	if opts.Timezone == "" {
+		opts.Timezone = nauticalTimezone(lon)
+	}
+	tz, err := opts.location()
+	if err != nil {
+		return WeatherData{}, err
+	}
+	u := opts.Units
+	windSpeed := func(ms *float64) *float64 { return scaled(ms, u.FromKmh(3.6)) }
+
+	w := WeatherData{Timezone: tz.String(), Units: u}
+	thisHour := time.Now().Truncate(time.Hour)
+	end := thisHour.Add(time.Duration(opts.forecastHours()) * time.Hour)
+	var precipitation *float64 // of the hour before, to match Open-Meteo
+	var prev time.Time
+	for _, ts := range resp.Properties.Timeseries {
+		d := ts.Data.Instant.Details
+		next := ts.Data.Next1Hours
+		if !prev.IsZero() && ts.Time.Sub(prev) != time.Hour {
+			precipitation = nil
+		}
+		if !ts.Time.Before(thisHour) && ts.Time.Before(end) {
+			h := HourlyForecast{
+				Time:          ts.Time.In(tz).Format(openMeteoTime),
+				Temperature:   d.AirTemperature,
+				WindSpeed:     windSpeed(d.WindSpeed),
+				WindGusts:     windSpeed(d.WindSpeedOfGust),
+				WindDirection: d.WindFromDirection,
+				Precipitation: precipitation,
+				Pressure:      d.AirPressureAtSeaLevel,
+			}
+			if next != nil {
+				h.WeatherCode = metNorwayCode(next.Summary.SymbolCode)
+			}
+			w.Hourly = append(w.Hourly, h)
+		}
+		if next == nil {
+			break // six-hourly from here on
+		}
+		precipitation, prev = next.Details.PrecipitationAmount, ts.Time
+	}
+	if len(w.Hourly) == 0 {
+		return WeatherData{}, fmt.Errorf("no forecast for %.4f, %.4f", lat, lon)
+	}
+	w.Daily = metNorwayDaily(w.Hourly, resp, tz)
+	return w, nil
+}
+
+// metNorwayDaily sums up the hours of each day like Open-Meteo's daily values. The
+// precipitation probability is the highest of the hours.
+func metNorwayDaily(hours []HourlyForecast, resp metNorwayResponse, tz *time.Location) []DailyForecast {
+	prob := make(map[string]*float64)
+	for _, ts := range resp.Properties.Timeseries {
+		if next := ts.Data.Next1Hours; next != nil {
+			t := ts.Time.In(tz).Format(openMeteoTime)
+			prob[t] = next.Details.ProbabilityOfPrecipitation
+		}
+	}
+
+	var days []DailyForecast
+	var u, v float64 // summed wind vectors for the dominant direction
+	for _, h := range hours {
+		date := h.Time[:len("2006-01-02")]
+		if len(days) == 0 || days[len(days)-1].Date != date {
+			days = append(days, DailyForecast{Date: date})
+			u, v = 0, 0
+		}
+		d := &days[len(days)-1]
+		d.TempMax, d.TempMin = maxOf(d.TempMax, h.Temperature), minOf(d.TempMin, h.Temperature)
+		d.WindSpeedMax, d.WindGustsMax = maxOf(d.WindSpeedMax, h.WindSpeed), maxOf(d.WindGustsMax, h.WindGusts)
+		if h.Precipitation != nil {
+			d.PrecipitationSum = ptr(*h.Precipitation + optValue(d.PrecipitationSum))
+		}
+		if p := prob[h.Time]; p != nil {
+			if pct := int(math.Round(*p)); d.PrecipitationProb == nil || pct > *d.PrecipitationProb {
+				d.PrecipitationProb = ptr(pct)
+			}
+		}
+		if h.WeatherCode != nil && (d.WeatherCode == nil || *h.WeatherCode > *d.WeatherCode) {
+			d.WeatherCode = h.WeatherCode
+		}
+		if h.WindSpeed != nil && h.WindDirection != nil {
+			rad := *h.WindDirection * math.Pi / 180
+			u, v = u+*h.WindSpeed*math.Sin(rad), v+*h.WindSpeed*math.Cos(rad)
+			d.WindDirection = ptr(math.Mod(math.Atan2(u, v)*180/math.Pi+360, 360))
+		}
+	}
+	return days
+}
+
+// optValue returns v, or 0 if it is missing.
+func optValue(v *float64) float64 {
+	if v == nil {
+		return 0
+	}
+	return *v
+}
+
+// Observations returns the forecast for the current hour; locationforecast has no
+// measurements.
+func (m *MetNorway) Observations(lat, lon float64, opts WeatherOptions) (CurrentWeather, error) {
+	resp, err := m.fetch(lat, lon)
+	if err != nil {
+		return CurrentWeather{}, err
+	}
+	u := opts.Units
+	windSpeed := func(ms *float64) *float64 { return scaled(ms, u.FromKmh(3.6)) }
+	percent := func(v *float64) *int {
+		if v == nil {
+			return nil
+		}
+		return ptr(int(math.Round(*v)))
+	}
+
+	thisHour := time.Now().Truncate(time.Hour)
+	for _, ts := range resp.Properties.Timeseries {
+		if ts.Time.Before(thisHour) {
+			continue
+		}
+		d := ts.Data.Instant.Details
+		c := CurrentWeather{
+			Temperature:   d.AirTemperature,
+			WindSpeed:     windSpeed(d.WindSpeed),
+			WindDirection: d.WindFromDirection,
+			WindGusts:     windSpeed(d.WindSpeedOfGust),
+			Humidity:      percent(d.RelativeHumidity),
+			Pressure:      d.AirPressureAtSeaLevel,
+			CloudCover:    percent(d.CloudAreaFraction),
+		}
+		if next := ts.Data.Next1Hours; next != nil {
+			c.Precipitation = next.Details.PrecipitationAmount
+			c.WeatherCode = metNorwayCode(next.Summary.SymbolCode)
+		}
+		return c, nil
+	}
+	return CurrentWeather{}, fmt.Errorf("no forecast for %.4f, %.4f", lat, lon)
+}
+
+// Marine returns errNotProvided: locationforecast has no sea state.
+func (*MetNorway) Marine(lat, lon float64, opts WeatherOptions) (marineResult, error) {
+	return marineResult{}, errNotProvided
+}
@@ -0,0 +1,79 @@
+package main
+
+import "math"
+
+const earthRadiusNm = 3440.065
+
+// rhumbLine returns the distance in nautical miles and the constant true course in
+// degrees of the rhumb line (loxodrome) between two positions.
+func rhumbLine(lat1, lon1, lat2, lon2 float64) (distanceNm, course float64) {
+	rad := math.Pi / 180
+	phi1, phi2 := lat1*rad, lat2*rad
+	dPhi := phi2 - phi1
+	dLambda := normalizeRadians((lon2 - lon1) * rad)
+
+	dPsi := math.Log(math.Tan(math.Pi/4+phi2/2) / math.Tan(math.Pi/4+phi1/2))
+	q := math.Cos(phi1)
+	if math.Abs(dPsi) > 1e-12 {
+		q = dPhi / dPsi
+	}
+
+	distanceNm = math.Sqrt(dPhi*dPhi+q*q*dLambda*dLambda) * earthRadiusNm
+	course = math.Mod(math.Atan2(dLambda, dPsi)/rad+360, 360)
+	return distanceNm, course
+}
+
+// rhumbPoint returns the position reached after sailing distanceNm on a constant course.
+func rhumbPoint(lat, lon, course, distanceNm float64) (float64, float64) {
+	rad := math.Pi / 180
+	delta := distanceNm / earthRadiusNm
+	theta := course * rad
+	phi1 := lat * rad
+
+	phi2 := phi1 + delta*math.Cos(theta)
+	// Do not sail over the pole.
+	if math.Abs(phi2) > math.Pi/2 {
+		phi2 = math.Copysign(math.Pi-math.Abs(phi2), phi2)
+	}
+
+	dPsi := math.Log(math.Tan(math.Pi/4+phi2/2) / math.Tan(math.Pi/4+phi1/2))
+	q := math.Cos(phi1)
+	if math.Abs(dPsi) > 1e-12 {
+		q = (phi2 - phi1) / dPsi
+	}
+	dLambda := delta * math.Sin(theta) / q
+
+	lon2 := normalizeRadians(lon*rad+dLambda) / rad
+	return phi2 / rad, lon2
+}
+
+func normalizeRadians(a float64) float64 {
+	for a > math.Pi {
+		a -= 2 * math.Pi
+	}
+	for a < -math.Pi {
+		a += 2 * math.Pi
+	}
+	return a
+}
+
+// trueWindAngle is the angle between the course and the direction the wind comes
+// from: 0° is dead upwind, 180° dead downwind.
+func trueWindAngle(course, windFrom float64) float64 {
+	return math.Abs(math.Mod(windFrom-course+540, 360) - 180)
+}
+
+// pointOfSail names the point of sail for a true wind angle.
+func pointOfSail(twa float64) string {
+	switch {
+	case twa < 45:
+		return "close-hauled"
+	case twa < 70:
+		return "close reach"
+	case twa < 110:
+		return "beam reach"
+	case twa < 150:
+		return "broad reach"
+	}
+	return "running"
+}
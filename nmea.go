@@ -0,0 +1,419 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// OnboardObservations are the latest values read from the boat's instruments over
+// NMEA 0183. Values the instruments did not send are nil.
+type OnboardObservations struct {
+	Received time.Time // when the last sentence arrived
+	Fix      *Fix
+
+	TrueWindSpeed      *float64 // knots
+	TrueWindAngle      *float64 // degrees relative to the bow, MWV
+	TrueWindDirection  *float64 // degrees true the wind blows from, MDA
+	ApparentWindSpeed  *float64 // knots
+	ApparentWindAngle  *float64 // degrees relative to the bow
+	Pressure           *float64 // hPa
+	AirTemperature     *float64 // °C
+	WaterTemperature   *float64 // °C
+	Depth              *float64 // m, below the waterline or keel depending on the offset set in the sounder
+	sentences, ignored int
+}
+
+// Fix is a position from the GPS.
+type Fix struct {
+	Lat, Lon float64
+	Time     time.Time // UTC; zero if the sentence had no date
+	SOG      *float64  // knots, RMC only
+	COG      *float64  // degrees true, RMC only
+}
+
+// ParseNMEASentence splits an NMEA 0183 sentence like "$GPRMC,...*hh" into its
+// talker-less type ("RMC") and fields, checking the checksum if there is one.
+func ParseNMEASentence(line string) (string, []string, error) {
+	line = strings.TrimSpace(line)
+	if len(line) < 7 || (line[0] != '$' && line[0] != '!') {
+		return "", nil, fmt.Errorf("not an NMEA sentence: %q", line)
+	}
+	body := line[1:]
+	if data, sum, ok := strings.Cut(body, "*"); ok {
+		want, err := strconv.ParseUint(sum, 16, 8)
+		if err != nil {
+			return "", nil, fmt.Errorf("invalid checksum in %q", line)
+		}
+		var got byte
+		for i := 0; i < len(data); i++ {
+			got ^= data[i]
+		}
+		if uint64(got) != want {
+			return "", nil, fmt.Errorf("checksum mismatch in %q: got %02X", line, got)
+		}
+		body = data
+	}
+	fields := strings.Split(body, ",")
+	if len(fields[0]) != 5 {
+		return "", nil, fmt.Errorf("unknown address field %q", fields[0])
+	}
+	return fields[0][2:], fields[1:], nil
+}
+
+// Apply reads one sentence into the observations. Sentences of other types are
+// ignored; invalid ones and fixes marked as invalid return an error.
+func (o *OnboardObservations) Apply(line string, received time.Time) error {
+	kind, f, err := ParseNMEASentence(line)
+	if err != nil {
+		return err
+	}
+	switch kind {
+	case "RMC":
+		err = o.applyRMC(f)
+	case "GGA":
+		err = o.applyGGA(f)
+	case "MWV":
+		err = o.applyMWV(f)
+	case "MDA":
+		o.applyMDA(f)
+	case "XDR":
+		o.applyXDR(f)
+	case "DPT":
+		err = o.applyDPT(f)
+	default:
+		o.ignored++
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", kind, err)
+	}
+	o.sentences++
+	o.Received = received
+	return nil
+}
+
+// $GPRMC,hhmmss.ss,A,llll.ll,a,yyyyy.yy,a,sog,cog,ddmmyy,magvar,E*hh
+func (o *OnboardObservations) applyRMC(f []string) error {
+	if len(f) < 9 {
+		return errors.New("too few fields")
+	}
+	if f[1] != "A" {
+		return errors.New("no valid fix")
+	}
+	lat, lon, err := nmeaPosition(f[2], f[3], f[4], f[5])
+	if err != nil {
+		return err
+	}
+	fix := &Fix{Lat: lat, Lon: lon, SOG: nmeaFloat(f[6]), COG: nmeaFloat(f[7])}
+	if t, err := time.Parse("020106 150405", f[8]+" "+strings.SplitN(f[0], ".", 2)[0]); err == nil {
+		fix.Time = t
+	}
+	o.Fix = fix
+	return nil
+}
+
+// $GPGGA,hhmmss.ss,llll.ll,a,yyyyy.yy,a,quality,satellites,hdop,altitude,M,...*hh
+// GGA has no date, so a fix from RMC with the same position keeps its time and speed.
+func (o *OnboardObservations) applyGGA(f []string) error {
+	if len(f) < 6 {
+		return errors.New("too few fields")
+	}
+	if f[5] == "" || f[5] == "0" {
+		return errors.New("no valid fix")
+	}
+	lat, lon, err := nmeaPosition(f[1], f[2], f[3], f[4])
+	if err != nil {
+		return err
+	}
+	if o.Fix == nil || o.Fix.Lat != lat || o.Fix.Lon != lon {
+		o.Fix = &Fix{Lat: lat, Lon: lon}
+	}
+	return nil
+}
+
+// $WIMWV,angle,R|T,speed,K|M|N|S,A*hh
+func (o *OnboardObservations) applyMWV(f []string) error {
+	if len(f) < 5 {
+		return errors.New("too few fields")
+	}
+	if f[4] != "A" {
+		return errors.New("data marked invalid")
+	}
+	speed := nmeaFloat(f[2])
+	if speed != nil {
+		switch f[3] {
+		case "N":
+		case "K":
+			*speed /= kmhPerKnot
+		case "M":
+			*speed *= 3.6 / kmhPerKnot
+		case "S":
+			*speed *= kmhPerMph / kmhPerKnot
+		default:
+			return fmt.Errorf("unknown speed unit %q", f[3])
+		}
+	}
+	switch f[1] {
+	case "T":
+		o.TrueWindAngle, o.TrueWindSpeed = keep(o.TrueWindAngle, nmeaFloat(f[0])), keep(o.TrueWindSpeed, speed)
+	case "R":
+		o.ApparentWindAngle, o.ApparentWindSpeed = keep(o.ApparentWindAngle, nmeaFloat(f[0])), keep(o.ApparentWindSpeed, speed)
+	default:
+		return fmt.Errorf("unknown reference %q", f[1])
+	}
+	return nil
+}
+
+// $WIMDA,inHg,I,bar,B,air,C,water,C,humidity,,dewpoint,C,dirTrue,T,dirMag,M,kn,N,m/s,M*hh
+func (o *OnboardObservations) applyMDA(f []string) {
+	field := func(i int) *float64 {
+		if i < len(f) {
+			return nmeaFloat(f[i])
+		}
+		return nil
+	}
+	if bar := field(2); bar != nil {
+		*bar *= 1000
+		o.Pressure = bar
+	} else if inHg := field(0); inHg != nil {
+		*inHg *= 33.8639
+		o.Pressure = inHg
+	}
+	o.AirTemperature = keep(o.AirTemperature, field(4))
+	o.WaterTemperature = keep(o.WaterTemperature, field(6))
+	o.TrueWindDirection = keep(o.TrueWindDirection, field(12))
+	o.TrueWindSpeed = keep(o.TrueWindSpeed, field(16))
+}
+
+// $IIXDR,type,value,unit,name[,type,value,unit,name...]*hh with P for pressure (bar
+// or pascal) and C for temperature. Temperatures are water temperatures if the
+// transducer name says so, air temperatures otherwise.
+func (o *OnboardObservations) applyXDR(f []string) {
+	for i := 0; i+3 < len(f); i += 4 {
+		v := nmeaFloat(f[i+1])
+		if v == nil {
+			continue
+		}
+		name := strings.ToUpper(f[i+3])
+		switch {
+		case f[i] == "P" && f[i+2] == "B":
+			*v *= 1000
+			o.Pressure = v
+		case f[i] == "P" && f[i+2] == "P":
+			*v /= 100
+			o.Pressure = v
+		case f[i] == "C" && f[i+2] == "C" && (strings.Contains(name, "WATER") || strings.Contains(name, "WTHI")):
+			o.WaterTemperature = v
+		case f[i] == "C" && f[i+2] == "C":
+			o.AirTemperature = v
+		}
+	}
+}
+
+// $SDDPT,depth,offset[,range]*hh: depth below the transducer and the offset to the
+// waterline (positive) or keel (negative).
+func (o *OnboardObservations) applyDPT(f []string) error {
+	if len(f) < 1 {
+		return errors.New("too few fields")
+	}
+	depth := nmeaFloat(f[0])
+	if depth == nil {
+		return nil
+	}
+	if len(f) > 1 {
+		if offset := nmeaFloat(f[1]); offset != nil {
+			*depth += *offset
+		}
+	}
+	o.Depth = depth
+	return nil
+}
+
+// nmeaPosition converts ddmm.mmmm/dddmm.mmmm with hemispheres to decimal degrees.
+func nmeaPosition(lat, ns, lon, ew string) (float64, float64, error) {
+	la, errLat := nmeaDegrees(lat, 2)
+	lo, errLon := nmeaDegrees(lon, 3)
+	if errLat != nil || errLon != nil || (ns != "N" && ns != "S") || (ew != "E" && ew != "W") {
+		return 0, 0, fmt.Errorf("invalid position %s %s %s %s", lat, ns, lon, ew)
+	}
+	if ns == "S" {
+		la = -la
+	}
+	if ew == "W" {
+		lo = -lo
+	}
+	return la, lo, nil
+}
+
+func nmeaDegrees(s string, degreeDigits int) (float64, error) {
+	if len(s) < degreeDigits+2 {
+		return 0, fmt.Errorf("invalid coordinate %q", s)
+	}
+	deg, err := strconv.Atoi(s[:degreeDigits])
+	if err != nil {
+		return 0, err
+	}
+	minutes, err := strconv.ParseFloat(s[degreeDigits:], 64)
+	if err != nil || minutes >= 60 {
+		return 0, fmt.Errorf("invalid coordinate %q", s)
+	}
+	return float64(deg) + minutes/60, nil
+}
+
+// nmeaFloat parses an optional numeric field; empty or invalid fields are nil.
+func nmeaFloat(s string) *float64 {
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return nil
+	}
+	return &v
+}
+
+// keep returns v, or old if v was not sent.
+func keep(old, v *float64) *float64 {
+	if v == nil {
+		return old
+	}
+	return v
+}
+
+// ReadNMEA reads sentences from r until it ends or ctx is done. Invalid sentences are
+// skipped; it is an error if nothing at all could be read.
+func ReadNMEA(ctx context.Context, r io.Reader) (OnboardObservations, error) {
+	var o OnboardObservations
+	var lastErr error
+	lines := make(chan string)
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(r)
+		for scanner.Scan() {
+			select {
+			case lines <- scanner.Text():
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	for {
+		select {
+		case <-ctx.Done():
+			return o, o.result(lastErr)
+		case line, ok := <-lines:
+			if !ok {
+				return o, o.result(lastErr)
+			}
+			if strings.TrimSpace(line) == "" {
+				continue
+			}
+			if err := o.Apply(line, time.Now()); err != nil {
+				lastErr = err
+			}
+		}
+	}
+}
+
+func (o *OnboardObservations) result(lastErr error) error {
+	if o.sentences > 0 {
+		return nil
+	}
+	if lastErr != nil {
+		return fmt.Errorf("no usable NMEA data, last error: %w", lastErr)
+	}
+	if o.ignored > 0 {
+		return fmt.Errorf("no usable NMEA data: %d sentences of other types", o.ignored)
+	}
+	return errors.New("no NMEA data received")
+}
+
+// CollectNMEA listens to an NMEA 0183 source for the given duration and returns the
+// latest observations. The source is tcp://host:port to connect to a multiplexer or
+// udp://[host]:port to listen for broadcasts.
+func CollectNMEA(source string, listen time.Duration) (OnboardObservations, error) {
+	u, err := url.Parse(source)
+	if err != nil || u.Host == "" {
+		return OnboardObservations{}, fmt.Errorf("invalid NMEA source %q (expected tcp://host:port or udp://:port)", source)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), listen)
+	defer cancel()
+
+	switch u.Scheme {
+	case "tcp":
+		var d net.Dialer
+		conn, err := d.DialContext(ctx, "tcp", u.Host)
+		if err != nil {
+			return OnboardObservations{}, fmt.Errorf("connecting to %s: %w", source, err)
+		}
+		defer conn.Close()
+		conn.SetReadDeadline(time.Now().Add(listen))
+		return ReadNMEA(ctx, conn)
+	case "udp":
+		pc, err := net.ListenPacket("udp", u.Host)
+		if err != nil {
+			return OnboardObservations{}, fmt.Errorf("listening on %s: %w", source, err)
+		}
+		defer pc.Close()
+		pc.SetReadDeadline(time.Now().Add(listen))
+		return ReadNMEA(ctx, packetReader{pc})
+	}
+	return OnboardObservations{}, fmt.Errorf("unsupported NMEA source %q (expected tcp:// or udp://)", source)
+}
+
+// packetReader reads UDP datagrams as a stream; every datagram holds whole sentences.
+type packetReader struct{ pc net.PacketConn }
+
+func (r packetReader) Read(p []byte) (int, error) {
+	n, _, err := r.pc.ReadFrom(p)
+	if n > 0 && p[n-1] != '\n' && n < len(p) {
+		p[n] = '\n'
+		n++
+	}
+	return n, err
+}
+
+// FormatOnboardObservations renders the instrument readings as a section of the user
+// message, next to the modelled current conditions.
+func FormatOnboardObservations(o OnboardObservations, u UnitSystem) string {
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("=== OBSERVED ON BOARD (%s) ===\n", o.Received.Local().Format("2006-01-02 15:04")))
+	if o.Fix != nil {
+		b.WriteString(fmt.Sprintf("GPS position: %.5f, %.5f\n", o.Fix.Lat, o.Fix.Lon))
+		if o.Fix.SOG != nil && o.Fix.COG != nil {
+			b.WriteString(fmt.Sprintf("Speed over ground: %.1f kn, course %.0f°\n", *o.Fix.SOG, *o.Fix.COG))
+		}
+	}
+	knots := func(kn float64) string { return u.formatWind(u.FromKmh(kn*kmhPerKnot), 1) }
+	if o.TrueWindSpeed != nil {
+		line := "True wind: " + knots(*o.TrueWindSpeed)
+		if o.TrueWindDirection != nil {
+			line += fmt.Sprintf(" from %.0f°", *o.TrueWindDirection)
+		}
+		if o.TrueWindAngle != nil {
+			line += fmt.Sprintf(", %.0f° off the bow", *o.TrueWindAngle)
+		}
+		b.WriteString(line + "\n")
+	}
+	if o.ApparentWindSpeed != nil && o.ApparentWindAngle != nil {
+		b.WriteString(fmt.Sprintf("Apparent wind: %s, %.0f° off the bow\n", knots(*o.ApparentWindSpeed), *o.ApparentWindAngle))
+	}
+	if o.Pressure != nil {
+		b.WriteString(fmt.Sprintf("Barometer: %.1f hPa\n", *o.Pressure))
+	}
+	if o.AirTemperature != nil {
+		b.WriteString(fmt.Sprintf("Air temperature: %.1f°C\n", *o.AirTemperature))
+	}
+	if o.WaterTemperature != nil {
+		b.WriteString(fmt.Sprintf("Water temperature: %.1f°C\n", *o.WaterTemperature))
+	}
+	if o.Depth != nil {
+		b.WriteString(fmt.Sprintf("Depth: %s\n", u.formatHeight(*o.Depth)))
+	}
+	return b.String()
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"math"
+	"net"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// replayNMEA serves a recorded NMEA log to the first TCP client, like a multiplexer.
+func replayNMEA(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.Write(data)
+	}()
+	return "tcp://" + ln.Addr().String()
+}
+
+func TestCollectNMEA(t *testing.T) {
+	obs, err := CollectNMEA(replayNMEA(t, "testdata/anchorage.nmea"), 2*time.Second)
+	if err != nil {
+		t.Fatalf("CollectNMEA: %v", err)
+	}
+
+	if obs.Fix == nil || math.Abs(obs.Fix.Lat-43.508) > 1e-6 || math.Abs(obs.Fix.Lon-16.44) > 1e-6 {
+		t.Fatalf("Fix = %+v, want 43.508, 16.44", obs.Fix)
+	}
+	if want := time.Date(2026, 3, 15, 8, 18, 36, 0, time.UTC); !obs.Fix.Time.Equal(want) {
+		t.Errorf("Fix.Time = %v, want %v (the GGA of the same position keeps the RMC time)", obs.Fix.Time, want)
+	}
+
+	checks := []struct {
+		name string
+		got  *float64
+		want float64
+	}{
+		{"TrueWindSpeed", obs.TrueWindSpeed, 10.1}, // MDA after MWV
+		{"TrueWindAngle", obs.TrueWindAngle, 61.5},
+		{"TrueWindDirection", obs.TrueWindDirection, 312},
+		{"ApparentWindSpeed", obs.ApparentWindSpeed, 14.2},
+		{"Pressure", obs.Pressure, 1016.2}, // XDR in pascal after MDA
+		{"AirTemperature", obs.AirTemperature, 18.4},
+		{"WaterTemperature", obs.WaterTemperature, 16.9},
+		{"Depth", obs.Depth, 7.7}, // plus the offset to the waterline
+	}
+	for _, c := range checks {
+		if c.got == nil {
+			t.Errorf("%s not read", c.name)
+		} else if math.Abs(*c.got-c.want) > 1e-6 {
+			t.Errorf("%s = %v, want %v", c.name, *c.got, c.want)
+		}
+	}
+
+	got := FormatOnboardObservations(obs, UnitsNautical)
+	for _, want := range []string{
+		"=== OBSERVED ON BOARD (",
+		"GPS position: 43.50800, 16.44000\n",
+		"Speed over ground: 0.1 kn, course 215°\n",
+		"True wind: 10.1 kn (Bft 3 Gentle breeze) from 312°, 62° off the bow\n",
+		"Barometer: 1016.2 hPa\n",
+		"Water temperature: 16.9°C\n",
+		"Depth: 7.7m\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("FormatOnboardObservations missing %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestCollectNMEAWithoutData(t *testing.T) {
+	dir := t.TempDir()
+	path := dir + "/empty.nmea"
+	os.WriteFile(path, []byte("$GPRMC,081836.00,V,,,,,,,150326,,,N*7A\r\n"), 0o644)
+	if _, err := CollectNMEA(replayNMEA(t, path), 2*time.Second); err == nil {
+		t.Error("CollectNMEA without a valid sentence returned no error")
+	}
+	if _, err := CollectNMEA("serial:///dev/ttyUSB0", time.Second); err == nil {
+		t.Error("CollectNMEA accepted an unsupported source")
+	}
+}
+
+func TestParseNMEASentence(t *testing.T) {
+	kind, fields, err := ParseNMEASentence("$SDDPT,7.3,0.4,100.0*54")
+	if err != nil || kind != "DPT" || len(fields) != 3 || fields[0] != "7.3" {
+		t.Errorf("ParseNMEASentence = %q, %q, %v", kind, fields, err)
+	}
+	if _, _, err := ParseNMEASentence("$SDDPT,7.3,0.4,100.0*55"); err == nil {
+		t.Error("wrong checksum accepted")
+	}
+	if _, _, err := ParseNMEASentence("$SDDPT,7.3,0.4,100.0"); err != nil {
+		t.Errorf("sentence without checksum rejected: %v", err)
+	}
+}
@@ -0,0 +1,628 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"net/smtp"
+	"net/textproto"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Notifier channel types.
+const (
+	ChannelSMTP    = "smtp"
+	ChannelMatrix  = "matrix"
+	ChannelWebhook = "webhook"
+)
+
+// What a channel receives: the whole briefing or only the warnings.
+const (
+	ContentBriefing = "briefing"
+	ContentWarnings = "warnings"
+)
+
+const (
+	defaultNotifyRetries    = 3
+	defaultNotifyRetryDelay = 10 * time.Second // times the attempt number
+	notifyTimeout           = 30 * time.Second // per attempt
+)
+
+// NotifyConfig is the notifier configuration file. Environment variables like
+// ${SMTP_PASSWORD} are expanded in its strings after it is parsed, so secrets can
+// stay out of it.
+type NotifyConfig struct {
+	DeadLetterDir string          `json:"dead_letter_dir"` // default: dead-letter in the cache directory
+	Channels      []ChannelConfig `json:"channels"`
+}
+
+// ChannelConfig configures one delivery channel.
+type ChannelConfig struct {
+	Name       string         `json:"name"`
+	Type       string         `json:"type"`        // smtp, matrix or webhook
+	Content    string         `json:"content"`     // briefing (default) or warnings
+	Format     string         `json:"format"`      // text format of the briefing, default markdown
+	Retries    *int           `json:"retries"`     // attempts after the first, default 3
+	RetryDelay string         `json:"retry_delay"` // e.g. "10s", multiplied by the attempt number
+	SMTP       *SMTPConfig    `json:"smtp"`
+	Matrix     *MatrixConfig  `json:"matrix"`
+	Webhook    *WebhookConfig `json:"webhook"`
+}
+
+// SMTPConfig is an SMTP relay. The message is sent as plain text with the HTML
+// briefing as alternative. STARTTLS is used when the server offers it; TLS selects
+// implicit TLS as on port 465.
+type SMTPConfig struct {
+	Host     string   `json:"host"`
+	Port     int      `json:"port"` // default 587
+	Username string   `json:"username"`
+	Password string   `json:"password"`
+	TLS      bool     `json:"tls"`
+	From     string   `json:"from"`
+	To       []string `json:"to"`
+}
+
+// MatrixConfig is a room on a Matrix homeserver, posted to with the client-server API.
+type MatrixConfig struct {
+	Homeserver  string `json:"homeserver"` // e.g. https://matrix.org
+	AccessToken string `json:"access_token"`
+	RoomID      string `json:"room_id"` // e.g. !abc123:matrix.org
+}
+
+// WebhookConfig is an HTTP endpoint that gets the message as a JSON object: the
+// fields of Body plus the text in TextField. For Telegram that is the sendMessage URL
+// with {"chat_id": ...} as Body.
+type WebhookConfig struct {
+	URL       string            `json:"url"`
+	Headers   map[string]string `json:"headers"`
+	Body      map[string]any    `json:"body"`
+	TextField string            `json:"text_field"` // default "text"
+}
+
+// Message is what a notifier delivers.
+type Message struct {
+	Subject string
+	Text    string
+	HTML    string // optional alternative to Text, used for email
+	ID      string // the same for every attempt, so retries can be recognized
+}
+
+// Notifier delivers messages over one channel.
+type Notifier interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// LoadNotifyConfig reads and checks a notifier configuration file.
+func LoadNotifyConfig(path string) (NotifyConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return NotifyConfig{}, err
+	}
+	var cfg NotifyConfig
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&cfg); err != nil {
+		return NotifyConfig{}, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	cfg.expandEnv()
+	for i, c := range cfg.Channels {
+		if c.Name == "" {
+			return NotifyConfig{}, fmt.Errorf("channel %d has no name", i+1)
+		}
+		if _, err := c.notifier(); err != nil {
+			return NotifyConfig{}, fmt.Errorf("channel %s: %w", c.Name, err)
+		}
+		if _, err := c.retryDelay(); err != nil {
+			return NotifyConfig{}, fmt.Errorf("channel %s: %w", c.Name, err)
+		}
+		switch c.Content {
+		case "", ContentBriefing, ContentWarnings:
+		default:
+			return NotifyConfig{}, fmt.Errorf("channel %s: unknown content %q (expected %s or %s)", c.Name, c.Content, ContentBriefing, ContentWarnings)
+		}
+		if c.Format == FormatHTML {
+			return NotifyConfig{}, fmt.Errorf("channel %s: format html is only used as the email alternative", c.Name)
+		}
+		if !slices.Contains(OutputFormats, c.textFormat()) {
+			return NotifyConfig{}, fmt.Errorf("channel %s: unknown format %q", c.Name, c.Format)
+		}
+	}
+	return cfg, nil
+}
+
+// envVar matches the ${VAR} references expanded in the configuration. A bare $ is
+// left alone, as it may be part of a password.
+var envVar = regexp.MustCompile(`\$\{(\w+)\}`)
+
+func expandEnv(s *string) {
+	*s = envVar.ReplaceAllStringFunc(*s, func(ref string) string {
+		return os.Getenv(ref[2 : len(ref)-1])
+	})
+}
+
+// expandEnv expands the environment variables in the strings of the configuration.
+func (cfg *NotifyConfig) expandEnv() {
+	expandEnv(&cfg.DeadLetterDir)
+	for i := range cfg.Channels {
+		c := &cfg.Channels[i]
+		for _, s := range []*string{&c.Name, &c.Type, &c.Content, &c.Format, &c.RetryDelay} {
+			expandEnv(s)
+		}
+		if m := c.SMTP; m != nil {
+			for _, s := range []*string{&m.Host, &m.Username, &m.Password, &m.From} {
+				expandEnv(s)
+			}
+			for j := range m.To {
+				expandEnv(&m.To[j])
+			}
+		}
+		if m := c.Matrix; m != nil {
+			for _, s := range []*string{&m.Homeserver, &m.AccessToken, &m.RoomID} {
+				expandEnv(s)
+			}
+		}
+		if w := c.Webhook; w != nil {
+			expandEnv(&w.URL)
+			expandEnv(&w.TextField)
+			for k, v := range w.Headers {
+				expandEnv(&v)
+				w.Headers[k] = v
+			}
+			for k, v := range w.Body {
+				w.Body[k] = expandEnvAny(v)
+			}
+		}
+	}
+}
+
+// expandEnvAny expands the strings in a decoded JSON value.
+func expandEnvAny(v any) any {
+	switch v := v.(type) {
+	case string:
+		expandEnv(&v)
+		return v
+	case []any:
+		for i := range v {
+			v[i] = expandEnvAny(v[i])
+		}
+	case map[string]any:
+		for k := range v {
+			v[k] = expandEnvAny(v[k])
+		}
+	}
+	return v
+}
+
+func (c ChannelConfig) notifier() (Notifier, error) {
+	switch c.Type {
+	case ChannelSMTP:
+		if c.SMTP == nil || c.SMTP.Host == "" || c.SMTP.From == "" || len(c.SMTP.To) == 0 {
+			return nil, errors.New("smtp needs host, from and to")
+		}
+		return smtpNotifier{*c.SMTP}, nil
+	case ChannelMatrix:
+		if c.Matrix == nil || c.Matrix.Homeserver == "" || c.Matrix.AccessToken == "" || c.Matrix.RoomID == "" {
+			return nil, errors.New("matrix needs homeserver, access_token and room_id")
+		}
+		return matrixNotifier{*c.Matrix, &http.Client{}}, nil
+	case ChannelWebhook:
+		if c.Webhook == nil || c.Webhook.URL == "" {
+			return nil, errors.New("webhook needs a url")
+		}
+		return webhookNotifier{*c.Webhook, &http.Client{}}, nil
+	}
+	return nil, fmt.Errorf("unknown type %q (expected %s, %s or %s)", c.Type, ChannelSMTP, ChannelMatrix, ChannelWebhook)
+}
+
+func (c ChannelConfig) retries() int {
+	if c.Retries == nil {
+		return defaultNotifyRetries
+	}
+	return max(*c.Retries, 0)
+}
+
+func (c ChannelConfig) retryDelay() (time.Duration, error) {
+	if c.RetryDelay == "" {
+		return defaultNotifyRetryDelay, nil
+	}
+	d, err := time.ParseDuration(c.RetryDelay)
+	if err != nil {
+		return 0, fmt.Errorf("retry_delay: %w", err)
+	}
+	return d, nil
+}
+
+func (c ChannelConfig) textFormat() string {
+	if c.Format == "" {
+		return FormatMarkdown
+	}
+	return c.Format
+}
+
+// message builds what the channel gets for a briefing. ok is false if there is
+// nothing to send, as for a warnings channel on a day without warnings.
+func (c ChannelConfig) message(out Output, lang string) (msg Message, ok bool, err error) {
+	if c.Content == ContentWarnings {
+		if len(out.Hazards) == 0 {
+			return Message{}, false, nil
+		}
+		title, found := hazardBlockTitle[lang]
+		if !found {
+			title = hazardBlockTitle["en"]
+		}
+		msg.Subject = fmt.Sprintf("%s: %s, %s", title, out.Location, out.Date)
+		var b strings.Builder
+		for _, h := range out.Hazards {
+			marker := "⚠️"
+			if h.Severity == SeverityDanger {
+				marker = "⛔"
+			}
+			b.WriteString(fmt.Sprintf("%s %s: %s\n", marker, h.Window(), h.describe(out.Weather.Units)))
+		}
+		msg.Text = b.String()
+		return msg, true, nil
+	}
+
+	msg.Subject = fmt.Sprintf("Tagesbriefing: %s, %s", out.Location, out.Date)
+	if msg.Text, err = RenderOutput(out, c.textFormat()); err != nil {
+		return Message{}, false, err
+	}
+	if c.Type == ChannelSMTP {
+		if msg.HTML, err = RenderOutput(out, FormatHTML); err != nil {
+			return Message{}, false, err
+		}
+	}
+	return msg, true, nil
+}
+
+// Deliver sends the briefing to every channel, retrying each one with a growing
+// delay. A message that cannot be delivered is written to the dead-letter directory.
+// The returned error lists the channels that failed.
+func Deliver(ctx context.Context, cfg NotifyConfig, out Output, lang string) error {
+	var errs []error
+	for _, c := range cfg.Channels {
+		msg, ok, err := c.message(out, lang)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", c.Name, err))
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if err := sendOrDeadLetter(ctx, cfg, c, msg); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		fmt.Fprintf(os.Stderr, "Sent %s to %s\n", c.content(), c.Name)
+	}
+	return errors.Join(errs...)
+}
+
+// DeliverAlert sends an alert to every channel, whatever content it is set up for.
+func DeliverAlert(ctx context.Context, cfg NotifyConfig, msg Message) error {
+	var errs []error
+	for _, c := range cfg.Channels {
+		if err := sendOrDeadLetter(ctx, cfg, c, msg); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		fmt.Fprintf(os.Stderr, "Sent alert to %s\n", c.Name)
+	}
+	return errors.Join(errs...)
+}
+
+func sendOrDeadLetter(ctx context.Context, cfg NotifyConfig, c ChannelConfig, msg Message) error {
+	err := deliverChannel(ctx, c, msg)
+	if err == nil {
+		return nil
+	}
+	err = fmt.Errorf("%s: %w", c.Name, err)
+	if dlErr := writeDeadLetter(cfg.DeadLetterDir, c, msg, err, time.Now()); dlErr != nil {
+		return errors.Join(err, fmt.Errorf("%s: writing dead letter: %w", c.Name, dlErr))
+	}
+	return err
+}
+
+func (c ChannelConfig) content() string {
+	if c.Content == "" {
+		return ContentBriefing
+	}
+	return c.Content
+}
+
+func deliverChannel(ctx context.Context, c ChannelConfig, msg Message) error {
+	n, err := c.notifier()
+	if err != nil {
+		return err
+	}
+	delay, err := c.retryDelay()
+	if err != nil {
+		return err
+	}
+
+	if msg.ID == "" {
+		id := make([]byte, 8)
+		rand.Read(id)
+		msg.ID = hex.EncodeToString(id)
+	}
+	attempts := c.retries() + 1
+	for attempt := 1; ; attempt++ {
+		attemptCtx, cancel := context.WithTimeout(ctx, notifyTimeout)
+		err = n.Send(attemptCtx, msg)
+		cancel()
+		if err == nil {
+			return nil
+		}
+		if errors.As(err, new(permanentError)) {
+			return fmt.Errorf("rejected, not retrying: %w", err)
+		}
+		if attempt == attempts {
+			return fmt.Errorf("giving up after %d attempts: %w", attempts, err)
+		}
+		wait := time.Duration(attempt) * delay
+		fmt.Fprintf(os.Stderr, "Warning: sending to %s failed (attempt %d/%d), retrying in %s: %v\n", c.Name, attempt, attempts, wait, err)
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// deadLetter is a message that could not be delivered, kept for manual resending.
+type deadLetter struct {
+	Channel string    `json:"channel"`
+	Type    string    `json:"type"`
+	Time    time.Time `json:"time"`
+	Error   string    `json:"error"`
+	Subject string    `json:"subject"`
+	Text    string    `json:"text"`
+	HTML    string    `json:"html,omitempty"`
+}
+
+func writeDeadLetter(dir string, c ChannelConfig, msg Message, sendErr error, now time.Time) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(deadLetter{
+		Channel: c.Name,
+		Type:    c.Type,
+		Time:    now,
+		Error:   sendErr.Error(),
+		Subject: msg.Subject,
+		Text:    msg.Text,
+		HTML:    msg.HTML,
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	// Several messages can fail on a channel within a second, e.g. a briefing and its
+	// warnings, so every dead letter gets a file of its own.
+	f, err := os.CreateTemp(dir, fmt.Sprintf("%s-%s-*.json", now.Format("20060102T150405"), safeFileName(c.Name)))
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func safeFileName(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' {
+			return r
+		}
+		return '_'
+	}, s)
+}
+
+// smtpNotifier sends email through an SMTP relay.
+type smtpNotifier struct {
+	cfg SMTPConfig
+}
+
+func (n smtpNotifier) Send(ctx context.Context, msg Message) error {
+	port := n.cfg.Port
+	if port == 0 {
+		port = 587
+	}
+	addr := net.JoinHostPort(n.cfg.Host, strconv.Itoa(port))
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	if n.cfg.TLS {
+		conn = tls.Client(conn, &tls.Config{ServerName: n.cfg.Host})
+	}
+	c, err := smtp.NewClient(conn, n.cfg.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok && !n.cfg.TLS {
+		if err := c.StartTLS(&tls.Config{ServerName: n.cfg.Host}); err != nil {
+			return err
+		}
+	}
+	if n.cfg.Username != "" {
+		if err := c.Auth(smtp.PlainAuth("", n.cfg.Username, n.cfg.Password, n.cfg.Host)); err != nil {
+			return err
+		}
+	}
+	if err := c.Mail(n.cfg.From); err != nil {
+		return err
+	}
+	for _, to := range n.cfg.To {
+		if err := c.Rcpt(to); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	body, err := buildEmail(n.cfg.From, n.cfg.To, msg, time.Now())
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(body); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+// buildEmail builds a MIME message: plain text, or text and HTML as alternatives.
+func buildEmail(from string, to []string, msg Message, now time.Time) ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteString("From: " + from + "\r\n")
+	b.WriteString("To: " + strings.Join(to, ", ") + "\r\n")
+	b.WriteString("Subject: " + mime.QEncoding.Encode("utf-8", msg.Subject) + "\r\n")
+	b.WriteString("Date: " + now.Format(time.RFC1123Z) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+
+	if msg.HTML == "" {
+		b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+		b.WriteString("Content-Transfer-Encoding: 8bit\r\n\r\n")
+		b.WriteString(crlf(msg.Text))
+		return b.Bytes(), nil
+	}
+
+	var parts bytes.Buffer
+	mw := multipart.NewWriter(&parts)
+	for _, p := range []struct{ contentType, body string }{
+		{"text/plain; charset=utf-8", msg.Text},
+		{"text/html; charset=utf-8", msg.HTML},
+	} {
+		w, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {p.contentType},
+			"Content-Transfer-Encoding": {"8bit"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		io.WriteString(w, crlf(p.body))
+	}
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+	b.WriteString("Content-Type: multipart/alternative; boundary=" + mw.Boundary() + "\r\n\r\n")
+	b.Write(parts.Bytes())
+	return b.Bytes(), nil
+}
+
+func crlf(s string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(s, "\r\n", "\n"), "\n", "\r\n")
+}
+
+// matrixNotifier posts to a Matrix room.
+type matrixNotifier struct {
+	cfg    MatrixConfig
+	client *http.Client
+}
+
+// Send uses the message ID as transaction ID, so the homeserver posts a message only
+// once however often a timed-out attempt is retried.
+func (n matrixNotifier) Send(ctx context.Context, msg Message) error {
+	endpoint := fmt.Sprintf("%s/_matrix/client/v3/rooms/%s/send/m.room.message/%s",
+		strings.TrimRight(n.cfg.Homeserver, "/"), url.PathEscape(n.cfg.RoomID), url.PathEscape(msg.ID))
+
+	body, err := json.Marshal(map[string]string{
+		"msgtype": "m.text",
+		"body":    msg.Subject + "\n\n" + msg.Text,
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+n.cfg.AccessToken)
+	return doNotifyRequest(n.client, req)
+}
+
+// webhookNotifier posts a JSON object to an HTTP endpoint.
+type webhookNotifier struct {
+	cfg    WebhookConfig
+	client *http.Client
+}
+
+func (n webhookNotifier) Send(ctx context.Context, msg Message) error {
+	fields := make(map[string]any, len(n.cfg.Body)+1)
+	for k, v := range n.cfg.Body {
+		fields[k] = v
+	}
+	field := n.cfg.TextField
+	if field == "" {
+		field = "text"
+	}
+	fields[field] = msg.Subject + "\n\n" + msg.Text
+
+	body, err := json.Marshal(fields)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range n.cfg.Headers {
+		req.Header.Set(k, v)
+	}
+	return doNotifyRequest(n.client, req)
+}
+
+func doNotifyRequest(client *http.Client, req *http.Request) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		err := fmt.Errorf("%s returned status %d: %s", req.URL.Host, resp.StatusCode, strings.TrimSpace(string(msg)))
+		switch {
+		case resp.StatusCode == http.StatusRequestTimeout, resp.StatusCode == http.StatusTooManyRequests, resp.StatusCode >= 500:
+			return err
+		}
+		return permanentError{err} // e.g. a wrong token or chat ID
+	}
+	return nil
+}
+
+// permanentError is a failed send that a retry would not fix.
+type permanentError struct {
+	error
+}
+
+func (e permanentError) Unwrap() error { return e.error }
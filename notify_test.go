@@ -0,0 +1,300 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// smtpStandIn accepts one SMTP session on localhost and sends the message data
+// it received to the channel.
+func smtpStandIn(t *testing.T) (host string, port int, received chan string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	received = make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		tp := textproto.NewConn(conn)
+		tp.PrintfLine("220 localhost ESMTP stand-in")
+		for {
+			line, err := tp.ReadLine()
+			if err != nil {
+				return
+			}
+			verb, _, _ := strings.Cut(strings.ToUpper(line), " ")
+			switch verb {
+			case "EHLO", "HELO":
+				tp.PrintfLine("250 localhost")
+			case "DATA":
+				tp.PrintfLine("354 go ahead")
+				data, _ := tp.ReadDotBytes()
+				received <- string(data)
+				tp.PrintfLine("250 queued")
+			case "QUIT":
+				tp.PrintfLine("221 bye")
+				return
+			default:
+				tp.PrintfLine("250 ok")
+			}
+		}
+	}()
+
+	addr := ln.Addr().(*net.TCPAddr)
+	return "127.0.0.1", addr.Port, received
+}
+
+func notifyOutput(hazards []Hazard) Output {
+	out := sampleOutput()
+	out.Hazards = hazards
+	return out
+}
+
+func TestDeliverSMTP(t *testing.T) {
+	host, port, received := smtpStandIn(t)
+	cfg := NotifyConfig{
+		DeadLetterDir: t.TempDir(),
+		Channels: []ChannelConfig{{
+			Name: "family",
+			Type: ChannelSMTP,
+			SMTP: &SMTPConfig{Host: host, Port: port, From: "boat@example.com", To: []string{"family@example.com"}},
+		}},
+	}
+	if err := Deliver(context.Background(), cfg, notifyOutput(nil), "de"); err != nil {
+		t.Fatal(err)
+	}
+
+	mail := <-received
+	for _, want := range []string{
+		"To: family@example.com\n",
+		"Subject: Tagesbriefing: Split, Kroatien, 2026-03-15\n",
+		"Content-Type: multipart/alternative; boundary=",
+		"Content-Type: text/plain; charset=utf-8",
+		"## Wetter und Seegang",
+		"Content-Type: text/html; charset=utf-8",
+		"<h2>Wetter und Seegang</h2>",
+	} {
+		if !strings.Contains(mail, want) {
+			t.Errorf("mail lacks %q:\n%s", want, mail)
+		}
+	}
+}
+
+func TestDeliverMatrixAndWebhook(t *testing.T) {
+	// The homeserver fails the first attempt; the retry must reuse its transaction ID.
+	var matrixBody map[string]string
+	var txns []string
+	matrix := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		txns = append(txns, path.Base(r.URL.Path))
+		if len(txns) == 1 {
+			http.Error(w, "timeout", http.StatusGatewayTimeout)
+			return
+		}
+		if r.Method != http.MethodPut || !strings.HasPrefix(r.URL.EscapedPath(), "/_matrix/client/v3/rooms/%21room:example.org/send/m.room.message/") {
+			t.Errorf("matrix request %s %s", r.Method, r.URL.EscapedPath())
+		}
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			t.Errorf("matrix authorization = %q", r.Header.Get("Authorization"))
+		}
+		json.NewDecoder(r.Body).Decode(&matrixBody)
+		io.WriteString(w, `{"event_id":"$1"}`)
+	}))
+	defer matrix.Close()
+
+	// The webhook fails twice before it accepts the message.
+	var calls atomic.Int32
+	var webhookBody map[string]any
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) < 3 {
+			http.Error(w, "busy", http.StatusServiceUnavailable)
+			return
+		}
+		json.NewDecoder(r.Body).Decode(&webhookBody)
+	}))
+	defer webhook.Close()
+
+	retries := 2
+	cfg := NotifyConfig{
+		DeadLetterDir: t.TempDir(),
+		Channels: []ChannelConfig{
+			{Name: "crew", Type: ChannelMatrix, RetryDelay: "1ms", Matrix: &MatrixConfig{Homeserver: matrix.URL, AccessToken: "secret", RoomID: "!room:example.org"}},
+			{
+				Name: "telegram", Type: ChannelWebhook, Content: ContentWarnings, Retries: &retries, RetryDelay: "1ms",
+				Webhook: &WebhookConfig{URL: webhook.URL, Body: map[string]any{"chat_id": "42"}},
+			},
+		},
+	}
+	hazards := []Hazard{{Start: "2026-03-15T14:00", End: "2026-03-15T18:00", Parameter: "gusts", Value: 50, Unit: "km/h", Severity: SeverityDanger}}
+	if err := Deliver(context.Background(), cfg, notifyOutput(hazards), "de"); err != nil {
+		t.Fatal(err)
+	}
+
+	if matrixBody["msgtype"] != "m.text" || !strings.Contains(matrixBody["body"], "## Wetter und Seegang") {
+		t.Errorf("matrix body = %v", matrixBody)
+	}
+	if len(txns) != 2 || txns[0] == "" || txns[0] != txns[1] {
+		t.Errorf("matrix transaction IDs = %q, want the same one twice", txns)
+	}
+	if calls.Load() != 3 {
+		t.Errorf("webhook called %d times, want 3", calls.Load())
+	}
+	text, _ := webhookBody["text"].(string)
+	if webhookBody["chat_id"] != "42" || !strings.HasPrefix(text, "⚠️ WARNUNG: Split, Kroatien, 2026-03-15\n\n⛔ ") || strings.Contains(text, "Wetter") {
+		t.Errorf("webhook body = %v", webhookBody)
+	}
+
+	// Without hazards a warnings channel sends nothing.
+	calls.Store(0)
+	cfg.Channels = cfg.Channels[1:]
+	if err := Deliver(context.Background(), cfg, notifyOutput(nil), "de"); err != nil || calls.Load() != 0 {
+		t.Errorf("warnings without hazards: %v, %d calls", err, calls.Load())
+	}
+}
+
+func TestDeliverDeadLetter(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		http.Error(w, "down", http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	retries := 1
+	cfg := NotifyConfig{
+		DeadLetterDir: filepath.Join(t.TempDir(), "dead-letter"),
+		Channels: []ChannelConfig{{
+			Name: "hook/1", Type: ChannelWebhook, Retries: &retries, RetryDelay: "1ms",
+			Webhook: &WebhookConfig{URL: srv.URL},
+		}},
+	}
+	err := Deliver(context.Background(), cfg, notifyOutput(nil), "de")
+	if err == nil || !strings.Contains(err.Error(), "giving up after 2 attempts") {
+		t.Fatalf("err = %v", err)
+	}
+	if calls.Load() != 2 {
+		t.Errorf("called %d times, want 2", calls.Load())
+	}
+
+	files, _ := filepath.Glob(filepath.Join(cfg.DeadLetterDir, "*-hook_1-*.json"))
+	if len(files) != 1 {
+		t.Fatalf("dead letters = %v", files)
+	}
+	data, _ := os.ReadFile(files[0])
+	var dl deadLetter
+	if err := json.Unmarshal(data, &dl); err != nil {
+		t.Fatal(err)
+	}
+	if dl.Channel != "hook/1" || !strings.Contains(dl.Error, "status 500") || !strings.Contains(dl.Text, "## Wetter und Seegang") {
+		t.Errorf("dead letter = %+v", dl)
+	}
+
+	// A rejected request is not retried, and its dead letter does not overwrite
+	// the one written within the same second.
+	calls.Store(0)
+	srv.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		http.Error(w, "bad token", http.StatusForbidden)
+	})
+	err = Deliver(context.Background(), cfg, notifyOutput(nil), "de")
+	if err == nil || !strings.Contains(err.Error(), "not retrying") {
+		t.Fatalf("err = %v", err)
+	}
+	if calls.Load() != 1 {
+		t.Errorf("rejected request called %d times, want 1", calls.Load())
+	}
+	now := time.Date(2026, 3, 15, 6, 0, 0, 0, time.UTC)
+	for range 2 {
+		if err := writeDeadLetter(cfg.DeadLetterDir, cfg.Channels[0], Message{Text: "x"}, errors.New("down"), now); err != nil {
+			t.Fatal(err)
+		}
+	}
+	files, _ = filepath.Glob(filepath.Join(cfg.DeadLetterDir, "*-hook_1-*.json"))
+	if len(files) != 4 {
+		t.Errorf("dead letters = %v", files)
+	}
+}
+
+func TestLoadNotifyConfig(t *testing.T) {
+	t.Setenv("TEST_MATRIX_TOKEN", "secret")
+	t.Setenv("TEST_CHAT_ID", `4"2\`)
+	dir := t.TempDir()
+	write := func(content string) string {
+		path := filepath.Join(dir, "notify.json")
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	cfg, err := LoadNotifyConfig(write(`{"channels": [{"name": "crew", "type": "matrix", "content": "warnings",
+		"matrix": {"homeserver": "https://matrix.example.org", "access_token": "${TEST_MATRIX_TOKEN}", "room_id": "!r:example.org"}}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Channels[0].Matrix.AccessToken != "secret" || cfg.Channels[0].retries() != defaultNotifyRetries {
+		t.Errorf("config = %+v", cfg.Channels[0])
+	}
+
+	// Only ${VAR} is expanded, after parsing: a $ in a password stays, and quotes or
+	// backslashes in a variable do not break the JSON.
+	cfg, err = LoadNotifyConfig(write(`{"channels": [
+		{"name": "mail", "type": "smtp", "smtp": {"host": "mail.example.org", "password": "pa$$word$HOME", "from": "a@example.org", "to": ["b@example.org"]}},
+		{"name": "telegram", "type": "webhook", "webhook": {"url": "https://example.org/bot", "body": {"chat_id": "${TEST_CHAT_ID}"}}}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := cfg.Channels[0].SMTP.Password; got != "pa$$word$HOME" {
+		t.Errorf("password = %q", got)
+	}
+	if got := cfg.Channels[1].Webhook.Body["chat_id"]; got != `4"2\` {
+		t.Errorf("chat_id = %q", got)
+	}
+
+	for _, bad := range []string{
+		`{"channels": [{"name": "x", "type": "pager"}]}`,
+		`{"channels": [{"name": "x", "type": "webhook"}]}`,
+		`{"channels": [{"type": "webhook", "webhook": {"url": "http://x"}}]}`,
+		`{"channels": [{"name": "x", "type": "webhook", "webhook": {"url": "http://x"}, "content": "all"}]}`,
+		`{"channels": [{"name": "x", "type": "webhook", "webhook": {"url": "http://x"}, "retry_delay": "soon"}]}`,
+		`{"channels": [{"name": "x", "type": "webhook", "webhook": {"url": "http://x"}, "format": "pdf"}]}`,
+		`{"channels": [], "extra": 1}`,
+	} {
+		if _, err := LoadNotifyConfig(write(bad)); err == nil {
+			t.Errorf("accepted %s", bad)
+		}
+	}
+}
+
+func TestBuildEmailPlain(t *testing.T) {
+	now := time.Date(2026, 3, 15, 7, 0, 0, 0, time.UTC)
+	mail, err := buildEmail("a@example.com", []string{"b@example.com"}, Message{Subject: "Hi", Text: "line 1\nline 2\n"}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "Content-Type: text/plain; charset=utf-8\r\nContent-Transfer-Encoding: 8bit\r\n\r\nline 1\r\nline 2\r\n"; !strings.HasSuffix(string(mail), want) {
+		t.Errorf("mail =\n%s", mail)
+	}
+	if !strings.Contains(string(mail), "Date: Sun, 15 Mar 2026 07:00:00 +0000\r\n") {
+		t.Errorf("mail has no date:\n%s", mail)
+	}
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"sailingnomads-briefing/logseq"
+)
+
+// offlineLocationRadiusKm is how far the snapshot position may be from the current
+// position before its place name is no longer trusted for the header.
+const offlineLocationRadiusKm = 20.0
+
+type offlineLabels struct {
+	Weather  string
+	Note     string
+	Distance string
+	NoData   string
+}
+
+var offlineLabelsByLang = map[string]offlineLabels{
+	"de": {
+		Weather:  "Wetter und Seegang",
+		Note:     "Offline-Briefing ohne Internet: Wetterdaten vom %s, keine Websuche",
+		Distance: "⚠️ Wetterdaten gelten für %.5f, %.5f (%.0f km entfernt)",
+		NoData:   "Keine gespeicherten Wetterdaten vorhanden",
+	},
+	"en": {
+		Weather:  "Weather and sea state",
+		Note:     "Offline briefing without internet: weather data from %s, no web search",
+		Distance: "⚠️ Weather data is for %.5f, %.5f (%.0f km away)",
+		NoData:   "No cached weather data available",
+	},
+}
+
+var locationPropertyRe = regexp.MustCompile(`(?m)^\s*location::\s*(.+?)\s*$`)
+
+// BuildOfflineBriefing produces a deterministic Logseq briefing without any language
+// model or web search. It uses the header format from prompt.md, the hazard warning
+// block, the sailing windows and the weather section from FormatWeatherData, based on
+// the last snapshot and the journal context.
+func BuildOfflineBriefing(lat, lon float64, snap Snapshot, journalContext, lang string, th HazardThresholds, vessel VesselProfile, now time.Time) string {
+	labels, ok := offlineLabelsByLang[lang]
+	if !ok {
+		labels = offlineLabelsByLang["en"]
+	}
+
+	var b strings.Builder
+	b.WriteString("- " + logseq.BriefingTag + "\n")
+	b.WriteString(fmt.Sprintf("\t- position:: %.5f, %.5f\n", lat, lon))
+	b.WriteString(fmt.Sprintf("\t  location:: %s\n", offlineLocationName(lat, lon, snap, journalContext)))
+
+	b.WriteString(fmt.Sprintf("\t- %s\n", labels.Weather))
+	if snap.FetchedAt.IsZero() {
+		b.WriteString(fmt.Sprintf("\t\t- %s\n", labels.NoData))
+		return b.String()
+	}
+
+	b.WriteString(fmt.Sprintf("\t\t- "+labels.Note+"\n", snap.FetchedAt.Local().Format("2006-01-02 15:04")))
+	if d := distanceKm(lat, lon, snap.Location.Latitude, snap.Location.Longitude); d > offlineLocationRadiusKm {
+		b.WriteString(fmt.Sprintf("\t\t- "+labels.Distance+"\n", snap.Location.Latitude, snap.Location.Longitude, d))
+	}
+
+	weather := trimPastForecast(snap.Weather, now)
+	b.WriteString(weatherToBlocks(FormatWeatherData(weather, th), 2))
+	briefing := InsertSailingBlock(b.String(), SummarizeSailing(AssessSailing(weather, vessel)), lang)
+	return InsertHazardBlock(briefing, EvaluateHazards(weather, th), weather.Units, lang)
+}
+
+// offlineLocationName picks the best place name available without geocoding: the
+// snapshot location if it is close by, else the latest location:: property in the
+// journal, else the bare coordinates.
+func offlineLocationName(lat, lon float64, snap Snapshot, journalContext string) string {
+	loc := snap.Location
+	if loc.DisplayName != "" && distanceKm(lat, lon, loc.Latitude, loc.Longitude) <= offlineLocationRadiusKm {
+		place := loc.City
+		if place == "" {
+			place, _, _ = strings.Cut(loc.DisplayName, ",")
+		}
+		if loc.Country != "" {
+			return place + ", " + loc.Country
+		}
+		return place
+	}
+
+	// The journal context lists the newest day first.
+	if m := locationPropertyRe.FindStringSubmatch(journalContext); m != nil {
+		return m[1]
+	}
+
+	return fmt.Sprintf("%.5f, %.5f", lat, lon)
+}
+
+// weatherToBlocks turns the "=== SECTION ===" text of FormatWeatherData into Logseq
+// blocks: one block per section with its lines as child blocks.
+func weatherToBlocks(text string, depth int) string {
+	var b strings.Builder
+	indent := strings.Repeat("\t", depth)
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "===") && strings.HasSuffix(line, "===") {
+			title := strings.TrimSpace(strings.Trim(line, "="))
+			b.WriteString(fmt.Sprintf("%s- %s\n", indent, title))
+			continue
+		}
+		b.WriteString(fmt.Sprintf("%s\t- %s\n", indent, line))
+	}
+	return b.String()
+}
+
+// trimPastForecast drops daily and hourly entries that lie before now in the
+// forecast's own timezone, so a snapshot from yesterday starts at today.
+func trimPastForecast(w WeatherData, now time.Time) WeatherData {
+	if tz, err := time.LoadLocation(w.Timezone); err == nil {
+		now = now.In(tz)
+	}
+	today := now.Format("2006-01-02")
+	thisHour := now.Format("2006-01-02T15") + ":00"
+
+	var daily []DailyForecast
+	for _, d := range w.Daily {
+		if d.Date >= today {
+			daily = append(daily, d)
+		}
+	}
+	var hourly []HourlyForecast
+	for _, h := range w.Hourly {
+		if h.Time >= thisHour {
+			hourly = append(hourly, h)
+		}
+	}
+	var hourlyMarine []HourlyMarine
+	for _, m := range w.HourlyMarine {
+		if m.Time >= thisHour {
+			hourlyMarine = append(hourlyMarine, m)
+		}
+	}
+
+	w.Daily = daily
+	w.Hourly = hourly
+	w.HourlyMarine = hourlyMarine
+	return w
+}
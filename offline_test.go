@@ -0,0 +1,59 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBuildOfflineBriefing(t *testing.T) {
+	snap := Snapshot{
+		FetchedAt: time.Date(2026, 3, 14, 6, 0, 0, 0, time.UTC),
+		Location: Location{
+			Latitude: 43.296, Longitude: 5.369,
+			City: "Marseille", Country: "France",
+			DisplayName: "Marseille, Bouches-du-Rhône, France",
+		},
+		Weather: WeatherData{
+			Timezone: "UTC",
+			Current:  CurrentWeather{Temperature: ptr(18.5), WindSpeed: ptr(12.0), WindDirection: ptr(300.0)},
+			Daily: []DailyForecast{
+				{Date: "2026-03-14", TempMax: ptr(20.0)},
+				{Date: "2026-03-15", TempMax: ptr(21.0)},
+			},
+		},
+	}
+	now := time.Date(2026, 3, 15, 7, 0, 0, 0, time.UTC)
+
+	got := BuildOfflineBriefing(43.3, 5.37, snap, "", "de", DefaultHazardThresholds, DefaultVesselProfile, now)
+
+	checks := []string{
+		"- [[Tagesbriefing]]\n",
+		"\t- position:: 43.30000, 5.37000\n",
+		"\t  location:: Marseille, France\n",
+		"\t- Wetter und Seegang\n",
+		"\t\t- CURRENT WEATHER (Timezone: UTC)\n",
+		"\t\t\t- Temperature: 18.5°C\n",
+		"2026-03-15",
+	}
+	for _, check := range checks {
+		if !contains(got, check) {
+			t.Errorf("offline briefing missing %q in:\n%s", check, got)
+		}
+	}
+	if contains(got, "2026-03-14:") {
+		t.Errorf("offline briefing still contains yesterday's forecast:\n%s", got)
+	}
+}
+
+func TestOfflineLocationNameFromJournal(t *testing.T) {
+	// Snapshot from a different anchorage: fall back to the newest location:: property.
+	snap := Snapshot{Location: Location{Latitude: 45.0, Longitude: 13.6, City: "Rovinj", DisplayName: "Rovinj"}}
+	journal := "--- 2026-03-15 ---\n- [[Tagesbriefing]]\n\t- position:: 43.5, 16.4\n\t  location:: Split, Croatia\n--- 2026-03-14 ---\n\t  location:: Trogir, Croatia\n"
+
+	if got := offlineLocationName(43.5, 16.4, snap, journal); got != "Split, Croatia" {
+		t.Errorf("offlineLocationName = %q, want %q", got, "Split, Croatia")
+	}
+	if got := offlineLocationName(43.5, 16.4, Snapshot{}, ""); got != "43.50000, 16.40000" {
+		t.Errorf("offlineLocationName without any data = %q", got)
+	}
+}
@@ -0,0 +1,339 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"sailingnomads-briefing/logseq"
+)
+
+// Passage planning: slide a departure time across the 7-day forecast and score the
+// conditions expected along the rhumb line for each departure.
+
+const (
+	planForecastHours = 168
+	openMeteoTime     = "2006-01-02T15:04"
+	closeHauledTWA    = 40 // sailing closer to the wind than this means beating
+)
+
+// PassagePlan describes the planned passage.
+type PassagePlan struct {
+	From, To Location
+	SpeedKn  float64
+	Distance float64 // nm
+	Course   float64 // degrees true
+}
+
+// Duration is the expected passage time at the planned speed.
+func (p PassagePlan) Duration() time.Duration {
+	return time.Duration(p.Distance / p.SpeedKn * float64(time.Hour))
+}
+
+// routeSample is a forecast at a point along the rhumb line.
+type routeSample struct {
+	Fraction float64 // 0 at the start, 1 at the destination
+	Weather  WeatherData
+	hourly   map[string]HourlyForecast
+	marine   map[string]HourlyMarine
+}
+
+func newRouteSample(fraction float64, w WeatherData) routeSample {
+	s := routeSample{
+		Fraction: fraction,
+		Weather:  w,
+		hourly:   make(map[string]HourlyForecast, len(w.Hourly)),
+		marine:   make(map[string]HourlyMarine, len(w.HourlyMarine)),
+	}
+	for _, h := range w.Hourly {
+		s.hourly[h.Time] = h
+	}
+	for _, m := range w.HourlyMarine {
+		s.marine[m.Time] = m
+	}
+	return s
+}
+
+// DepartureOption is the expected passage for one departure time.
+type DepartureOption struct {
+	Depart, Arrive   string
+	Verdict          Verdict
+	Reasons          []Reason
+	HasWind          bool // some hour had wind speed, gusts and direction
+	TWAMin, TWAMax   float64
+	WindMax, GustMax float64 // knots
+	WaveMax          float64 // meters
+	Sunset           string  // sunset on the arrival day, empty if unknown
+	ArriveBeforeDark bool
+	Penalty          float64 // lower is better among equal verdicts
+}
+
+// EvaluateDeparture estimates the conditions hour by hour along the rhumb line when
+// leaving at depart, using the nearest route sample for each position.
+func EvaluateDeparture(plan PassagePlan, samples []routeSample, depart time.Time, vessel VesselProfile) (DepartureOption, bool) {
+	hours := int(math.Ceil(plan.Duration().Hours()))
+	arrive := depart.Add(plan.Duration())
+	opt := DepartureOption{
+		Depart: depart.Format(openMeteoTime),
+		Arrive: arrive.Format(openMeteoTime),
+		TWAMin: 180,
+	}
+
+	for k := 0; k <= hours; k++ {
+		fraction := math.Min(float64(k)*plan.SpeedKn/plan.Distance, 1)
+		sample := nearestSample(samples, fraction)
+		t := depart.Add(time.Duration(k) * time.Hour).Format(openMeteoTime)
+
+		h, ok := sample.hourly[t]
+		if !ok {
+			return DepartureOption{}, false // beyond the forecast
+		}
+		var m *HourlyMarine
+		if mh, ok := sample.marine[t]; ok {
+			m = &mh
+		}
+
+		a := AssessHour(h, m, sample.Weather.Units, vessel)
+		opt.Verdict = max(opt.Verdict, a.Verdict)
+		opt.Reasons = appendDistinctKinds(opt.Reasons, a.Reasons)
+
+		opt.Penalty += float64(a.Verdict) * 3
+		if m != nil && m.WaveHeight != nil {
+			opt.WaveMax = math.Max(opt.WaveMax, *m.WaveHeight)
+		}
+		if h.WindSpeed == nil || h.WindGusts == nil || h.WindDirection == nil {
+			continue // AssessHour made missing wind or gusts caution
+		}
+
+		twa := trueWindAngle(plan.Course, *h.WindDirection)
+		wind := sample.Weather.Units.ToKnots(*h.WindSpeed)
+		opt.HasWind = true
+		opt.TWAMin = math.Min(opt.TWAMin, twa)
+		opt.TWAMax = math.Max(opt.TWAMax, twa)
+		opt.WindMax = math.Max(opt.WindMax, wind)
+		opt.GustMax = math.Max(opt.GustMax, sample.Weather.Units.ToKnots(*h.WindGusts))
+
+		// Prefer reaching over beating and some wind over motoring.
+		if twa < closeHauledTWA && wind >= lightWindKn {
+			opt.Penalty += 2
+		}
+		if wind < lightWindKn {
+			opt.Penalty++
+		}
+	}
+	if opt.TWAMin < closeHauledTWA && opt.WindMax >= lightWindKn {
+		opt.Verdict = max(opt.Verdict, VerdictCaution)
+		opt.Reasons = append(opt.Reasons, Reason{Kind: ReasonBeating, Value: opt.TWAMin, Unit: "°"})
+	}
+
+	dest := samples[len(samples)-1].Weather
+	for _, d := range dest.Daily {
+		if d.Date == arrive.Format("2006-01-02") && d.Sunset != "" {
+			opt.Sunset = d.Sunset
+			opt.ArriveBeforeDark = opt.Arrive <= d.Sunset && opt.Arrive >= d.Sunrise
+		}
+	}
+	if opt.Sunset != "" && !opt.ArriveBeforeDark {
+		opt.Verdict = max(opt.Verdict, VerdictCaution)
+		opt.Reasons = append(opt.Reasons, Reason{Kind: ReasonDark})
+	}
+
+	return opt, true
+}
+
+func nearestSample(samples []routeSample, fraction float64) routeSample {
+	best := samples[0]
+	for _, s := range samples[1:] {
+		if math.Abs(s.Fraction-fraction) < math.Abs(best.Fraction-fraction) {
+			best = s
+		}
+	}
+	return best
+}
+
+// FindDepartures evaluates every hourly departure in the forecast of the first sample
+// from now on. Departures whose passage runs past the end of the forecast are skipped.
+func FindDepartures(plan PassagePlan, samples []routeSample, vessel VesselProfile, now time.Time) []DepartureOption {
+	origin := samples[0].Weather
+	if tz, err := time.LoadLocation(origin.Timezone); err == nil {
+		now = now.In(tz)
+	}
+	// Open-Meteo times are local wall-clock times; compare them as such.
+	thisHour := now.Format("2006-01-02T15") + ":00"
+
+	var options []DepartureOption
+	for _, h := range origin.Hourly {
+		if h.Time < thisHour {
+			continue
+		}
+		depart, err := time.Parse(openMeteoTime, h.Time)
+		if err != nil {
+			continue
+		}
+		if opt, ok := EvaluateDeparture(plan, samples, depart, vessel); ok {
+			options = append(options, opt)
+		}
+	}
+	return options
+}
+
+// DepartureWindow is a run of consecutive departure hours with the same outcome.
+type DepartureWindow struct {
+	First, Last DepartureOption
+	Best        DepartureOption
+}
+
+// BestDepartureWindows merges consecutive departures with the same verdict and
+// daylight arrival into windows and returns the best n, best first.
+func BestDepartureWindows(options []DepartureOption, n int) []DepartureWindow {
+	var windows []DepartureWindow
+	for _, opt := range options {
+		if k := len(windows); k > 0 {
+			w := &windows[k-1]
+			if w.Last.Verdict == opt.Verdict && w.Last.ArriveBeforeDark == opt.ArriveBeforeDark && nextHour(w.Last.Depart, opt.Depart) {
+				w.Last = opt
+				if opt.Penalty < w.Best.Penalty {
+					w.Best = opt
+				}
+				continue
+			}
+		}
+		windows = append(windows, DepartureWindow{First: opt, Last: opt, Best: opt})
+	}
+
+	sort.SliceStable(windows, func(i, j int) bool {
+		a, b := windows[i].Best, windows[j].Best
+		if a.Verdict != b.Verdict {
+			return a.Verdict < b.Verdict
+		}
+		return a.Penalty < b.Penalty
+	})
+	if len(windows) > n {
+		windows = windows[:n]
+	}
+	return windows
+}
+
+func nextHour(prev, next string) bool {
+	a, errA := time.Parse(openMeteoTime, prev)
+	b, errB := time.Parse(openMeteoTime, next)
+	return errA == nil && errB == nil && b.Sub(a) == time.Hour
+}
+
+// FormatPassagePlan renders the plan and its best departure windows.
+func FormatPassagePlan(plan PassagePlan, windows []DepartureWindow) string {
+	var b strings.Builder
+	d := plan.Duration()
+
+	b.WriteString("=== PASSAGE PLAN ===\n")
+	b.WriteString(fmt.Sprintf("From: %.5f, %.5f %s\n", plan.From.Latitude, plan.From.Longitude, plan.From.City))
+	b.WriteString(fmt.Sprintf("To: %.5f, %.5f %s\n", plan.To.Latitude, plan.To.Longitude, plan.To.City))
+	b.WriteString(fmt.Sprintf("Distance: %.1f nm, course %.0f° (%s), at %.1f kn about %dh%02dm\n",
+		plan.Distance, plan.Course, degToCompass(plan.Course), plan.SpeedKn, int(d.Hours()), int(d.Minutes())%60))
+
+	b.WriteString("\n=== BEST DEPARTURE WINDOWS ===\n")
+	if len(windows) == 0 {
+		b.WriteString("No departure fits within the forecast.\n")
+	}
+	for i, w := range windows {
+		best := w.Best
+		b.WriteString(fmt.Sprintf("%d. Depart %s–%s: %s\n", i+1, w.First.Depart, hourOf(w.Last.Depart), strings.ToUpper(best.Verdict.String())))
+		b.WriteString(fmt.Sprintf("   Best: depart %s, arrive %s", best.Depart, best.Arrive))
+		if best.Sunset != "" {
+			b.WriteString(fmt.Sprintf(" (sunset %s)", hourOf(best.Sunset)))
+		}
+		b.WriteString("\n")
+		if best.HasWind {
+			b.WriteString(fmt.Sprintf("   TWA %.0f–%.0f° (%s to %s), wind up to %.0f kn, gusts up to %.0f kn, waves up to %.1f m\n",
+				best.TWAMin, best.TWAMax, pointOfSail(best.TWAMin), pointOfSail(best.TWAMax),
+				best.WindMax, best.GustMax, best.WaveMax))
+		} else {
+			b.WriteString(fmt.Sprintf("   TWA %s, wind %s, gusts %s, waves up to %.1f m\n",
+				notAvailable, notAvailable, notAvailable, best.WaveMax))
+		}
+		if len(best.Reasons) > 0 {
+			b.WriteString("   Notes: " + formatReasons(best.Reasons) + "\n")
+		}
+	}
+	return b.String()
+}
+
+// resolvePlace turns coordinates or a place name into a Location.
+func resolvePlace(s string) (Location, error) {
+	if lat, lon, ok := logseq.ParsePosition(s); ok {
+		return Location{Latitude: lat, Longitude: lon}, nil
+	}
+	return ForwardGeocode(s)
+}
+
+// fetchRouteSamples fetches the 7-day forecast at the start, middle and end of the
+// rhumb line, all in the timezone of the start so the hours line up.
+func fetchRouteSamples(plan PassagePlan, units UnitSystem, providers []WeatherProvider) ([]routeSample, error) {
+	opts := WeatherOptions{Units: units, ForecastHours: planForecastHours, Providers: providers}
+
+	var samples []routeSample
+	for _, fraction := range []float64{0, 0.5, 1} {
+		lat, lon := rhumbPoint(plan.From.Latitude, plan.From.Longitude, plan.Course, plan.Distance*fraction)
+		w, err := FetchWeather(lat, lon, opts)
+		if err != nil {
+			return nil, fmt.Errorf("weather at %.0f%% of the route: %w", fraction*100, err)
+		}
+		if opts.Timezone == "" {
+			opts.Timezone = w.Timezone
+		}
+		samples = append(samples, newRouteSample(fraction, w))
+	}
+	return samples, nil
+}
+
+// runPlan implements the plan subcommand: find the best departure windows for a
+// passage from --lat/--lon to --to.
+func runPlan(args []string) {
+	fs := flag.NewFlagSet("plan", flag.ExitOnError)
+	lat := fs.Float64("lat", 0, "Latitude of the departure (required)")
+	lon := fs.Float64("lon", 0, "Longitude of the departure (required)")
+	to := fs.String("to", "", "Destination as \"lat,lon\" or a place name (required)")
+	speed := fs.Float64("speed", 5, "Expected average speed over ground (kn)")
+	top := fs.Int("top", 3, "Number of departure windows to show")
+	vessel := vesselFlags(fs)
+	common := registerCommonFlags(fs)
+	common.parse(fs, args)
+
+	if (*lat == 0 && *lon == 0) || *to == "" || *speed <= 0 {
+		fmt.Fprintln(os.Stderr, "Error: --lat, --lon, --to and a positive --speed are required")
+		fmt.Fprintln(os.Stderr, "Usage: briefing plan --lat <latitude> --lon <longitude> --to <lat,lon|place> [--speed <kn>] [--top <n>]")
+		os.Exit(1)
+	}
+
+	dest, err := resolvePlace(*to)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error resolving destination: %v\n", err)
+		os.Exit(1)
+	}
+	distance, course := rhumbLine(*lat, *lon, dest.Latitude, dest.Longitude)
+	if distance < 0.1 {
+		fmt.Fprintln(os.Stderr, "Error: departure and destination are the same position")
+		os.Exit(1)
+	}
+	plan := PassagePlan{
+		From:     Location{Latitude: *lat, Longitude: *lon},
+		To:       dest,
+		SpeedKn:  *speed,
+		Distance: distance,
+		Course:   course,
+	}
+
+	fmt.Fprintln(os.Stderr, "Fetching weather along the route...")
+	samples, err := fetchRouteSamples(plan, common.units, common.weather)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error fetching weather: %v\n", err)
+		os.Exit(1)
+	}
+
+	options := FindDepartures(plan, samples, *vessel, time.Now())
+	fmt.Print(FormatPassagePlan(plan, BestDepartureWindows(options, *top)))
+}
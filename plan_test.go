@@ -0,0 +1,117 @@
+package main
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRhumbLine(t *testing.T) {
+	// One degree of latitude due north is 60 nm.
+	d, c := rhumbLine(43, 16, 44, 16)
+	if math.Abs(d-60.04) > 0.1 || c != 0 {
+		t.Errorf("rhumbLine north = %.2f nm, %.1f°", d, c)
+	}
+
+	d, c = rhumbLine(43.508, 16.440, 43.062, 16.183)
+	lat, lon := rhumbPoint(43.508, 16.440, c, d)
+	if math.Abs(lat-43.062) > 1e-6 || math.Abs(lon-16.183) > 1e-6 {
+		t.Errorf("rhumbPoint after %.1f nm on %.0f° = %.4f, %.4f", d, c, lat, lon)
+	}
+}
+
+func TestTrueWindAngle(t *testing.T) {
+	tests := []struct{ course, wind, want float64 }{
+		{0, 0, 0},
+		{0, 180, 180},
+		{350, 20, 30},
+		{90, 0, 90},
+		{200, 90, 110},
+	}
+	for _, tt := range tests {
+		if got := trueWindAngle(tt.course, tt.wind); got != tt.want {
+			t.Errorf("trueWindAngle(%v, %v) = %v, want %v", tt.course, tt.wind, got, tt.want)
+		}
+	}
+}
+
+// planWeather is a forecast with a steady wind from windFrom and a blow (35 kn) in
+// the given hours.
+func planWeather(windFrom float64, blowHours map[int]bool) WeatherData {
+	w := WeatherData{
+		Units:    UnitsNautical,
+		Timezone: "UTC",
+		Daily:    []DailyForecast{{Date: "2026-03-15", Sunrise: "2026-03-15T06:00", Sunset: "2026-03-15T18:00"}},
+	}
+	for hour := 0; hour < 24; hour++ {
+		h := HourlyForecast{
+			Time:          time.Date(2026, 3, 15, hour, 0, 0, 0, time.UTC).Format(openMeteoTime),
+			WindSpeed:     ptr(12.0),
+			WindGusts:     ptr(15.0),
+			WindDirection: ptr(windFrom),
+		}
+		if blowHours[hour] {
+			h.WindSpeed, h.WindGusts = ptr(35.0), ptr(45.0)
+		}
+		w.Hourly = append(w.Hourly, h)
+	}
+	return w
+}
+
+func TestFindDepartures(t *testing.T) {
+	// 20 nm due south at 5 kn: four hours with a beam reach in a westerly.
+	plan := PassagePlan{SpeedKn: 5, Distance: 20, Course: 180}
+	samples := []routeSample{
+		newRouteSample(0, planWeather(270, map[int]bool{10: true})),
+		newRouteSample(1, planWeather(270, map[int]bool{10: true, 11: true})),
+	}
+	now := time.Date(2026, 3, 15, 5, 30, 0, 0, time.UTC)
+
+	options := FindDepartures(plan, samples, DefaultVesselProfile, now)
+	// Departures from 05:00 to 19:00; later ones run past the forecast.
+	if len(options) != 15 || options[0].Depart != "2026-03-15T05:00" {
+		t.Fatalf("FindDepartures returned %d options starting %s", len(options), options[0].Depart)
+	}
+	if opt := options[0]; opt.Verdict != VerdictGo || !opt.ArriveBeforeDark || opt.TWAMin != 90 {
+		t.Errorf("05:00 departure = %+v, want go with daylight arrival on a beam reach", opt)
+	}
+	// 06:00 meets the blow at the destination at 10:00, 10:00 at the start.
+	for _, i := range []int{1, 5} {
+		if opt := options[i]; opt.Verdict != VerdictNoGo {
+			t.Errorf("%s departure through the blow = %s, want no-go", opt.Depart, opt.Verdict)
+		}
+	}
+	if opt := options[10]; opt.Verdict != VerdictCaution || opt.ArriveBeforeDark {
+		t.Errorf("15:00 departure = %s, daylight %v; want caution for arriving after sunset", opt.Verdict, opt.ArriveBeforeDark)
+	}
+
+	windows := BestDepartureWindows(options, 2)
+	want := [][2]string{{"2026-03-15T05:00", "2026-03-15T05:00"}, {"2026-03-15T11:00", "2026-03-15T14:00"}}
+	if len(windows) != len(want) {
+		t.Fatalf("BestDepartureWindows returned %d windows, want %d", len(windows), len(want))
+	}
+	for i, w := range windows {
+		if w.First.Depart != want[i][0] || w.Last.Depart != want[i][1] || w.Best.Verdict != VerdictGo {
+			t.Errorf("window %d = %s–%s %s, want go %s–%s", i, w.First.Depart, w.Last.Depart, w.Best.Verdict, want[i][0], want[i][1])
+		}
+	}
+}
+
+func TestEvaluateDepartureWithoutWind(t *testing.T) {
+	w := planWeather(270, nil)
+	for i := range w.Hourly {
+		w.Hourly[i].WindDirection = nil
+	}
+	plan := PassagePlan{SpeedKn: 5, Distance: 20, Course: 180}
+	samples := []routeSample{newRouteSample(0, w), newRouteSample(1, w)}
+
+	opt, ok := EvaluateDeparture(plan, samples, time.Date(2026, 3, 15, 8, 0, 0, 0, time.UTC), DefaultVesselProfile)
+	if !ok || opt.HasWind {
+		t.Fatalf("departure without wind direction = %+v, want no wind", opt)
+	}
+	out := FormatPassagePlan(plan, []DepartureWindow{{First: opt, Last: opt, Best: opt}})
+	if !strings.Contains(out, "   TWA n/a, wind n/a, gusts n/a, waves up to 0.0 m\n") {
+		t.Errorf("FormatPassagePlan without wind =\n%s", out)
+	}
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+const (
+	pressureHistoryHours  = 24 // past hours fetched for the 24-hour tendency
+	pressureTendencyHours = 3  // span of the tendency in synoptic reports
+
+	// Limits of the 3-hour tendency categories used in shipping forecasts (hPa).
+	pressureSteady     = 0.1
+	pressureSlowly     = 1.5
+	pressureChange     = 3.5
+	pressureQuickly    = 6.0
+	pressureFallStrong = 3.6 // falling quickly: strong wind or gale likely
+)
+
+// PressureTendency is the change of the mean sea level pressure over the hours up
+// to Time.
+type PressureTendency struct {
+	Time   string
+	Hours  int
+	Change float64 // hPa, negative when falling
+}
+
+// Category names the tendency as in shipping forecasts: steady, rising or falling
+// slowly, rising or falling, quickly, or very rapidly. Tendencies over more than
+// three hours are scaled to three hours first.
+func (t PressureTendency) Category() string {
+	rate := math.Abs(t.Change) * pressureTendencyHours / float64(t.Hours)
+	dir := "rising"
+	if t.Change < 0 {
+		dir = "falling"
+	}
+	switch {
+	case rate < pressureSteady:
+		return "steady"
+	case rate <= pressureSlowly:
+		return dir + " slowly"
+	case rate <= pressureChange:
+		return dir
+	case rate <= pressureQuickly:
+		return dir + " quickly"
+	}
+	return dir + " very rapidly"
+}
+
+func (t PressureTendency) String() string {
+	return fmt.Sprintf("%+.1f hPa in %d h, %s", t.Change, t.Hours, t.Category())
+}
+
+// pressureSeries joins the pressure of the past hours and of the hourly forecast.
+// Hours without pressure data are left out.
+func pressureSeries(w WeatherData) []PressureReading {
+	series := make([]PressureReading, 0, len(w.PressureHistory)+len(w.Hourly))
+	series = append(series, w.PressureHistory...)
+	for _, h := range w.Hourly {
+		if h.Pressure != nil {
+			series = append(series, PressureReading{Time: h.Time, Pressure: *h.Pressure})
+		}
+	}
+	return series
+}
+
+// pressureTendencyAt is the tendency over the given hours up to series[i]. ok is
+// false if the series does not reach back that far without a gap.
+func pressureTendencyAt(series []PressureReading, i, hours int) (PressureTendency, bool) {
+	if i-hours < 0 || i >= len(series) {
+		return PressureTendency{}, false
+	}
+	from, to := series[i-hours], series[i]
+	a, errA := time.Parse(openMeteoTime, from.Time)
+	b, errB := time.Parse(openMeteoTime, to.Time)
+	if errA != nil || errB != nil || b.Sub(a) != time.Duration(hours)*time.Hour {
+		return PressureTendency{}, false
+	}
+	return PressureTendency{Time: to.Time, Hours: hours, Change: to.Pressure - from.Pressure}, true
+}
+
+// pressureFalls maps each hour to the fall of the pressure over the preceding
+// pressureTendencyHours, positive when falling.
+func pressureFalls(series []PressureReading) map[string]float64 {
+	falls := make(map[string]float64)
+	for i := range series {
+		if t, ok := pressureTendencyAt(series, i, pressureTendencyHours); ok {
+			falls[t.Time] = -t.Change
+		}
+	}
+	return falls
+}
+
+// currentPressureIndex is the index of the current hour in the pressure series: the
+// first forecast hour.
+func currentPressureIndex(w WeatherData, series []PressureReading) (int, bool) {
+	if len(w.Hourly) == 0 {
+		return 0, false
+	}
+	for i, r := range series {
+		if r.Time == w.Hourly[0].Time {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// stormWarning describes what a fall over pressureTendencyHours announces, or
+// returns "" for a fall below limitHPa, the --hazard-pressure-fall threshold. It
+// matches the severity of the pressure fall hazard.
+func stormWarning(fall, limitHPa float64) string {
+	switch {
+	case fall < limitHPa:
+		return ""
+	case fall > pressureQuickly:
+		return "storm likely"
+	}
+	return "strong wind or gale likely"
+}
+
+// FormatPressureTrend renders the 3-hour and 24-hour tendency of the mean sea level
+// pressure, the lowest pressure and the steepest fall in the forecast, and a storm
+// warning when the pressure falls by fallHPa or more in pressureTendencyHours.
+func FormatPressureTrend(w WeatherData, fallHPa float64) string {
+	series := pressureSeries(w)
+	now, ok := currentPressureIndex(w, series)
+	if !ok {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("\n=== PRESSURE TREND (mean sea level) ===\n")
+	b.WriteString(fmt.Sprintf("Now: %.1f hPa\n", series[now].Pressure))
+	for _, hours := range []int{pressureTendencyHours, pressureHistoryHours} {
+		if t, ok := pressureTendencyAt(series, now, hours); ok {
+			b.WriteString(fmt.Sprintf("Last %d h: %s\n", hours, t))
+		}
+	}
+
+	lowest := series[now]
+	var steepest PressureTendency
+	for i := now + 1; i < len(series); i++ {
+		if series[i].Pressure < lowest.Pressure {
+			lowest = series[i]
+		}
+		if t, ok := pressureTendencyAt(series, i, pressureTendencyHours); ok && t.Change < steepest.Change {
+			steepest = t
+		}
+	}
+	if lowest.Time != series[now].Time {
+		b.WriteString(fmt.Sprintf("Forecast low: %.1f hPa at %s\n", lowest.Pressure, lowest.Time))
+	}
+	if steepest.Change < 0 {
+		b.WriteString(fmt.Sprintf("Steepest forecast fall: %s, until %s\n", steepest, steepest.Time))
+	}
+
+	if t, ok := pressureTendencyAt(series, now, pressureTendencyHours); ok {
+		if warning := stormWarning(-t.Change, fallHPa); warning != "" {
+			b.WriteString(fmt.Sprintf("STORM WARNING: pressure %s (%.1f hPa in %d h), %s\n", t.Category(), -t.Change, t.Hours, warning))
+		}
+	}
+	if warning := stormWarning(-steepest.Change, fallHPa); warning != "" {
+		b.WriteString(fmt.Sprintf("STORM WARNING: pressure forecast %s until %s (%.1f hPa in %d h), %s\n",
+			steepest.Category(), steepest.Time, -steepest.Change, steepest.Hours, warning))
+	}
+	return b.String()
+}
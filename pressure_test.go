@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestPressureTendencyCategory(t *testing.T) {
+	tests := []struct {
+		tendency PressureTendency
+		want     string
+	}{
+		{PressureTendency{Hours: 3, Change: 0.05}, "steady"},
+		{PressureTendency{Hours: 3, Change: 1.2}, "rising slowly"},
+		{PressureTendency{Hours: 3, Change: -2.0}, "falling"},
+		{PressureTendency{Hours: 3, Change: -4.0}, "falling quickly"},
+		{PressureTendency{Hours: 3, Change: -6.5}, "falling very rapidly"},
+		{PressureTendency{Hours: 24, Change: -8.0}, "falling slowly"}, // 1.0 hPa per 3 hours
+	}
+	for _, tt := range tests {
+		if got := tt.tendency.Category(); got != tt.want {
+			t.Errorf("Category(%+.1f hPa in %d h) = %q, want %q", tt.tendency.Change, tt.tendency.Hours, got, tt.want)
+		}
+	}
+}
+
+// fallingPressure is a forecast whose pressure falls from 1016 hPa by the given
+// hPa per hour, with 24 steady hours before it.
+func fallingPressure(perHour float64) WeatherData {
+	var w WeatherData
+	for h := range 24 {
+		w.PressureHistory = append(w.PressureHistory, PressureReading{Time: fmt.Sprintf("2026-03-14T%02d:00", h), Pressure: 1016})
+	}
+	for h := range 12 {
+		w.Hourly = append(w.Hourly, HourlyForecast{Time: fmt.Sprintf("2026-03-15T%02d:00", h), Pressure: ptr(1016 - perHour*float64(h))})
+	}
+	return w
+}
+
+func TestFormatPressureTrend(t *testing.T) {
+	got := FormatPressureTrend(fallingPressure(2.5), pressureFallStrong)
+	for _, want := range []string{
+		"=== PRESSURE TREND (mean sea level) ===",
+		"Now: 1016.0 hPa",
+		"Last 3 h: +0.0 hPa in 3 h, steady",
+		"Last 24 h: +0.0 hPa in 24 h, steady",
+		"Forecast low: 988.5 hPa at 2026-03-15T11:00",
+		"Steepest forecast fall: -7.5 hPa in 3 h, falling very rapidly, until 2026-03-15T03:00",
+		"STORM WARNING: pressure forecast falling very rapidly until 2026-03-15T03:00 (7.5 hPa in 3 h), storm likely",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("FormatPressureTrend missing %q:\n%s", want, got)
+		}
+	}
+
+	if got := FormatPressureTrend(fallingPressure(0.2), pressureFallStrong); strings.Contains(got, "STORM WARNING") {
+		t.Errorf("slow fall gave a storm warning:\n%s", got)
+	}
+	if got := FormatPressureTrend(WeatherData{Hourly: []HourlyForecast{{Time: "2026-03-15T00:00"}}}, pressureFallStrong); got != "" {
+		t.Errorf("FormatPressureTrend without pressure data = %q, want empty", got)
+	}
+
+	// A custom --hazard-pressure-fall moves the storm warning with the hazard.
+	th := DefaultHazardThresholds
+	th.PressureFallHPa = 2.5
+	w := fallingPressure(1)
+	got = FormatPressureTrend(w, th.PressureFallHPa)
+	hazards := EvaluateHazards(w, th)
+	if !strings.Contains(got, "STORM WARNING: pressure forecast falling until 2026-03-15T03:00 (3.0 hPa in 3 h), strong wind or gale likely") || len(hazards) == 0 || hazards[0].Parameter != HazardPressureFall {
+		t.Errorf("3 hPa fall with a 2.5 hPa limit: hazards %+v, trend:\n%s", hazards, got)
+	}
+	if got := FormatPressureTrend(fallingPressure(2.5), 8); strings.Contains(got, "STORM WARNING") {
+		t.Errorf("7.5 hPa fall with an 8 hPa limit gave a storm warning:\n%s", got)
+	}
+}
+
+func TestPressureFallHazard(t *testing.T) {
+	var got []Hazard
+	for _, h := range EvaluateHazards(fallingPressure(1.5), DefaultHazardThresholds) {
+		if h.Parameter == HazardPressureFall {
+			got = append(got, h)
+		}
+	}
+	want := Hazard{Start: "2026-03-15T03:00", End: "2026-03-15T11:00", Parameter: HazardPressureFall, Value: 4.5, Unit: "hPa", Severity: SeverityWarning}
+	if len(got) != 1 || got[0] != want {
+		t.Fatalf("pressure fall hazards = %+v, want %+v", got, want)
+	}
+	if d := got[0].describe(UnitsMetric); d != "pressure falling 4.5 hPa in 3 h (strong wind or gale likely)" {
+		t.Errorf("describe = %q", d)
+	}
+}
@@ -0,0 +1,259 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// WeatherProvider is a source of weather data at a position. Values a provider does
+// not have are nil; a provider without a whole kind of data returns errNotProvided.
+type WeatherProvider interface {
+	Name() string
+	// Forecast returns the daily and hourly forecast, the pressure of the past hours
+	// and the timezone of the times.
+	Forecast(lat, lon float64, opts WeatherOptions) (WeatherData, error)
+	// Marine returns the current and hourly sea state.
+	Marine(lat, lon float64, opts WeatherOptions) (marineResult, error)
+	// Observations returns the conditions at the position now.
+	Observations(lat, lon float64, opts WeatherOptions) (CurrentWeather, error)
+}
+
+// errNotProvided is returned by providers for data they do not have at all.
+var errNotProvided = errors.New("not provided")
+
+// Names of the providers for --weather.
+const (
+	ProviderOpenMeteo = "open-meteo"
+	ProviderMetNorway = "met-no"
+	ProviderFile      = "file:" // followed by the path of a GRIB2 file
+)
+
+// ParseWeatherProviders builds the providers named in --weather, in order of
+// preference. Empty URLs select the public APIs.
+func ParseWeatherProviders(names []string, openMeteoURL, metNorwayURL string) ([]WeatherProvider, error) {
+	var providers []WeatherProvider
+	for _, name := range names {
+		switch {
+		case name == ProviderOpenMeteo:
+			providers = append(providers, NewOpenMeteo(openMeteoURL))
+		case name == ProviderMetNorway:
+			providers = append(providers, NewMetNorway(metNorwayURL))
+		case strings.HasPrefix(name, ProviderFile) && len(name) > len(ProviderFile):
+			providers = append(providers, &GRIBFile{Path: strings.TrimPrefix(name, ProviderFile)})
+		default:
+			return nil, fmt.Errorf("unknown weather provider %q, want %s, %s or %s<path>", name, ProviderOpenMeteo, ProviderMetNorway, ProviderFile)
+		}
+	}
+	return providers, nil
+}
+
+// mergeWeather fetches the forecast, the current conditions and the sea state from
+// the providers of opts. For each of them the first provider that answers is the
+// primary one; the later providers are only asked while values are missing, and
+// fill them in field by field at the same times. WeatherData.Sources records where
+// each part and every filled-in value came from.
+func mergeWeather(lat, lon float64, opts WeatherOptions) (WeatherData, error) {
+	providers := opts.providers()
+
+	var data WeatherData
+	primary := -1
+	var failed []string
+	for i, p := range providers {
+		w, err := p.Forecast(lat, lon, opts)
+		if err != nil {
+			if !errors.Is(err, errNotProvided) {
+				failed = append(failed, fmt.Sprintf("%s: %v", p.Name(), err))
+			}
+			continue
+		}
+		data, primary = w, i
+		break
+	}
+	if primary < 0 {
+		return WeatherData{}, fmt.Errorf("fetching weather: %s", strings.Join(failed, "; "))
+	}
+	for _, f := range failed {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", f)
+	}
+	data.Units = opts.Units
+	data.Sources = []string{"forecast from " + providers[primary].Name()}
+	// The other providers answer in the same timezone, so that their hours line up.
+	if opts.Timezone == "" {
+		opts.Timezone = data.Timezone
+	}
+	for _, p := range providers[primary+1:] {
+		if !hasMissing(data.Hourly, hourlyChecks) && !hasMissing(data.Daily, dailyChecks) {
+			break
+		}
+		w, err := p.Forecast(lat, lon, opts)
+		if err != nil {
+			warnProvider(p, err)
+			continue
+		}
+		data.Sources = append(data.Sources, fillMissing(data.Hourly, w.Hourly, hourlyTime, hourlyChecks, "hourly", p.Name())...)
+		data.Sources = append(data.Sources, fillMissing(data.Daily, w.Daily, dailyDate, dailyChecks, "daily", p.Name())...)
+	}
+
+	current := []CurrentWeather{{}}
+	source := ""
+	for _, p := range providers {
+		if source != "" && !hasMissing(current, currentChecks) {
+			break
+		}
+		c, err := p.Observations(lat, lon, opts)
+		if err != nil {
+			warnProvider(p, err)
+			continue
+		}
+		if source == "" {
+			current[0], source = c, p.Name()
+			data.Sources = append(data.Sources, "current weather from "+source)
+			continue
+		}
+		data.Sources = append(data.Sources, fillMissing(current, []CurrentWeather{c}, nil, currentChecks, "current", p.Name())...)
+	}
+	data.Current = current[0]
+
+	source = ""
+	var marineErrs []string
+	for _, p := range providers {
+		if source != "" && !hasMissing(data.HourlyMarine, marineChecks) {
+			break
+		}
+		m, err := p.Marine(lat, lon, opts)
+		if err != nil {
+			if !errors.Is(err, errNotProvided) {
+				marineErrs = append(marineErrs, fmt.Sprintf("%s: %v", p.Name(), err))
+			}
+			continue
+		}
+		if source == "" {
+			data.Marine, data.HourlyMarine, source = m.Current, m.Hourly, p.Name()
+			data.Sources = append(data.Sources, "marine from "+source)
+			continue
+		}
+		if data.Marine.WaveHeight == nil && m.Current.WaveHeight != nil {
+			data.Marine = m.Current
+			data.Sources = append(data.Sources, "current sea state from "+p.Name())
+		}
+		data.Sources = append(data.Sources, fillMissing(data.HourlyMarine, m.Hourly, marineTime, marineChecks, "hourly", p.Name())...)
+	}
+	if source == "" && len(marineErrs) > 0 {
+		fmt.Fprintf(os.Stderr, "Warning: could not fetch marine data: %s\n", strings.Join(marineErrs, "; "))
+	}
+
+	return data, nil
+}
+
+// warnProvider reports a fallback provider that failed. Missing kinds of data are
+// expected and not reported.
+func warnProvider(p WeatherProvider, err error) {
+	if !errors.Is(err, errNotProvided) {
+		fmt.Fprintf(os.Stderr, "Warning: %s: %v\n", p.Name(), err)
+	}
+}
+
+func hourlyTime(h HourlyForecast) string { return h.Time }
+func dailyDate(d DailyForecast) string   { return d.Date }
+func marineTime(m HourlyMarine) string   { return m.Time }
+
+// hasMissing reports whether any checked value is missing in any row.
+func hasMissing[T any](rows []T, checks []fieldCheck[T]) bool {
+	for _, r := range rows {
+		for _, c := range checks {
+			if !c.present(r) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// fillMissing fills the values missing in rows from the row of another provider at
+// the same time, or from its only row if timeOf is nil. It describes what it took,
+// e.g. "hourly gusts from MET Norway (12 of 48)".
+func fillMissing[T any](rows, from []T, timeOf func(T) string, checks []fieldCheck[T], label, source string) []string {
+	match := func(r T) (T, bool) {
+		if timeOf == nil {
+			return from[0], len(from) == 1
+		}
+		for _, f := range from {
+			if timeOf(f) == timeOf(r) {
+				return f, true
+			}
+		}
+		var zero T
+		return zero, false
+	}
+	var filled []string
+	for _, c := range checks {
+		n := 0
+		for i := range rows {
+			if f, ok := match(rows[i]); ok && !c.present(rows[i]) && c.present(f) {
+				c.take(&rows[i], f)
+				n++
+			}
+		}
+		switch {
+		case n == 0:
+		case len(rows) == 1:
+			filled = append(filled, fmt.Sprintf("%s %s from %s", label, c.name, source))
+		default:
+			filled = append(filled, fmt.Sprintf("%s %s from %s (%d of %d)", label, c.name, source, n, len(rows)))
+		}
+	}
+	return filled
+}
+
+// GRIBFile is a WeatherProvider reading a GRIB2 file, e.g. from Saildocs. The file
+// is read and decoded once, on first use.
+type GRIBFile struct {
+	Path string
+
+	once   sync.Once
+	fields []GRIBField
+	err    error
+}
+
+func (g *GRIBFile) Name() string { return "GRIB file " + filepath.Base(g.Path) }
+
+// weather builds the weather data at a position from the decoded file, in the
+// nautical time zone of the position unless opts has a timezone.
+func (g *GRIBFile) weather(lat, lon float64, now time.Time, opts WeatherOptions) (WeatherData, error) {
+	g.once.Do(func() { g.fields, g.err = readGRIBFile(g.Path) })
+	if g.err != nil {
+		return WeatherData{}, g.err
+	}
+	if opts.Timezone == "" {
+		opts.Timezone = nauticalTimezone(lon)
+	}
+	return gribWeather(g.fields, lat, lon, now, opts)
+}
+
+func (g *GRIBFile) Forecast(lat, lon float64, opts WeatherOptions) (WeatherData, error) {
+	w, err := g.weather(lat, lon, time.Now(), opts)
+	w.Current, w.Marine, w.HourlyMarine = CurrentWeather{}, MarineData{}, nil
+	return w, err
+}
+
+func (g *GRIBFile) Observations(lat, lon float64, opts WeatherOptions) (CurrentWeather, error) {
+	w, err := g.weather(lat, lon, time.Now(), opts)
+	return w.Current, err
+}
+
+// Marine returns errNotProvided for files without waves.
+func (g *GRIBFile) Marine(lat, lon float64, opts WeatherOptions) (marineResult, error) {
+	w, err := g.weather(lat, lon, time.Now(), opts)
+	if err != nil {
+		return marineResult{}, err
+	}
+	if !hasMarineData(w) {
+		return marineResult{}, errNotProvided
+	}
+	return marineResult{Current: w.Marine, Hourly: w.HourlyMarine}, nil
+}
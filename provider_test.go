@@ -0,0 +1,306 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testProvider answers with fixed data; a nil marine result means errNotProvided.
+type testProvider struct {
+	name     string
+	forecast WeatherData
+	current  CurrentWeather
+	marine   *marineResult
+	err      error
+	calls    int
+}
+
+func (p *testProvider) Name() string { return p.name }
+
+func (p *testProvider) Forecast(lat, lon float64, opts WeatherOptions) (WeatherData, error) {
+	p.calls++
+	return p.forecast, p.err
+}
+
+func (p *testProvider) Observations(lat, lon float64, opts WeatherOptions) (CurrentWeather, error) {
+	return p.current, p.err
+}
+
+func (p *testProvider) Marine(lat, lon float64, opts WeatherOptions) (marineResult, error) {
+	if p.marine == nil {
+		return marineResult{}, errNotProvided
+	}
+	return *p.marine, p.err
+}
+
+// completeHour has every hourly value, so that nothing needs filling in.
+func completeHour(t string, wind float64) HourlyForecast {
+	return HourlyForecast{Time: t, Temperature: ptr(18.0), WindSpeed: ptr(wind), WindGusts: ptr(wind * 1.5), WindDirection: ptr(180.0),
+		Precipitation: ptr(0.0), WeatherCode: ptr(1), Visibility: ptr(20000.0), CAPE: ptr(0.0), LiftedIndex: ptr(2.0), Pressure: ptr(1015.0)}
+}
+
+func TestMergeWeather(t *testing.T) {
+	// The GRIB file has wind and pressure but no gusts, temperature or sea state.
+	gribHour := func(t string) HourlyForecast {
+		return HourlyForecast{Time: t, WindSpeed: ptr(20.0), WindDirection: ptr(200.0), Pressure: ptr(1012.0)}
+	}
+	grib := &testProvider{
+		name:     "GRIB file",
+		forecast: WeatherData{Timezone: "UTC", Hourly: []HourlyForecast{gribHour("2026-03-15T12:00"), gribHour("2026-03-15T13:00")}},
+		current:  CurrentWeather{WindSpeed: ptr(18.0), WindDirection: ptr(190.0)},
+	}
+	online := &testProvider{
+		name:     "Open-Meteo",
+		forecast: WeatherData{Timezone: "UTC", Hourly: []HourlyForecast{completeHour("2026-03-15T13:00", 30), completeHour("2026-03-15T14:00", 30)}},
+		current:  CurrentWeather{Temperature: ptr(17.5), WindSpeed: ptr(25.0), WindDirection: ptr(180.0), Humidity: ptr(70)},
+		marine:   &marineResult{Current: MarineData{WaveHeight: ptr(1.2)}, Hourly: []HourlyMarine{{Time: "2026-03-15T12:00", WaveHeight: ptr(1.2)}}},
+	}
+	broken := &testProvider{name: "broken", err: errors.New("status 503")}
+
+	w, err := mergeWeather(43.5, 16.4, WeatherOptions{Units: UnitsMetric, Providers: []WeatherProvider{broken, grib, online}})
+	if err != nil {
+		t.Fatalf("mergeWeather: %v", err)
+	}
+	// The primary's values stay; only the missing ones are filled in, at the same hour.
+	if h := w.Hourly[0]; *h.WindSpeed != 20 || h.WindGusts != nil || h.Temperature != nil {
+		t.Errorf("12:00 = %+v, want the GRIB wind and nothing from Open-Meteo", h)
+	}
+	if h := w.Hourly[1]; *h.WindSpeed != 20 || h.WindGusts == nil || *h.WindGusts != 45 || *h.Temperature != 18 {
+		t.Errorf("13:00 = %+v, want the GRIB wind and the Open-Meteo gusts", h)
+	}
+	if len(w.Hourly) != 2 {
+		t.Errorf("%d hours, want the primary's 2", len(w.Hourly))
+	}
+	if c := w.Current; *c.WindSpeed != 18 || c.Temperature == nil || *c.Temperature != 17.5 || *c.Humidity != 70 {
+		t.Errorf("Current = %+v", c)
+	}
+	if w.Marine.WaveHeight == nil || len(w.HourlyMarine) != 1 {
+		t.Errorf("marine data = %+v, %+v", w.Marine, w.HourlyMarine)
+	}
+
+	want := []string{
+		"forecast from GRIB file",
+		"hourly temperature from Open-Meteo (1 of 2)",
+		"hourly gusts from Open-Meteo (1 of 2)",
+		"current weather from GRIB file",
+		"current temperature from Open-Meteo",
+		"marine from Open-Meteo",
+	}
+	for _, s := range want {
+		if !strings.Contains(strings.Join(w.Sources, "|"), s) {
+			t.Errorf("Sources = %q, missing %q", w.Sources, s)
+		}
+	}
+	if got := FormatDataQuality(w); !strings.Contains(got, "Sources: forecast from GRIB file; hourly temperature from Open-Meteo (1 of 2);") {
+		t.Errorf("FormatDataQuality:\n%s", got)
+	}
+
+	// A complete primary forecast leaves the fallback alone.
+	online.calls = 0
+	complete := &testProvider{name: "complete", forecast: WeatherData{Hourly: []HourlyForecast{completeHour("2026-03-15T12:00", 10)}}}
+	if _, err := mergeWeather(43.5, 16.4, WeatherOptions{Providers: []WeatherProvider{complete, online}}); err != nil {
+		t.Fatal(err)
+	}
+	if online.calls != 0 {
+		t.Errorf("fallback asked for the forecast %d times, want 0", online.calls)
+	}
+
+	if _, err := mergeWeather(43.5, 16.4, WeatherOptions{Providers: []WeatherProvider{broken}}); err == nil || !strings.Contains(err.Error(), "broken: status 503") {
+		t.Errorf("all providers failing: err = %v", err)
+	}
+}
+
+func TestParseWeatherProviders(t *testing.T) {
+	providers, err := ParseWeatherProviders([]string{"file:/tmp/GFS.grb2", "open-meteo", "met-no"}, "http://localhost:8080/", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(providers) != 3 {
+		t.Fatalf("providers = %+v", providers)
+	}
+	if g, ok := providers[0].(*GRIBFile); !ok || g.Path != "/tmp/GFS.grb2" {
+		t.Errorf("GRIB file provider = %+v", providers[0])
+	}
+	if om := providers[1].(*OpenMeteo); *om != (OpenMeteo{"http://localhost:8080", "http://localhost:8080", "http://localhost:8080"}) {
+		t.Errorf("self-hosted Open-Meteo = %+v", om)
+	}
+	if opts := (WeatherOptions{Providers: providers}); opts.openMeteo() != providers[1] {
+		t.Error("openMeteo() does not find the configured instance")
+	}
+	if m := providers[2].(*MetNorway); m.BaseURL != metNorwayURL {
+		t.Errorf("MET Norway = %+v", m)
+	}
+	if _, err := ParseWeatherProviders([]string{"dwd"}, "", ""); err == nil {
+		t.Error("unknown provider: no error")
+	}
+}
+
+func TestOpenMeteoBaseURL(t *testing.T) {
+	var paths []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		switch r.URL.Path {
+		case "/v1/forecast":
+			fmt.Fprint(w, `{"timezone": "Europe/Zagreb",
+				"current": {"time": "2026-03-15T12:15", "temperature_2m": 16.5, "wind_speed_10m": 12},
+				"hourly": {"time": ["2026-03-15T11:00", "2026-03-15T12:00"], "wind_speed_10m": [10, 12], "pressure_msl": [1016, 1015]}}`)
+		case "/v1/marine":
+			fmt.Fprint(w, `{"current": {"wave_height": 0.8}, "hourly": {"time": ["2026-03-15T12:00"], "wave_height": [0.8]}}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	w, err := FetchWeather(43.5, 16.4, WeatherOptions{Units: UnitsMetric, Providers: []WeatherProvider{NewOpenMeteo(srv.URL)}})
+	if err != nil {
+		t.Fatalf("FetchWeather: %v", err)
+	}
+	if len(w.Hourly) != 1 || *w.Hourly[0].WindSpeed != 12 || len(w.PressureHistory) != 1 {
+		t.Errorf("hourly = %+v, history = %+v", w.Hourly, w.PressureHistory)
+	}
+	if *w.Current.Temperature != 16.5 || *w.Marine.WaveHeight != 0.8 || w.Timezone != "Europe/Zagreb" {
+		t.Errorf("current = %+v, marine = %+v", w.Current, w.Marine)
+	}
+	if strings.Join(paths, ",") != "/v1/forecast,/v1/forecast,/v1/marine" {
+		t.Errorf("requested %v", paths)
+	}
+}
+
+func TestMetNorway(t *testing.T) {
+	// Hourly steps from the current hour, then a six-hourly one.
+	now := time.Now().UTC().Truncate(time.Hour)
+	step := func(t time.Time, wind, rain float64, symbol string) string {
+		next := ""
+		if symbol != "" {
+			next = fmt.Sprintf(`, "next_1_hours": {"summary": {"symbol_code": %q}, "details": {"precipitation_amount": %g, "probability_of_precipitation": 40}}`, symbol, rain)
+		}
+		return fmt.Sprintf(`{"time": %q, "data": {"instant": {"details": {"air_temperature": 15, "air_pressure_at_sea_level": 1012.3,
+			"relative_humidity": 81.6, "cloud_area_fraction": 99.2, "wind_speed": %g, "wind_speed_of_gust": %g, "wind_from_direction": 225}}%s}}`,
+			t.Format(time.RFC3339), wind, wind*1.5, next)
+	}
+	body := `{"properties": {"timeseries": [` + strings.Join([]string{
+		step(now.Add(-time.Hour), 4, 0, "cloudy"),
+		step(now, 5, 0.4, "lightrain_day"),
+		step(now.Add(time.Hour), 10, 2.1, "rainandthunder"),
+		step(now.Add(2*time.Hour), 12, 0, ""),
+		step(now.Add(8*time.Hour), 6, 0, ""),
+	}, ",") + `]}}`
+	var agent string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		agent = r.UserAgent()
+		if r.URL.Path != "/locationforecast/2.0/complete" || r.URL.Query().Get("lat") != "43.5081" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, body)
+	}))
+	defer srv.Close()
+
+	m := NewMetNorway(srv.URL)
+	opts := WeatherOptions{Units: UnitsNautical}
+	w, err := m.Forecast(43.50812, 16.44, opts)
+	if err != nil {
+		t.Fatalf("Forecast: %v", err)
+	}
+	if agent != userAgent {
+		t.Errorf("User-Agent = %q", agent)
+	}
+	// Split is one hour east of UTC; the hours and days follow the local clock.
+	local := time.FixedZone("", 3600)
+	if len(w.Hourly) != 3 || w.Hourly[0].Time != now.In(local).Format(openMeteoTime) || w.Timezone != "Etc/GMT-1" {
+		t.Fatalf("Hourly = %+v", w.Hourly)
+	}
+	// 12 m/s is 23.3 kn; the rain of the hour before is counted at the end of the hour.
+	if h := w.Hourly[2]; math.Abs(*h.WindSpeed-23.33) > 0.01 || *h.Precipitation != 2.1 || h.WeatherCode != nil {
+		t.Errorf("+2h = %+v", h)
+	}
+	if h := w.Hourly[1]; *h.Precipitation != 0.4 || *h.WeatherCode != 95 {
+		t.Errorf("+1h = %+v", h)
+	}
+	if h := w.Hourly[0]; *h.Precipitation != 0 || *h.WeatherCode != 61 || *h.Pressure != 1012.3 {
+		t.Errorf("now = %+v", h)
+	}
+	if len(w.Daily) == 0 || w.Daily[0].Date != now.In(local).Format("2006-01-02") || w.Daily[0].PrecipitationProb == nil || *w.Daily[0].PrecipitationProb != 40 {
+		t.Errorf("Daily = %+v", w.Daily)
+	}
+
+	c, err := m.Observations(43.50812, 16.44, opts)
+	if err != nil {
+		t.Fatalf("Observations: %v", err)
+	}
+	if *c.Humidity != 82 || *c.CloudCover != 99 || *c.WeatherCode != 61 || *c.Precipitation != 0.4 || c.Visibility != nil {
+		t.Errorf("Observations = %+v", c)
+	}
+	// An explicit timezone wins.
+	if w, err := m.Forecast(43.50812, 16.44, WeatherOptions{Timezone: "UTC"}); err != nil || w.Hourly[0].Time != now.Format(openMeteoTime) {
+		t.Errorf("Forecast in UTC: %v, %+v", err, w.Hourly)
+	}
+	if _, err := m.Marine(43.50812, 16.44, opts); !errors.Is(err, errNotProvided) {
+		t.Errorf("Marine: err = %v, want errNotProvided", err)
+	}
+}
+
+func TestNauticalTimezone(t *testing.T) {
+	for _, tc := range []struct {
+		lon  float64
+		want string
+	}{
+		{16.44, "Etc/GMT-1"}, {-28.6, "Etc/GMT+2"}, {7.4, "UTC"}, {-7.6, "Etc/GMT+1"}, {179.9, "Etc/GMT-12"}, {-179.9, "Etc/GMT+12"},
+	} {
+		got := nauticalTimezone(tc.lon)
+		if got != tc.want {
+			t.Errorf("nauticalTimezone(%g) = %s, want %s", tc.lon, got, tc.want)
+		}
+		if _, err := time.LoadLocation(got); err != nil {
+			t.Errorf("nauticalTimezone(%g): %v", tc.lon, err)
+		}
+	}
+}
+
+func TestMetNorwayCode(t *testing.T) {
+	for symbol, want := range map[string]int{
+		"clearsky_day": 0, "lightsleet": 61, "sleet_night": 63, "heavysleet": 65,
+		"heavyrainandthunder": 95, "lightsnowshowers_polartwilight": 85,
+	} {
+		if got := metNorwayCode(symbol); got == nil || *got != want {
+			t.Errorf("metNorwayCode(%q) = %v, want %d", symbol, got, want)
+		}
+	}
+	if got := metNorwayCode("unknown"); got != nil {
+		t.Errorf("metNorwayCode(unknown) = %d, want nil", *got)
+	}
+}
+
+func TestGRIBFileProvider(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "saildocs.grb2")
+	if err := os.WriteFile(path, testGRIB(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	g := &GRIBFile{Path: path}
+	now := time.Date(2026, 3, 15, 3, 30, 0, 0, time.UTC)
+	w, err := g.weather(43.5, 15.5, now, WeatherOptions{ForecastHours: 6})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Like MET Norway, in the nautical time zone of 15.5°E.
+	if w.Timezone != "Etc/GMT-1" || w.Hourly[0].Time != "2026-03-15T04:00" {
+		t.Errorf("timezone %s, first hour %s, want Etc/GMT-1 from 04:00", w.Timezone, w.Hourly[0].Time)
+	}
+
+	// The file is decoded once: the other kinds of data do not read it again.
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if w, err := g.weather(43.5, 15.5, now, WeatherOptions{Timezone: "UTC"}); err != nil || w.Timezone != "UTC" || w.Marine.WaveHeight == nil {
+		t.Errorf("second read: %s, %+v, %v", w.Timezone, w.Marine, err)
+	}
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// fieldCheck names a value of a forecast row, reports whether the weather model
+// reported it and copies it from another row when merging providers.
+type fieldCheck[T any] struct {
+	name    string
+	present func(T) bool
+	take    func(dst *T, src T)
+}
+
+var currentChecks = []fieldCheck[CurrentWeather]{
+	{"temperature",
+		func(c CurrentWeather) bool { return c.Temperature != nil },
+		func(c *CurrentWeather, from CurrentWeather) { c.Temperature = from.Temperature }},
+	{"wind",
+		func(c CurrentWeather) bool { return c.WindSpeed != nil && c.WindDirection != nil },
+		func(c *CurrentWeather, from CurrentWeather) {
+			c.WindSpeed, c.WindDirection = from.WindSpeed, from.WindDirection
+		}},
+	{"gusts",
+		func(c CurrentWeather) bool { return c.WindGusts != nil },
+		func(c *CurrentWeather, from CurrentWeather) { c.WindGusts = from.WindGusts }},
+	{"weather code",
+		func(c CurrentWeather) bool { return c.WeatherCode != nil },
+		func(c *CurrentWeather, from CurrentWeather) { c.WeatherCode = from.WeatherCode }},
+	{"humidity",
+		func(c CurrentWeather) bool { return c.Humidity != nil },
+		func(c *CurrentWeather, from CurrentWeather) { c.Humidity = from.Humidity }},
+	{"pressure",
+		func(c CurrentWeather) bool { return c.Pressure != nil },
+		func(c *CurrentWeather, from CurrentWeather) { c.Pressure = from.Pressure }},
+	{"cloud cover",
+		func(c CurrentWeather) bool { return c.CloudCover != nil },
+		func(c *CurrentWeather, from CurrentWeather) { c.CloudCover = from.CloudCover }},
+	{"precipitation",
+		func(c CurrentWeather) bool { return c.Precipitation != nil },
+		func(c *CurrentWeather, from CurrentWeather) { c.Precipitation = from.Precipitation }},
+	{"visibility",
+		func(c CurrentWeather) bool { return c.Visibility != nil },
+		func(c *CurrentWeather, from CurrentWeather) { c.Visibility = from.Visibility }},
+}
+
+var dailyChecks = []fieldCheck[DailyForecast]{
+	{"temperature",
+		func(d DailyForecast) bool { return d.TempMin != nil && d.TempMax != nil },
+		func(d *DailyForecast, from DailyForecast) { d.TempMin, d.TempMax = from.TempMin, from.TempMax }},
+	{"wind",
+		func(d DailyForecast) bool { return d.WindSpeedMax != nil && d.WindDirection != nil },
+		func(d *DailyForecast, from DailyForecast) {
+			d.WindSpeedMax, d.WindDirection = from.WindSpeedMax, from.WindDirection
+		}},
+	{"gusts",
+		func(d DailyForecast) bool { return d.WindGustsMax != nil },
+		func(d *DailyForecast, from DailyForecast) { d.WindGustsMax = from.WindGustsMax }},
+	{"weather code",
+		func(d DailyForecast) bool { return d.WeatherCode != nil },
+		func(d *DailyForecast, from DailyForecast) { d.WeatherCode = from.WeatherCode }},
+	{"precipitation",
+		func(d DailyForecast) bool { return d.PrecipitationSum != nil && d.PrecipitationProb != nil },
+		func(d *DailyForecast, from DailyForecast) {
+			d.PrecipitationSum, d.PrecipitationProb = from.PrecipitationSum, from.PrecipitationProb
+		}},
+}
+
+var hourlyChecks = []fieldCheck[HourlyForecast]{
+	{"temperature",
+		func(h HourlyForecast) bool { return h.Temperature != nil },
+		func(h *HourlyForecast, from HourlyForecast) { h.Temperature = from.Temperature }},
+	{"wind",
+		func(h HourlyForecast) bool { return h.WindSpeed != nil && h.WindDirection != nil },
+		func(h *HourlyForecast, from HourlyForecast) {
+			h.WindSpeed, h.WindDirection = from.WindSpeed, from.WindDirection
+		}},
+	{"gusts",
+		func(h HourlyForecast) bool { return h.WindGusts != nil },
+		func(h *HourlyForecast, from HourlyForecast) { h.WindGusts = from.WindGusts }},
+	{"weather code",
+		func(h HourlyForecast) bool { return h.WeatherCode != nil },
+		func(h *HourlyForecast, from HourlyForecast) { h.WeatherCode = from.WeatherCode }},
+	{"precipitation",
+		func(h HourlyForecast) bool { return h.Precipitation != nil },
+		func(h *HourlyForecast, from HourlyForecast) { h.Precipitation = from.Precipitation }},
+	{"visibility",
+		func(h HourlyForecast) bool { return h.Visibility != nil },
+		func(h *HourlyForecast, from HourlyForecast) { h.Visibility = from.Visibility }},
+	{"CAPE",
+		func(h HourlyForecast) bool { return h.CAPE != nil },
+		func(h *HourlyForecast, from HourlyForecast) { h.CAPE = from.CAPE }},
+	{"lifted index",
+		func(h HourlyForecast) bool { return h.LiftedIndex != nil },
+		func(h *HourlyForecast, from HourlyForecast) { h.LiftedIndex = from.LiftedIndex }},
+	{"pressure",
+		func(h HourlyForecast) bool { return h.Pressure != nil },
+		func(h *HourlyForecast, from HourlyForecast) { h.Pressure = from.Pressure }},
+}
+
+var marineChecks = []fieldCheck[HourlyMarine]{
+	{"wave height",
+		func(m HourlyMarine) bool { return m.WaveHeight != nil },
+		func(m *HourlyMarine, from HourlyMarine) { m.WaveHeight = from.WaveHeight }},
+	{"wave period",
+		func(m HourlyMarine) bool { return m.WavePeriod != nil },
+		func(m *HourlyMarine, from HourlyMarine) { m.WavePeriod = from.WavePeriod }},
+	{"swell",
+		func(m HourlyMarine) bool { return m.SwellWaveHeight != nil && m.SwellWaveDir != nil },
+		func(m *HourlyMarine, from HourlyMarine) {
+			m.SwellWaveHeight, m.SwellWaveDir = from.SwellWaveHeight, from.SwellWaveDir
+		}},
+	{"sea level",
+		func(m HourlyMarine) bool { return m.SeaLevel != nil },
+		func(m *HourlyMarine, from HourlyMarine) { m.SeaLevel = from.SeaLevel }},
+	{"current",
+		func(m HourlyMarine) bool { return m.CurrentVelocity != nil && m.CurrentDirection != nil },
+		func(m *HourlyMarine, from HourlyMarine) {
+			m.CurrentVelocity, m.CurrentDirection = from.CurrentVelocity, from.CurrentDirection
+		}},
+	{"sea temperature",
+		func(m HourlyMarine) bool { return m.SeaSurfaceTemp != nil },
+		func(m *HourlyMarine, from HourlyMarine) { m.SeaSurfaceTemp = from.SeaSurfaceTemp }},
+}
+
+// missingValues lists, for each checked value that is missing in some rows, how
+// often and when.
+func missingValues[T any](rows []T, timeOf func(T) string, checks []fieldCheck[T]) []string {
+	var missing []string
+	for _, c := range checks {
+		var times []string
+		for _, r := range rows {
+			if !c.present(r) {
+				times = append(times, timeOf(r))
+			}
+		}
+		switch {
+		case len(times) == 0:
+		case len(times) == len(rows):
+			missing = append(missing, c.name+" (all)")
+		default:
+			missing = append(missing, fmt.Sprintf("%s (%d of %d: %s)", c.name, len(times), len(rows), formatHourRanges(times)))
+		}
+	}
+	return missing
+}
+
+// FormatDataQuality tells the model which values the weather models did not report,
+// so that "n/a" is never read as calm wind or a flat sea.
+func FormatDataQuality(w WeatherData) string {
+	var b strings.Builder
+	b.WriteString("\n=== DATA QUALITY ===\n")
+	line := func(label string, count int, unit string, missing []string) {
+		switch {
+		case count == 0:
+			b.WriteString(fmt.Sprintf("%s: not available\n", label))
+		case len(missing) == 0:
+			b.WriteString(fmt.Sprintf("%s: %d %s, complete\n", label, count, unit))
+		default:
+			b.WriteString(fmt.Sprintf("%s: %d %s, missing %s\n", label, count, unit, strings.Join(missing, ", ")))
+		}
+	}
+
+	var current []string
+	for _, c := range currentChecks {
+		if !c.present(w.Current) {
+			current = append(current, c.name)
+		}
+	}
+	if len(current) == 0 {
+		b.WriteString("Current weather: complete\n")
+	} else {
+		b.WriteString(fmt.Sprintf("Current weather: missing %s\n", strings.Join(current, ", ")))
+	}
+
+	line("Daily forecast", len(w.Daily), "days", missingValues(w.Daily, func(d DailyForecast) string { return d.Date }, dailyChecks))
+	line("Hourly forecast", len(w.Hourly), "hours", missingValues(w.Hourly, func(h HourlyForecast) string { return h.Time }, hourlyChecks))
+	if !hasMarineData(w) {
+		b.WriteString("Marine data: not available for this position, the sea state is unknown (not calm)\n")
+	} else {
+		line("Hourly marine forecast", len(w.HourlyMarine), "hours", missingValues(w.HourlyMarine, func(m HourlyMarine) string { return m.Time }, marineChecks))
+	}
+	if len(w.Sources) > 0 {
+		b.WriteString(fmt.Sprintf("Sources: %s\n", strings.Join(w.Sources, "; ")))
+	}
+	b.WriteString("Values shown as n/a were not reported by the weather model. Do not read them as zero.\n")
+	return b.String()
+}
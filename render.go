@@ -0,0 +1,243 @@
+package main
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"fmt"
+	htmltemplate "html/template"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"sailingnomads-briefing/logseq"
+)
+
+// Output formats of the briefing, the values of the --format flag.
+const (
+	FormatLogseq   = "logseq"
+	FormatObsidian = "obsidian"
+	FormatMarkdown = "markdown"
+	FormatHTML     = "html"
+	FormatJSON     = "json"
+)
+
+// OutputFormats lists the supported output formats.
+var OutputFormats = []string{FormatLogseq, FormatObsidian, FormatMarkdown, FormatHTML, FormatJSON}
+
+//go:embed templates/*.tmpl
+var templateFS embed.FS
+
+var templateFuncs = map[string]any{
+	// oneline keeps model text from breaking the block or list structure.
+	"oneline": func(s string) string {
+		return strings.Join(strings.Fields(s), " ")
+	},
+	"yaml": strconv.Quote, // a JSON string is a valid YAML scalar
+	"obsidianList": func(blocks []*logseq.Block) string {
+		return strings.TrimRight(markdownList(blocks, "", "\t", true), "\n")
+	},
+	"markdownList": func(blocks []*logseq.Block) string {
+		return strings.TrimRight(markdownList(blocks, "", "  ", false), "\n")
+	},
+	"plain":    stripPageLinks,
+	"htmlList": htmlList,
+	"inline":   htmlInline,
+}
+
+var (
+	textTemplates = template.Must(template.New("").Funcs(templateFuncs).ParseFS(templateFS, "templates/logseq.tmpl", "templates/obsidian.tmpl", "templates/markdown.tmpl"))
+	htmlTemplates = htmltemplate.Must(htmltemplate.New("").Funcs(templateFuncs).ParseFS(templateFS, "templates/html.tmpl"))
+)
+
+// RenderStructuredBriefing renders the structured briefing as Logseq blocks.
+func RenderStructuredBriefing(doc StructuredBriefing) (string, error) {
+	var b bytes.Buffer
+	if err := textTemplates.ExecuteTemplate(&b, "logseq.tmpl", doc); err != nil {
+		return "", fmt.Errorf("rendering %s: %w", FormatLogseq, err)
+	}
+	return strings.TrimRight(b.String(), "\n") + "\n", nil
+}
+
+// Output is a finished Logseq briefing together with the data it was written from.
+// Every output format is rendered from it, so all formats carry the same content.
+type Output struct {
+	Date      string
+	Latitude  float64
+	Longitude float64
+	Location  string
+	Sections  []*logseq.Block // the blocks below the header
+	Hazards   []Hazard
+	Weather   WeatherData
+	Logseq    string
+}
+
+// NewOutput parses a Logseq briefing for rendering. Position and place come from
+// the header block, or from in if the briefing has none.
+func NewOutput(briefing string, in BriefingInput, now time.Time) Output {
+	out := Output{
+		Date:      now.Format("2006-01-02"),
+		Latitude:  in.Location.Latitude,
+		Longitude: in.Location.Longitude,
+		Location:  briefingHeader(in).Location,
+		Hazards:   in.Hazards,
+		Weather:   in.Weather,
+		Logseq:    briefing,
+	}
+
+	page := logseq.Parse(briefing)
+	if len(page.Blocks) == 0 || !logseq.IsBriefing(page.Blocks[0]) {
+		out.Sections = page.Blocks
+		return out
+	}
+	for _, b := range page.Blocks[0].Children {
+		position, hasPosition := b.Property("position")
+		location, hasLocation := b.Property("location")
+		if !hasPosition || !hasLocation {
+			out.Sections = append(out.Sections, b)
+			continue
+		}
+		if lat, lon, ok := logseq.ParsePosition(position); ok {
+			out.Latitude, out.Longitude = lat, lon
+		}
+		out.Location = location
+	}
+	return out
+}
+
+// RenderOutput renders the briefing in the given format.
+func RenderOutput(out Output, format string) (string, error) {
+	var b bytes.Buffer
+	var err error
+	switch format {
+	case FormatLogseq:
+		return out.Logseq, nil
+	case FormatObsidian, FormatMarkdown:
+		err = textTemplates.ExecuteTemplate(&b, format+".tmpl", out)
+	case FormatHTML:
+		err = htmlTemplates.ExecuteTemplate(&b, "html.tmpl", out)
+	case FormatJSON:
+		return renderJSON(out)
+	default:
+		return "", fmt.Errorf("unknown format %q (expected one of %s)", format, strings.Join(OutputFormats, ", "))
+	}
+	if err != nil {
+		return "", fmt.Errorf("rendering %s: %w", format, err)
+	}
+	return strings.TrimRight(b.String(), "\n") + "\n", nil
+}
+
+// jsonOutput is the document of the json format.
+type jsonOutput struct {
+	Date      string        `json:"date"`
+	Location  string        `json:"location"`
+	Latitude  float64       `json:"latitude"`
+	Longitude float64       `json:"longitude"`
+	Warnings  []jsonWarning `json:"warnings"`
+	Weather   WeatherData   `json:"weather"`
+	Briefing  string        `json:"briefing"` // Markdown
+	Logseq    string        `json:"logseq"`
+}
+
+type jsonWarning struct {
+	Start     string  `json:"start"`
+	End       string  `json:"end"`
+	Parameter string  `json:"parameter"`
+	Value     float64 `json:"value"`
+	Unit      string  `json:"unit"`
+	Severity  string  `json:"severity"`
+	Text      string  `json:"text"`
+}
+
+func renderJSON(out Output) (string, error) {
+	text, err := RenderOutput(out, FormatMarkdown)
+	if err != nil {
+		return "", err
+	}
+	doc := jsonOutput{
+		Date:      out.Date,
+		Location:  out.Location,
+		Latitude:  out.Latitude,
+		Longitude: out.Longitude,
+		Warnings:  []jsonWarning{},
+		Weather:   out.Weather,
+		Briefing:  text,
+		Logseq:    out.Logseq,
+	}
+	for _, h := range out.Hazards {
+		doc.Warnings = append(doc.Warnings, jsonWarning{
+			Start:     h.Start,
+			End:       h.End,
+			Parameter: h.Parameter,
+			Value:     h.Value,
+			Unit:      h.Unit,
+			Severity:  h.Severity.String(),
+			Text:      h.Window() + ": " + h.describe(out.Weather.Units),
+		})
+	}
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("rendering %s: %w", FormatJSON, err)
+	}
+	return string(data) + "\n", nil
+}
+
+var (
+	pageLinkRe = regexp.MustCompile(`\[\[([^\]]+)\]\]`)
+	linkRe     = regexp.MustCompile(`\[([^\]]+)\]\((https?://[^)\s]+)\)`)
+	boldRe     = regexp.MustCompile(`\*\*(.+?)\*\*`)
+)
+
+// stripPageLinks turns Logseq page links like [[Split]] into plain text.
+func stripPageLinks(s string) string {
+	return pageLinkRe.ReplaceAllString(s, "$1")
+}
+
+// markdownList renders blocks as a nested Markdown list. Obsidian understands
+// [[page links]]; for plain Markdown they are removed.
+func markdownList(blocks []*logseq.Block, indent, step string, pageLinks bool) string {
+	var b strings.Builder
+	for _, block := range blocks {
+		text := block.Text()
+		if !pageLinks {
+			text = stripPageLinks(text)
+		}
+		b.WriteString(indent + "- " + strings.ReplaceAll(text, "\n", "\n"+indent+"  ") + "\n")
+		b.WriteString(markdownList(block.Children, indent+step, step, pageLinks))
+	}
+	return b.String()
+}
+
+// htmlInline escapes block text and keeps its Markdown links and bold text.
+func htmlInline(s string) htmltemplate.HTML {
+	s = htmltemplate.HTMLEscapeString(stripPageLinks(s))
+	s = linkRe.ReplaceAllString(s, `<a href="$2">$1</a>`)
+	s = boldRe.ReplaceAllString(s, "<strong>$1</strong>")
+	return htmltemplate.HTML(strings.ReplaceAll(s, "\n", "<br>"))
+}
+
+// htmlList renders blocks as nested lists, highlighting warnings.
+func htmlList(blocks []*logseq.Block) htmltemplate.HTML {
+	if len(blocks) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("<ul>\n")
+	for _, block := range blocks {
+		text := block.Text()
+		if strings.HasPrefix(text, "⚠️") || strings.HasPrefix(text, "⛔") {
+			b.WriteString(`<li class="warning">`)
+		} else {
+			b.WriteString("<li>")
+		}
+		b.WriteString(string(htmlInline(text)))
+		if len(block.Children) > 0 {
+			b.WriteString("\n" + string(htmlList(block.Children)) + "\n")
+		}
+		b.WriteString("</li>\n")
+	}
+	b.WriteString("</ul>")
+	return htmltemplate.HTML(b.String())
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+const sampleLogseqBriefing = "- [[Tagesbriefing]]\n" +
+	"\t- position:: 43.50800, 16.44000\n" +
+	"\t  location:: Split, Kroatien\n" +
+	"\t- ⚠️ WARNUNG\n" +
+	"\t\t- ⚠️ heute 14:00–18:00: Böen 50 km/h\n" +
+	"\t- Wetter und Seegang\n" +
+	"\t\t- Sonnig, 22°C\n" +
+	"\t\t\t- Bora ab Mittag in [[Split]]\n" +
+	"\t- Veranstaltungen\n" +
+	"\t\t- [Fischmarkt](https://example.com/market?a=1&b=2) in der **Peskarija**\n" +
+	"\t- Gute Fahrt <3\n"
+
+func sampleOutput() Output {
+	in := BriefingInput{
+		Location: Location{Latitude: 43.5, Longitude: 16.4, City: "Split", Country: "Croatia", DisplayName: "Split, Croatia"},
+		Weather:  WeatherData{Units: UnitsMetric, Current: CurrentWeather{Temperature: ptr(22.0)}},
+		Hazards:  []Hazard{{Start: "2026-03-15T14:00", End: "2026-03-15T18:00", Parameter: "gusts", Value: 50, Unit: "km/h", Severity: SeverityWarning}},
+	}
+	return NewOutput(sampleLogseqBriefing, in, time.Date(2026, 3, 15, 7, 0, 0, 0, time.UTC))
+}
+
+func TestNewOutput(t *testing.T) {
+	out := sampleOutput()
+	if out.Date != "2026-03-15" || out.Location != "Split, Kroatien" || out.Latitude != 43.508 || out.Longitude != 16.44 {
+		t.Errorf("header = %s %s %v %v", out.Date, out.Location, out.Latitude, out.Longitude)
+	}
+	if len(out.Sections) != 4 || out.Sections[1].Text() != "Wetter und Seegang" {
+		t.Errorf("sections = %d", len(out.Sections))
+	}
+
+	// Without a header the position comes from the input.
+	out = NewOutput("- Wetter\n", BriefingInput{Location: Location{Latitude: 43.5, Longitude: 16.4, City: "Split", Country: "Croatia", DisplayName: "Split"}}, time.Now())
+	if out.Location != "Split, Croatia" || out.Latitude != 43.5 || len(out.Sections) != 1 {
+		t.Errorf("without header = %+v", out)
+	}
+}
+
+func TestRenderOutput(t *testing.T) {
+	out := sampleOutput()
+	render := func(format string) string {
+		t.Helper()
+		s, err := RenderOutput(out, format)
+		if err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		return s
+	}
+
+	if got := render(FormatLogseq); got != sampleLogseqBriefing {
+		t.Errorf("Logseq =\n%s", got)
+	}
+
+	wantObsidian := "---\n" +
+		"date: 2026-03-15\n" +
+		"location: \"Split, Kroatien\"\n" +
+		"position: [43.50800, 16.44000]\n" +
+		"warnings: 1\n" +
+		"tags: [tagesbriefing]\n" +
+		"---\n\n" +
+		"# Tagesbriefing: Split, Kroatien\n\n" +
+		"## ⚠️ WARNUNG\n\n" +
+		"- ⚠️ heute 14:00–18:00: Böen 50 km/h\n\n" +
+		"## Wetter und Seegang\n\n" +
+		"- Sonnig, 22°C\n" +
+		"\t- Bora ab Mittag in [[Split]]\n\n" +
+		"## Veranstaltungen\n\n" +
+		"- [Fischmarkt](https://example.com/market?a=1&b=2) in der **Peskarija**\n\n" +
+		"Gute Fahrt <3\n"
+	if got := render(FormatObsidian); got != wantObsidian {
+		t.Errorf("Obsidian =\n%s\nwant\n%s", got, wantObsidian)
+	}
+
+	md := render(FormatMarkdown)
+	for _, want := range []string{"# Tagesbriefing: Split, Kroatien\n\n2026-03-15, Position 43.50800, 16.44000\n", "- Sonnig, 22°C\n  - Bora ab Mittag in Split\n"} {
+		if !strings.Contains(md, want) {
+			t.Errorf("Markdown missing %q in:\n%s", want, md)
+		}
+	}
+
+	html := render(FormatHTML)
+	for _, want := range []string{
+		"<style>",
+		`<li class="warning">⚠️ heute 14:00–18:00: Böen 50 km/h</li>`,
+		`<a href="https://example.com/market?a=1&amp;b=2">Fischmarkt</a> in der <strong>Peskarija</strong>`,
+		"<p>Gute Fahrt &lt;3</p>",
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("HTML missing %q in:\n%s", want, html)
+		}
+	}
+
+	var doc struct {
+		Location string
+		Warnings []struct{ Severity, Text string }
+		Weather  struct{ Current struct{ Temperature float64 } }
+		Briefing string
+		Logseq   string
+	}
+	if err := json.Unmarshal([]byte(render(FormatJSON)), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc.Location != "Split, Kroatien" || len(doc.Warnings) != 1 || doc.Warnings[0].Severity != SeverityWarning.String() ||
+		doc.Weather.Current.Temperature != 22 || doc.Briefing != md || doc.Logseq != sampleLogseqBriefing {
+		t.Errorf("JSON = %+v", doc)
+	}
+
+	if _, err := RenderOutput(out, "pdf"); err == nil {
+		t.Error("unknown format accepted")
+	}
+}
@@ -0,0 +1,375 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+	"time"
+
+	"sailingnomads-briefing/logseq"
+)
+
+// Passage briefing: weather and sea state along a route of waypoints, aligned with
+// the time the boat is expected at each point.
+
+const (
+	routeSpacingNm     = 10 // distance between forecast points along a leg
+	openMeteoBatchSize = 50 // coordinates per multi-coordinate request
+)
+
+// Waypoint is a turning point of a planned route.
+type Waypoint struct {
+	Name     string
+	Lat, Lon float64
+}
+
+// PassagePoint is a point along the route with the forecast for the time we pass it.
+type PassagePoint struct {
+	Leg        int    // 1-based
+	Name       string // waypoint name, empty between waypoints
+	Lat, Lon   float64
+	DistanceNm float64 // from the start of the route
+	Course     float64 // course of the leg, degrees true
+	ETA        time.Time
+	Weather    *HourlyForecast // nil when ETA is beyond the forecast
+	Marine     *HourlyMarine
+}
+
+// Passage is the planned route sampled into points.
+type Passage struct {
+	Waypoints []Waypoint
+	SpeedKn   float64
+	Depart    time.Time
+	Points    []PassagePoint
+	Units     UnitSystem
+}
+
+// ParseWaypoints parses inline waypoints: positions separated by ";", each as
+// "lat,lon" or "name=lat,lon".
+func ParseWaypoints(s string) ([]Waypoint, error) {
+	var waypoints []Waypoint
+	for _, part := range strings.Split(s, ";") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, pos, ok := strings.Cut(part, "=")
+		if !ok {
+			name, pos = "", part
+		}
+		lat, lon, ok := logseq.ParsePosition(pos)
+		if !ok {
+			return nil, fmt.Errorf("invalid waypoint %q", part)
+		}
+		waypoints = append(waypoints, Waypoint{Name: strings.TrimSpace(name), Lat: lat, Lon: lon})
+	}
+	if len(waypoints) < 2 {
+		return nil, fmt.Errorf("a route needs at least two waypoints, got %d", len(waypoints))
+	}
+	return waypoints, nil
+}
+
+type gpxPoint struct {
+	Lat  float64 `xml:"lat,attr"`
+	Lon  float64 `xml:"lon,attr"`
+	Name string  `xml:"name"`
+}
+
+type gpxFile struct {
+	Routes []struct {
+		Points []gpxPoint `xml:"rtept"`
+	} `xml:"rte"`
+	Tracks []struct {
+		Segments []struct {
+			Points []gpxPoint `xml:"trkpt"`
+		} `xml:"trkseg"`
+	} `xml:"trk"`
+	Waypoints []gpxPoint `xml:"wpt"`
+}
+
+// ParseGPX reads the first route of a GPX file. Without a route, the first track
+// is used, and without a track the waypoints in file order.
+func ParseGPX(data []byte) ([]Waypoint, error) {
+	var f gpxFile
+	if err := xml.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("parsing GPX: %w", err)
+	}
+
+	var points []gpxPoint
+	switch {
+	case len(f.Routes) > 0:
+		points = f.Routes[0].Points
+	case len(f.Tracks) > 0:
+		for _, seg := range f.Tracks[0].Segments {
+			points = append(points, seg.Points...)
+		}
+	default:
+		points = f.Waypoints
+	}
+	if len(points) < 2 {
+		return nil, fmt.Errorf("GPX file has no route with at least two points")
+	}
+
+	waypoints := make([]Waypoint, len(points))
+	for i, p := range points {
+		waypoints[i] = Waypoint{Name: strings.TrimSpace(p.Name), Lat: p.Lat, Lon: p.Lon}
+	}
+	return waypoints, nil
+}
+
+// loadRoute reads waypoints from a .gpx file or parses them inline.
+func loadRoute(spec string) ([]Waypoint, error) {
+	if strings.HasSuffix(strings.ToLower(spec), ".gpx") {
+		data, err := os.ReadFile(spec)
+		if err != nil {
+			return nil, err
+		}
+		return ParseGPX(data)
+	}
+	return ParseWaypoints(spec)
+}
+
+// SamplePassage places points along every leg at most spacingNm apart, always
+// including the waypoints, and computes when the boat passes each at speedKn.
+func SamplePassage(waypoints []Waypoint, speedKn float64, depart time.Time, spacingNm float64) Passage {
+	p := Passage{Waypoints: waypoints, SpeedKn: speedKn, Depart: depart}
+	at := func(leg int, name string, lat, lon, dist, course float64) {
+		p.Points = append(p.Points, PassagePoint{
+			Leg:        leg,
+			Name:       name,
+			Lat:        lat,
+			Lon:        lon,
+			DistanceNm: dist,
+			Course:     course,
+			ETA:        depart.Add(time.Duration(dist / speedKn * float64(time.Hour))),
+		})
+	}
+
+	total := 0.0
+	for i := 1; i < len(waypoints); i++ {
+		from, to := waypoints[i-1], waypoints[i]
+		legNm, course := rhumbLine(from.Lat, from.Lon, to.Lat, to.Lon)
+		if i == 1 {
+			at(1, from.Name, from.Lat, from.Lon, 0, course)
+		}
+		steps := max(int(math.Ceil(legNm/spacingNm)), 1)
+		for k := 1; k < steps; k++ {
+			d := legNm * float64(k) / float64(steps)
+			lat, lon := rhumbPoint(from.Lat, from.Lon, course, d)
+			at(i, "", lat, lon, total+d, course)
+		}
+		total += legNm
+		at(i, to.Name, to.Lat, to.Lon, total, course)
+	}
+	return p
+}
+
+// DistanceNm is the length of the route.
+func (p Passage) DistanceNm() float64 {
+	if len(p.Points) == 0 {
+		return 0
+	}
+	return p.Points[len(p.Points)-1].DistanceNm
+}
+
+// FetchPassageForecast fetches the hourly forecast and sea state for every point
+// with batched multi-coordinate requests and picks the hour nearest to each ETA.
+// Times are taken in opts.Timezone so they line up with the departure time.
+func FetchPassageForecast(p *Passage, opts WeatherOptions) error {
+	p.Units = opts.Units
+	loc, err := opts.location()
+	if err != nil {
+		return err
+	}
+	for start := 0; start < len(p.Points); start += openMeteoBatchSize {
+		batch := p.Points[start:min(start+openMeteoBatchSize, len(p.Points))]
+
+		weather, err := fetchForecastBatch(batch, opts)
+		if err != nil {
+			return fmt.Errorf("fetching route weather: %w", err)
+		}
+		marine, err := fetchMarineBatch(batch, opts)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: could not fetch route marine data: %v\n", err)
+		}
+
+		for i := range batch {
+			t := forecastHour(batch[i].ETA, loc)
+			if i < len(weather) {
+				for _, h := range weather[i] {
+					if h.Time == t {
+						batch[i].Weather = &h
+						break
+					}
+				}
+			}
+			if i < len(marine) {
+				for _, m := range marine[i] {
+					if m.Time == t {
+						batch[i].Marine = &m
+						break
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// forecastHour is the hourly forecast time nearest to t, rounded on the clock of loc:
+// in zones with a half-hour offset the whole hours of UTC are not whole hours there.
+func forecastHour(t time.Time, loc *time.Location) string {
+	t = t.In(loc)
+	hour := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, loc)
+	if t.Sub(hour) >= 30*time.Minute {
+		hour = hour.Add(time.Hour)
+	}
+	return hour.Format(openMeteoTime)
+}
+
+// batchCoordinates builds the latitude and longitude query parameters and a cache
+// key on the cache grid for a batch of points.
+func batchCoordinates(points []PassagePoint) (string, string) {
+	lats := make([]string, len(points))
+	lons := make([]string, len(points))
+	keys := make([]string, len(points))
+	for i, pt := range points {
+		lats[i] = fmt.Sprintf("%.4f", pt.Lat)
+		lons[i] = fmt.Sprintf("%.4f", pt.Lon)
+		keys[i] = gridKey(pt.Lat, pt.Lon)
+	}
+	return "latitude=" + strings.Join(lats, ",") + "&longitude=" + strings.Join(lons, ","), strings.Join(keys, ";")
+}
+
+func fetchForecastBatch(points []PassagePoint, opts WeatherOptions) ([][]HourlyForecast, error) {
+	coords, key := batchCoordinates(points)
+	query := fmt.Sprintf("&hourly=%s&timezone=%s&forecast_hours=%d&wind_speed_unit=%s",
+		"wind_speed_10m,wind_direction_10m,wind_gusts_10m,precipitation,weather_code,visibility",
+		opts.timezone(), opts.forecastHours(), opts.Units.windSpeedParam())
+
+	resps, err := fetchJSONBatch[openMeteoWeatherResponse](SourceForecast, key+"|"+query,
+		opts.openMeteo().ForecastURL+"/v1/forecast?"+coords+query)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([][]HourlyForecast, len(resps))
+	for n, resp := range resps {
+		for i, t := range resp.Hourly.Time {
+			result[n] = append(result[n], HourlyForecast{
+				Time:          t,
+				WindSpeed:     safeIndex(resp.Hourly.WindSpeed10m, i),
+				WindDirection: safeIndex(resp.Hourly.WindDirection10m, i),
+				WindGusts:     safeIndex(resp.Hourly.WindGusts10m, i),
+				Precipitation: safeIndex(resp.Hourly.Precipitation, i),
+				WeatherCode:   safeIndex(resp.Hourly.WeatherCode, i),
+				Visibility:    safeIndex(resp.Hourly.Visibility, i),
+			})
+		}
+	}
+	return result, nil
+}
+
+func fetchMarineBatch(points []PassagePoint, opts WeatherOptions) ([][]HourlyMarine, error) {
+	coords, key := batchCoordinates(points)
+	query := fmt.Sprintf("&hourly=wave_height,wave_direction,wave_period&timezone=%s&forecast_hours=%d",
+		opts.timezone(), opts.forecastHours())
+
+	resps, err := fetchJSONBatch[openMeteoMarineResponse](SourceMarine, key+"|"+query,
+		opts.openMeteo().MarineURL+"/v1/marine?"+coords+query)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([][]HourlyMarine, len(resps))
+	for n, resp := range resps {
+		for i, t := range resp.Hourly.Time {
+			result[n] = append(result[n], HourlyMarine{
+				Time:          t,
+				WaveHeight:    safeIndex(resp.Hourly.WaveHeight, i),
+				WaveDirection: safeIndex(resp.Hourly.WaveDirection, i),
+				WavePeriod:    safeIndex(resp.Hourly.WavePeriod, i),
+			})
+		}
+	}
+	return result, nil
+}
+
+// planPassage samples the route for a departure given as local time in the
+// timezone of the forecast (now if empty) and fetches the forecast along it.
+func planPassage(waypoints []Waypoint, speedKn float64, depart, timezone string, units UnitSystem, providers []WeatherProvider) (Passage, error) {
+	tz, err := time.LoadLocation(timezone)
+	if err != nil {
+		tz = time.Local
+	}
+	start := time.Now().In(tz)
+	if depart != "" {
+		if start, err = time.ParseInLocation(openMeteoTime, depart, tz); err != nil {
+			return Passage{}, fmt.Errorf("invalid departure time %q, want YYYY-MM-DDTHH:MM", depart)
+		}
+	}
+
+	p := SamplePassage(waypoints, speedKn, start, routeSpacingNm)
+	err = FetchPassageForecast(&p, WeatherOptions{Units: units, ForecastHours: planForecastHours, Timezone: tz.String(), Providers: providers})
+	return p, err
+}
+
+// FormatPassage renders the leg-by-leg, time-aligned table for the user message.
+func FormatPassage(p Passage) string {
+	var b strings.Builder
+	u := p.Units
+
+	b.WriteString(fmt.Sprintf("=== PASSAGE (planned route at %.1f kn) ===\n", p.SpeedKn))
+	if len(p.Points) == 0 {
+		return b.String()
+	}
+	last := p.Points[len(p.Points)-1]
+	b.WriteString(fmt.Sprintf("Depart %s, arrive %s, %.1f nm in %d legs\n",
+		p.Depart.Format(openMeteoTime), last.ETA.Format(openMeteoTime), p.DistanceNm(), len(p.Waypoints)-1))
+
+	for i := 1; i < len(p.Waypoints); i++ {
+		from, to := p.Waypoints[i-1], p.Waypoints[i]
+		legNm, course := rhumbLine(from.Lat, from.Lon, to.Lat, to.Lon)
+		b.WriteString(fmt.Sprintf("Leg %d: %s → %s, %.1f nm, course %.0f° (%s)\n",
+			i, waypointLabel(from), waypointLabel(to), legNm, course, degToCompass(course)))
+	}
+
+	b.WriteString("ETA | leg | position | nm | wind | gusts | TWA | waves | weather\n")
+	for _, pt := range p.Points {
+		pos := fmt.Sprintf("%.4f, %.4f", pt.Lat, pt.Lon)
+		if pt.Name != "" {
+			pos = pt.Name + " " + pos
+		}
+		row := fmt.Sprintf("%s | %d | %s | %.1f", pt.ETA.Format(openMeteoTime), pt.Leg, pos, pt.DistanceNm)
+		h := pt.Weather
+		if h == nil {
+			b.WriteString(row + " | beyond forecast\n")
+			continue
+		}
+		row += fmt.Sprintf(" | %s %s %s | %s %s",
+			optf("%.0f", h.WindSpeed), u.WindUnit(), optCompass(h.WindDirection), optf("%.0f", h.WindGusts), u.WindUnit())
+		if h.WindDirection != nil {
+			twa := trueWindAngle(pt.Course, *h.WindDirection)
+			row += fmt.Sprintf(" | %.0f° %s", twa, pointOfSail(twa))
+		} else {
+			row += " | " + notAvailable
+		}
+		if m := pt.Marine; m != nil && m.WaveHeight != nil {
+			row += fmt.Sprintf(" | %s / %s", u.formatHeight(*m.WaveHeight), optf("%.0f s", m.WavePeriod))
+		} else {
+			row += " | " + notAvailable
+		}
+		row += " | " + optCondition(h.WeatherCode)
+		b.WriteString(row + "\n")
+	}
+	return b.String()
+}
+
+func waypointLabel(w Waypoint) string {
+	if w.Name != "" {
+		return w.Name
+	}
+	return fmt.Sprintf("%.4f, %.4f", w.Lat, w.Lon)
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseWaypoints(t *testing.T) {
+	got, err := ParseWaypoints("Split=43.508,16.440; 43.38,16.29 ;Vis=43.062/16.183")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Waypoint{{"Split", 43.508, 16.440}, {"", 43.38, 16.29}, {"Vis", 43.062, 16.183}}
+	if len(got) != len(want) {
+		t.Fatalf("ParseWaypoints = %+v", got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("waypoint %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+
+	for _, s := range []string{"43.5,16.4", "43.5,16.4;Vis"} {
+		if _, err := ParseWaypoints(s); err == nil {
+			t.Errorf("ParseWaypoints(%q) accepted", s)
+		}
+	}
+}
+
+func TestParseGPX(t *testing.T) {
+	gpx := `<?xml version="1.0"?>
+<gpx version="1.1" xmlns="http://www.topografix.com/GPX/1/1">
+  <wpt lat="1" lon="1"><name>Ignored</name></wpt>
+  <rte>
+    <rtept lat="43.508" lon="16.440"><name>Split</name></rtept>
+    <rtept lat="43.062" lon="16.183"><name>Vis</name></rtept>
+  </rte>
+</gpx>`
+	got, err := ParseGPX([]byte(gpx))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].Name != "Split" || got[1].Lat != 43.062 {
+		t.Errorf("ParseGPX = %+v", got)
+	}
+
+	track := `<gpx><trk><trkseg><trkpt lat="1" lon="2"/><trkpt lat="1.1" lon="2"/></trkseg><trkseg><trkpt lat="1.2" lon="2"/></trkseg></trk></gpx>`
+	if got, err := ParseGPX([]byte(track)); err != nil || len(got) != 3 {
+		t.Errorf("ParseGPX track = %+v, %v", got, err)
+	}
+}
+
+func TestSamplePassage(t *testing.T) {
+	// Two legs due north of 25 and 5 nm at 5 kn.
+	waypoints := []Waypoint{{"A", 43, 16}, {"B", 43 + 25.0/60, 16}, {"C", 43.5, 16}}
+	depart := time.Date(2026, 3, 15, 8, 0, 0, 0, time.UTC)
+
+	p := SamplePassage(waypoints, 5, depart, 10)
+	var names []string
+	for _, pt := range p.Points {
+		names = append(names, pt.Name)
+	}
+	// The first leg is split into three parts, the short second leg is not split.
+	if got := strings.Join(names, ","); got != "A,,,B,C" {
+		t.Fatalf("points = %q, want A,,,B,C", got)
+	}
+	if b := p.Points[3]; b.Leg != 1 || math.Abs(b.DistanceNm-25) > 0.1 || b.ETA.Sub(depart).Round(time.Minute) != 5*time.Hour {
+		t.Errorf("waypoint B = leg %d, %.2f nm, ETA %s", b.Leg, b.DistanceNm, b.ETA)
+	}
+	if c := p.Points[4]; c.Leg != 2 || math.Abs(p.DistanceNm()-30) > 0.1 {
+		t.Errorf("waypoint C = leg %d, route %.2f nm", c.Leg, p.DistanceNm())
+	}
+}
+
+func TestForecastHour(t *testing.T) {
+	kolkata, err := time.LoadLocation("Asia/Kolkata") // UTC+5:30
+	if err != nil {
+		t.Skip(err)
+	}
+	tests := []struct {
+		eta  time.Time
+		want string
+	}{
+		{time.Date(2026, 3, 15, 2, 30, 0, 0, time.UTC), "2026-03-15T08:00"},
+		{time.Date(2026, 3, 15, 2, 50, 0, 0, time.UTC), "2026-03-15T08:00"},
+		{time.Date(2026, 3, 15, 3, 0, 0, 0, time.UTC), "2026-03-15T09:00"},
+		{time.Date(2026, 3, 15, 18, 20, 0, 0, time.UTC), "2026-03-16T00:00"},
+	}
+	for _, tt := range tests {
+		if got := forecastHour(tt.eta, kolkata); got != tt.want {
+			t.Errorf("forecastHour(%s) = %s, want %s", tt.eta.Format(time.RFC3339), got, tt.want)
+		}
+	}
+	if got := forecastHour(time.Date(2026, 3, 15, 8, 29, 0, 0, time.UTC), time.UTC); got != "2026-03-15T08:00" {
+		t.Errorf("forecastHour in UTC = %s", got)
+	}
+}
+
+func TestFormatPassage(t *testing.T) {
+	waypoints := []Waypoint{{"Split", 43.5, 16.4}, {"", 43.5 + 5.0/60, 16.4}}
+	depart := time.Date(2026, 3, 15, 8, 0, 0, 0, time.UTC)
+	p := SamplePassage(waypoints, 5, depart, 10)
+	p.Units = UnitsNautical
+	p.Points[0].Weather = &HourlyForecast{Time: "2026-03-15T08:00", WindSpeed: ptr(12.0), WindGusts: ptr(18.0), WindDirection: ptr(90.0), WeatherCode: ptr(1)}
+	p.Points[0].Marine = &HourlyMarine{Time: "2026-03-15T08:00", WaveHeight: ptr(0.8), WavePeriod: ptr(4.0)}
+
+	got := FormatPassage(p)
+	for _, want := range []string{
+		"=== PASSAGE (planned route at 5.0 kn) ===\n",
+		"Depart 2026-03-15T08:00, arrive 2026-03-15T09:00, 5.0 nm in 1 legs\n",
+		"Leg 1: Split → 43.5833, 16.4000, 5.0 nm, course 0° (N)\n",
+		"2026-03-15T08:00 | 1 | Split 43.5000, 16.4000 | 0.0 | 12 kn E | 18 kn | 90° beam reach | 0.8m / 4 s | Mainly clear\n",
+		"2026-03-15T09:00 | 1 | 43.5833, 16.4000 | 5.0 | beyond forecast\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("FormatPassage missing %q in:\n%s", want, got)
+		}
+	}
+}
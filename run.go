@@ -0,0 +1,95 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"sailingnomads-briefing/logseq"
+)
+
+const (
+	positionSearchDays = 30 // how far back to look for current_position::
+	runAttempts        = 3
+	runRetryDelay      = 30 * time.Second // times the attempt number
+)
+
+// runJournal implements the run subcommand: the whole daily pipeline against a
+// Logseq sail log. It finds the latest position in the journal, builds the context
+// from the recent pages, generates the briefing (retrying, then falling back to the
+// offline briefing) and writes it to today's page, replacing an earlier briefing.
+func runJournal(args []string) {
+	fs := flag.NewFlagSet("run", flag.ExitOnError)
+	saillog := fs.String("saillog", "", "Logseq graph directory with a journals/ subdirectory (required)")
+	contextDays := fs.Int("context-days", 10, "How many days of journal pages to include as context")
+	keepVersions := fs.Int("keep-versions", 3, "How many replaced briefings of the day to keep as collapsed blocks")
+	opts := registerBriefingFlags(fs)
+	opts.parse(fs, args)
+
+	if *saillog == "" {
+		fmt.Fprintln(os.Stderr, "Error: --saillog is required")
+		fmt.Fprintln(os.Stderr, "Usage: briefing run --saillog <dir> [--context-days <n>] [--config <config.env>] [briefing flags]")
+		os.Exit(1)
+	}
+
+	journal, err := logseq.Open(*saillog)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	today := time.Now()
+
+	// A GPS fix from the instruments is newer than any position typed into the journal.
+	var pos logseq.Position
+	opts.readOnboard()
+	if opts.onboard != nil && opts.onboard.Fix != nil {
+		pos = logseq.Position{Lat: opts.onboard.Fix.Lat, Lon: opts.onboard.Fix.Lon, Date: today}
+	} else {
+		fmt.Fprintln(os.Stderr, "Searching for current_position in recent journal entries...")
+		if pos, err = journal.LatestPosition(today, positionSearchDays); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			fmt.Fprintln(os.Stderr, "Please add a line like this to a recent journal entry:")
+			fmt.Fprintln(os.Stderr, "  - current_position:: 47.13826/8.60032")
+			os.Exit(1)
+		}
+		fmt.Fprintf(os.Stderr, "Found position: %.5f, %.5f (from %s)\n", pos.Lat, pos.Lon, pos.Date.Format("2006-01-02"))
+	}
+
+	journalContext, pages, err := journal.Context(today, *contextDays)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading journal: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Fprintf(os.Stderr, "Included %d journal files\n", pages)
+
+	var briefing string
+	var in BriefingInput
+	if !opts.offline {
+		for attempt := 1; attempt <= runAttempts; attempt++ {
+			if attempt > 1 {
+				wait := time.Duration(attempt-1) * runRetryDelay
+				fmt.Fprintf(os.Stderr, "Retrying in %s (attempt %d/%d)...\n", wait, attempt, runAttempts)
+				time.Sleep(wait)
+			}
+			if briefing, in, err = opts.briefing(pos.Lat, pos.Lon, journalContext); err == nil {
+				break
+			}
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		}
+		if briefing == "" {
+			fmt.Fprintln(os.Stderr, "Online generation failed, falling back to offline briefing...")
+		}
+	}
+	if briefing == "" {
+		briefing, in = opts.offlineBriefing(pos.Lat, pos.Lon, journalContext)
+	}
+
+	if err := journal.WriteBriefing(today, briefing, *keepVersions); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing journal: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Fprintf(os.Stderr, "Briefing written to %s\n", journal.PagePath(today))
+
+	opts.deliver(briefing, in)
+}
@@ -0,0 +1,323 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"sailingnomads-briefing/logseq"
+)
+
+// saildocsAddress is where Saildocs takes GRIB requests by mail.
+const saildocsAddress = "query@saildocs.com"
+
+// SaildocsRequest is a GRIB request to the Saildocs mail service.
+type SaildocsRequest struct {
+	Model                    string  // e.g. GFS, ECMWF, ICON
+	South, North, West, East int     // area in whole degrees, north and east positive
+	Resolution               float64 // grid spacing in degrees
+	Hours                    int     // forecast length
+	Step                     int     // hours between time steps
+	Params                   []string
+}
+
+// saildocsFields is how many GRIB fields a Saildocs parameter returns.
+var saildocsFields = map[string]int{"WIND": 2, "WAVES": 3}
+
+// SaildocsArea is the box around the points with margin degrees on every side,
+// rounded outwards to whole degrees.
+func SaildocsArea(points []Waypoint, margin float64) (south, north, west, east int) {
+	minLat, maxLat, minLon, maxLon := 90.0, -90.0, 180.0, -180.0
+	for _, p := range points {
+		minLat, maxLat = min(minLat, p.Lat), max(maxLat, p.Lat)
+		minLon, maxLon = min(minLon, p.Lon), max(maxLon, p.Lon)
+	}
+	south = max(int(math.Floor(minLat-margin)), -90)
+	north = min(int(math.Ceil(maxLat+margin)), 90)
+	west = max(int(math.Floor(minLon-margin)), -180)
+	east = min(int(math.Ceil(maxLon+margin)), 180)
+	return south, north, west, east
+}
+
+// String renders the request line, e.g. "send GFS:40N,45N,12E,18E|0.5,0.5|0,6..72|WIND,PRMSL".
+func (r SaildocsRequest) String() string {
+	lat := func(v int) string {
+		if v < 0 {
+			return fmt.Sprintf("%dS", -v)
+		}
+		return fmt.Sprintf("%dN", v)
+	}
+	lon := func(v int) string {
+		if v < 0 {
+			return fmt.Sprintf("%dW", -v)
+		}
+		return fmt.Sprintf("%dE", v)
+	}
+	res := fmt.Sprintf("%g", r.Resolution)
+	times := fmt.Sprintf("0,%d..%d", r.Step, r.Hours)
+	if r.Step >= r.Hours {
+		times = fmt.Sprintf("0,%d", r.Hours)
+	}
+	return fmt.Sprintf("send %s:%s,%s,%s,%s|%s,%s|%s|%s", r.Model,
+		lat(r.South), lat(r.North), lon(r.West), lon(r.East), res, res, times, strings.Join(r.Params, ","))
+}
+
+// EstimatedBytes is a rough size of the reply, at 16 bits per grid point and value.
+// Mail encoding adds another third.
+func (r SaildocsRequest) EstimatedBytes() int {
+	ni := int(float64(r.East-r.West)/r.Resolution) + 1
+	nj := int(float64(r.North-r.South)/r.Resolution) + 1
+	steps := r.Hours/max(r.Step, 1) + 1
+	fields := 0
+	for _, p := range r.Params {
+		fields += max(saildocsFields[strings.ToUpper(p)], 1)
+	}
+	return fields * steps * (ni*nj*2 + 100)
+}
+
+// GRIBAttachment is a GRIB file attached to a mail.
+type GRIBAttachment struct {
+	Name string
+	Data []byte
+}
+
+// ExtractGRIBAttachments finds the GRIB attachments in a single mail (.eml) or in
+// every mail of an mbox file, oldest first.
+func ExtractGRIBAttachments(r io.Reader) ([]GRIBAttachment, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	var attachments []GRIBAttachment
+	for _, raw := range splitMbox(data) {
+		msg, err := mail.ReadMessage(bytes.NewReader(raw))
+		if err != nil {
+			return nil, fmt.Errorf("reading mail: %w", err)
+		}
+		found, err := gribParts(msg.Header, msg.Body)
+		if err != nil {
+			return nil, fmt.Errorf("reading mail %q: %w", msg.Header.Get("Subject"), err)
+		}
+		attachments = append(attachments, found...)
+	}
+	if len(attachments) == 0 {
+		return nil, errors.New("no GRIB attachment found")
+	}
+	return attachments, nil
+}
+
+// splitMbox splits an mbox file at its "From " lines. A single mail is returned as is.
+func splitMbox(data []byte) [][]byte {
+	if !bytes.HasPrefix(data, []byte("From ")) {
+		return [][]byte{data}
+	}
+	var msgs [][]byte
+	var cur []byte
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	sc.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for sc.Scan() {
+		line := sc.Bytes()
+		if bytes.HasPrefix(line, []byte("From ")) {
+			if cur != nil {
+				msgs = append(msgs, cur)
+			}
+			cur = []byte{}
+			continue
+		}
+		cur = append(append(cur, line...), '\n')
+	}
+	if cur != nil {
+		msgs = append(msgs, cur)
+	}
+	return msgs
+}
+
+// partHeader is the header of a mail or a MIME part.
+type partHeader interface {
+	Get(key string) string
+}
+
+// gribParts walks a MIME entity and returns the parts that are GRIB files.
+func gribParts(h partHeader, body io.Reader) ([]GRIBAttachment, error) {
+	mediaType, params, err := mime.ParseMediaType(h.Get("Content-Type"))
+	if err != nil {
+		mediaType = "text/plain"
+	}
+	if strings.HasPrefix(mediaType, "multipart/") {
+		mr := multipart.NewReader(body, params["boundary"])
+		var found []GRIBAttachment
+		for {
+			part, err := mr.NextRawPart()
+			if err == io.EOF {
+				return found, nil
+			}
+			if err != nil {
+				return nil, err
+			}
+			sub, err := gribParts(part.Header, part)
+			if err != nil {
+				return nil, err
+			}
+			found = append(found, sub...)
+		}
+	}
+
+	switch strings.ToLower(h.Get("Content-Transfer-Encoding")) {
+	case "base64":
+		body = base64.NewDecoder(base64.StdEncoding, body)
+	case "quoted-printable":
+		body = quotedprintable.NewReader(body)
+	}
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.HasPrefix(data, []byte("GRIB")) {
+		return nil, nil
+	}
+	name := params["name"]
+	if _, disp, err := mime.ParseMediaType(h.Get("Content-Disposition")); err == nil && disp["filename"] != "" {
+		name = disp["filename"]
+	}
+	if name = filepath.Base(name); name == "." || name == "/" {
+		name = ""
+	}
+	return []GRIBAttachment{{Name: name, Data: data}}, nil
+}
+
+// SaveGRIBAttachment writes an attachment to the grib directory of the cache and
+// returns its path. Attachments without a name are named after the time received.
+func SaveGRIBAttachment(cacheDir string, a GRIBAttachment, now time.Time) (string, error) {
+	dir := filepath.Join(cacheDir, "grib")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	name := a.Name
+	if name == "" {
+		name = now.UTC().Format("20060102-1504") + ".grb"
+	}
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, a.Data, 0o644); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// runGRIB implements the grib subcommand with its request and extract commands.
+func runGRIB(args []string) {
+	if len(args) > 0 {
+		switch args[0] {
+		case "request":
+			runGRIBRequest(args[1:])
+			return
+		case "extract":
+			runGRIBExtract(args[1:])
+			return
+		}
+	}
+	fmt.Fprintln(os.Stderr, "Usage: briefing grib request --lat <latitude> --lon <longitude> [--route <route>] [--hours 72] [--resolution 0.5]")
+	fmt.Fprintln(os.Stderr, "       briefing grib extract [--mail <reply.eml|mbox>]")
+	os.Exit(1)
+}
+
+// runGRIBRequest prints the Saildocs request for the area around the position, the
+// route or both.
+func runGRIBRequest(args []string) {
+	fs := flag.NewFlagSet("grib request", flag.ExitOnError)
+	lat := fs.Float64("lat", 0, "Latitude of the current position")
+	lon := fs.Float64("lon", 0, "Longitude of the current position")
+	saillog := fs.String("saillog", "", "Logseq graph directory to read the current position from instead of --lat/--lon")
+	route := fs.String("route", "", "Planned route as a GPX file or inline waypoints \"lat,lon;name=lat,lon;...\"")
+	margin := fs.Float64("margin", 3, "Degrees to add around the position and route")
+	req := SaildocsRequest{Params: []string{"WIND", "GUST", "PRMSL", "RAIN", "AIRTMP", "WAVES"}}
+	fs.StringVar(&req.Model, "grib-model", "GFS", "Saildocs model, e.g. GFS, ECMWF or ICON")
+	fs.Float64Var(&req.Resolution, "resolution", 0.5, "Grid spacing in degrees")
+	fs.IntVar(&req.Hours, "hours", 72, "Forecast length in hours")
+	fs.IntVar(&req.Step, "step", 6, "Hours between time steps")
+	fs.Var((*stringList)(&req.Params), "params", "Saildocs parameters (default WIND,GUST,PRMSL,RAIN,AIRTMP,WAVES)")
+	common := registerCommonFlags(fs)
+	common.parse(fs, args)
+
+	if *saillog != "" {
+		journal, err := logseq.Open(*saillog)
+		if err == nil {
+			var pos logseq.Position
+			if pos, err = journal.LatestPosition(time.Now(), positionSearchDays); err == nil {
+				*lat, *lon = pos.Lat, pos.Lon
+			}
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+	}
+	var points []Waypoint
+	if *lat != 0 || *lon != 0 {
+		points = append(points, Waypoint{Lat: *lat, Lon: *lon})
+	}
+	if *route != "" {
+		waypoints, err := loadRoute(*route)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading route: %v\n", err)
+			os.Exit(1)
+		}
+		points = append(points, waypoints...)
+	}
+	if len(points) == 0 || req.Resolution <= 0 || req.Hours <= 0 || req.Step <= 0 {
+		fmt.Fprintln(os.Stderr, "Error: --lat and --lon, --saillog or --route are required, with a positive --resolution, --hours and --step")
+		fmt.Fprintln(os.Stderr, "Usage: briefing grib request --lat <latitude> --lon <longitude> [--route <route>] [--hours 72] [--resolution 0.5]")
+		os.Exit(1)
+	}
+
+	req.South, req.North, req.West, req.East = SaildocsArea(points, *margin)
+	fmt.Fprintf(os.Stderr, "Send this line as the body of a mail to %s (roughly %d kB in the reply):\n", saildocsAddress, (req.EstimatedBytes()+1023)/1024)
+	fmt.Println(req)
+}
+
+// runGRIBExtract saves the GRIB attachments of a Saildocs reply to the cache and
+// prints their paths, for --grib.
+func runGRIBExtract(args []string) {
+	fs := flag.NewFlagSet("grib extract", flag.ExitOnError)
+	mailPath := fs.String("mail", "", "Mail (.eml) or mbox file with the reply (default: stdin)")
+	common := registerCommonFlags(fs)
+	common.parse(fs, args)
+
+	var in io.Reader = os.Stdin
+	if *mailPath != "" {
+		f, err := os.Open(*mailPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		in = f
+	}
+	attachments, err := ExtractGRIBAttachments(in)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	for _, a := range attachments {
+		path, err := SaveGRIBAttachment(common.cacheDir, a, time.Now())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error saving GRIB file: %v\n", err)
+			os.Exit(1)
+		}
+		if len(a.Data) > 7 && a.Data[7] == 1 {
+			fmt.Fprintf(os.Stderr, "Warning: %s is GRIB1; convert it with cdo -f grb2 copy before using it with --grib\n", path)
+		}
+		fmt.Println(path)
+	}
+}
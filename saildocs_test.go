@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSaildocsRequest(t *testing.T) {
+	route := []Waypoint{{Lat: 43.508, Lon: 16.44}, {Name: "Vis", Lat: 43.062, Lon: 16.183}}
+	req := SaildocsRequest{Model: "GFS", Resolution: 0.5, Hours: 72, Step: 6, Params: []string{"WIND", "PRMSL"}}
+	req.South, req.North, req.West, req.East = SaildocsArea(route, 1)
+	if got, want := req.String(), "send GFS:42N,45N,15E,18E|0.5,0.5|0,6..72|WIND,PRMSL"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+	// 7×7 points, 13 time steps, three fields.
+	if got := req.EstimatedBytes(); got != 3*13*(49*2+100) {
+		t.Errorf("EstimatedBytes() = %d", got)
+	}
+
+	// South and west of zero, clamped at the pole.
+	south, north, west, east := SaildocsArea([]Waypoint{{Lat: -88.5, Lon: -0.5}}, 3)
+	req = SaildocsRequest{Model: "ECMWF", South: south, North: north, West: west, East: east, Resolution: 1, Hours: 24, Step: 24, Params: []string{"WIND"}}
+	if got, want := req.String(), "send ECMWF:90S,85S,4W,3E|1,1|0,24|WIND"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
+
+// saildocsReply is a Saildocs reply with a text part and a base64 GRIB attachment.
+func saildocsReply(name string, grib []byte) string {
+	return "From: query-reply@saildocs.com\r\n" +
+		"Subject: Saildocs GRIB\r\n" +
+		"MIME-Version: 1.0\r\n" +
+		"Content-Type: multipart/mixed; boundary=\"b1\"\r\n" +
+		"\r\n" +
+		"--b1\r\n" +
+		"Content-Type: text/plain\r\n" +
+		"\r\n" +
+		"Grib extracted from GFS\r\n" +
+		"--b1\r\n" +
+		"Content-Type: application/octet-stream; name=\"" + name + "\"\r\n" +
+		"Content-Transfer-Encoding: base64\r\n" +
+		"Content-Disposition: attachment; filename=\"" + name + "\"\r\n" +
+		"\r\n" +
+		base64.StdEncoding.EncodeToString(grib) + "\r\n" +
+		"--b1--\r\n"
+}
+
+func TestExtractGRIBAttachments(t *testing.T) {
+	grib := testGRIB()
+	grib = grib[bytes.Index(grib, []byte("GRIB")):]
+
+	got, err := ExtractGRIBAttachments(strings.NewReader(saildocsReply("GFS20260315.grb2", grib)))
+	if err != nil {
+		t.Fatalf("ExtractGRIBAttachments: %v", err)
+	}
+	if len(got) != 1 || got[0].Name != "GFS20260315.grb2" || !bytes.Equal(got[0].Data, grib) {
+		t.Fatalf("ExtractGRIBAttachments = %d attachments, want the GRIB file", len(got))
+	}
+
+	dir := t.TempDir()
+	path, err := SaveGRIBAttachment(dir, got[0], time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if path != filepath.Join(dir, "grib", "GFS20260315.grb2") {
+		t.Errorf("saved to %s", path)
+	}
+	if _, err := LoadGRIBWeather(path, 43.5, 15.5, time.Date(2026, 3, 15, 3, 0, 0, 0, time.UTC), WeatherOptions{}); err != nil {
+		t.Errorf("LoadGRIBWeather on the saved file: %v", err)
+	}
+
+	// An mbox with an earlier reply; the names leave out any directory.
+	mbox := "From query-reply@saildocs.com Sun Mar 15 06:00:00 2026\n" + saildocsReply("../first.grb", grib) +
+		"\nFrom query-reply@saildocs.com Sun Mar 15 12:00:00 2026\n" + saildocsReply("second.grb", grib)
+	got, err = ExtractGRIBAttachments(strings.NewReader(mbox))
+	if err != nil {
+		t.Fatalf("ExtractGRIBAttachments(mbox): %v", err)
+	}
+	if len(got) != 2 || got[0].Name != "first.grb" || got[1].Name != "second.grb" {
+		t.Errorf("mbox attachments = %d", len(got))
+	}
+
+	if _, err := ExtractGRIBAttachments(strings.NewReader("Subject: hello\r\n\r\nno attachment\r\n")); err == nil {
+		t.Error("mail without attachment: no error")
+	}
+}
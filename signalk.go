@@ -0,0 +1,315 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// SignalKClient talks to the REST and WebSocket API of a Signal K server.
+type SignalKClient struct {
+	BaseURL string // e.g. http://192.168.1.1:3000
+	Token   string // optional access token of a device or user
+	client  *http.Client
+}
+
+// NewSignalKClient returns a client for the server at baseURL.
+func NewSignalKClient(baseURL, token string) *SignalKClient {
+	return &SignalKClient{
+		BaseURL: strings.TrimRight(baseURL, "/"),
+		Token:   token,
+		client:  &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// signalKValue is a leaf of the Signal K data model. Values are in SI units.
+type signalKValue[T any] struct {
+	Value     *T     `json:"value"`
+	Timestamp string `json:"timestamp"`
+}
+
+// signalKSelf is the part of vessels/self the briefing uses.
+type signalKSelf struct {
+	Name       string `json:"name"`
+	Navigation struct {
+		Position signalKValue[struct {
+			Latitude  float64 `json:"latitude"`
+			Longitude float64 `json:"longitude"`
+		}] `json:"position"`
+		SpeedOverGround      signalKValue[float64] `json:"speedOverGround"`      // m/s
+		CourseOverGroundTrue signalKValue[float64] `json:"courseOverGroundTrue"` // rad
+	} `json:"navigation"`
+	Environment struct {
+		Wind struct {
+			SpeedTrue      signalKValue[float64] `json:"speedTrue"`      // m/s
+			AngleTrueWater signalKValue[float64] `json:"angleTrueWater"` // rad, relative to the bow
+			DirectionTrue  signalKValue[float64] `json:"directionTrue"`  // rad
+			SpeedApparent  signalKValue[float64] `json:"speedApparent"`  // m/s
+			AngleApparent  signalKValue[float64] `json:"angleApparent"`  // rad
+		} `json:"wind"`
+		Outside struct {
+			Pressure    signalKValue[float64] `json:"pressure"`    // Pa
+			Temperature signalKValue[float64] `json:"temperature"` // K
+		} `json:"outside"`
+		Water struct {
+			Temperature signalKValue[float64] `json:"temperature"` // K
+		} `json:"water"`
+		Depth struct {
+			BelowSurface    signalKValue[float64] `json:"belowSurface"`
+			BelowTransducer signalKValue[float64] `json:"belowTransducer"`
+		} `json:"depth"`
+	} `json:"environment"`
+}
+
+// SignalKVessel is the own vessel as reported by the server.
+type SignalKVessel struct {
+	Name         string
+	Observations OnboardObservations
+}
+
+// Self reads the position, the environment data and the name of the own vessel.
+func (c *SignalKClient) Self(ctx context.Context) (SignalKVessel, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.BaseURL+"/signalk/v1/api/vessels/self", nil)
+	if err != nil {
+		return SignalKVessel{}, err
+	}
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return SignalKVessel{}, fmt.Errorf("requesting Signal K data: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return SignalKVessel{}, fmt.Errorf("the Signal K server returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	var self signalKSelf
+	if err := json.NewDecoder(resp.Body).Decode(&self); err != nil {
+		return SignalKVessel{}, fmt.Errorf("decoding Signal K response: %w", err)
+	}
+	return self.vessel(time.Now()), nil
+}
+
+// vessel converts the SI values of the data model to the units of the NMEA reader.
+func (s signalKSelf) vessel(now time.Time) SignalKVessel {
+	const msToKnots = 3.6 / kmhPerKnot
+	convert := func(v *float64, factor, offset float64) *float64 {
+		if v == nil {
+			return nil
+		}
+		r := *v*factor + offset
+		return &r
+	}
+	degrees := func(v *float64) *float64 {
+		if v == nil {
+			return nil
+		}
+		d := math.Mod(*v*180/math.Pi+360, 360)
+		return &d
+	}
+	angle := func(v *float64) *float64 { // -180..180 off the bow, to the 0..180 of MWV
+		if v == nil {
+			return nil
+		}
+		d := math.Abs(*v * 180 / math.Pi)
+		return &d
+	}
+
+	nav, env := s.Navigation, s.Environment
+	o := OnboardObservations{
+		Received:          now,
+		TrueWindSpeed:     convert(env.Wind.SpeedTrue.Value, msToKnots, 0),
+		TrueWindAngle:     angle(env.Wind.AngleTrueWater.Value),
+		TrueWindDirection: degrees(env.Wind.DirectionTrue.Value),
+		ApparentWindSpeed: convert(env.Wind.SpeedApparent.Value, msToKnots, 0),
+		ApparentWindAngle: angle(env.Wind.AngleApparent.Value),
+		Pressure:          convert(env.Outside.Pressure.Value, 0.01, 0),
+		AirTemperature:    convert(env.Outside.Temperature.Value, 1, -273.15),
+		WaterTemperature:  convert(env.Water.Temperature.Value, 1, -273.15),
+		Depth:             keep(env.Depth.BelowTransducer.Value, env.Depth.BelowSurface.Value),
+	}
+	if p := nav.Position.Value; p != nil {
+		o.Fix = &Fix{
+			Lat: p.Latitude,
+			Lon: p.Longitude,
+			SOG: convert(nav.SpeedOverGround.Value, msToKnots, 0),
+			COG: degrees(nav.CourseOverGroundTrue.Value),
+		}
+		if t, err := time.Parse(time.RFC3339, nav.Position.Timestamp); err == nil {
+			o.Fix.Time = t
+		}
+	}
+	return SignalKVessel{Name: s.Name, Observations: o}
+}
+
+// signalKNotification is the value of a notifications.* path.
+type signalKNotification struct {
+	State   string   `json:"state"` // normal, warn or alarm
+	Method  []string `json:"method"`
+	Message string   `json:"message"`
+}
+
+// hazardNotifications turns the hazards into one notification per parameter under
+// notifications.weather. Parameters without a hazard are reset to normal, so that a
+// warning disappears from the plotter once the forecast has improved.
+func hazardNotifications(hazards []Hazard, u UnitSystem) map[string]signalKNotification {
+	notes := make(map[string]signalKNotification, len(hazardParameters))
+	for _, p := range hazardParameters {
+		notes["notifications.weather."+p] = signalKNotification{State: "normal", Method: []string{}}
+	}
+	for _, h := range hazards {
+		path := "notifications.weather." + h.Parameter
+		n := notes[path]
+		state, method := "warn", []string{"visual"}
+		if h.Severity == SeverityDanger {
+			state, method = "alarm", []string{"visual", "sound"}
+		}
+		if n.State != "alarm" {
+			n.State, n.Method = state, method
+		}
+		if n.Message != "" {
+			n.Message += "; "
+		}
+		n.Message += h.Window() + ": " + h.describe(u)
+		notes[path] = n
+	}
+	return notes
+}
+
+// PublishHazards sends the hazard warnings to the server as a delta over the
+// WebSocket stream, where chartplotters and apps show them as notifications.
+func (c *SignalKClient) PublishHazards(ctx context.Context, hazards []Hazard, u UnitSystem) error {
+	type pathValue struct {
+		Path  string              `json:"path"`
+		Value signalKNotification `json:"value"`
+	}
+	notes := hazardNotifications(hazards, u)
+	var values []pathValue
+	for _, p := range hazardParameters {
+		path := "notifications.weather." + p
+		values = append(values, pathValue{path, notes[path]})
+	}
+	delta, err := json.Marshal(map[string]any{
+		"context": "vessels.self",
+		"updates": []any{map[string]any{
+			"source":    map[string]string{"label": "sailingnomads-briefing"},
+			"timestamp": time.Now().UTC().Format(time.RFC3339),
+			"values":    values,
+		}},
+	})
+	if err != nil {
+		return err
+	}
+
+	conn, err := c.dialStream(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if err := writeWebSocketFrame(conn, wsOpText, delta); err != nil {
+		return fmt.Errorf("sending Signal K delta: %w", err)
+	}
+	return writeWebSocketFrame(conn, wsOpClose, binary.BigEndian.AppendUint16(nil, 1000))
+}
+
+// dialStream opens the Signal K WebSocket stream without subscriptions.
+func (c *SignalKClient) dialStream(ctx context.Context) (net.Conn, error) {
+	u, err := url.Parse(c.BaseURL + "/signalk/v1/stream?subscribe=none")
+	if err != nil {
+		return nil, fmt.Errorf("invalid Signal K url: %w", err)
+	}
+	host := u.Host
+	if u.Port() == "" && u.Scheme == "https" {
+		host = net.JoinHostPort(u.Hostname(), "443")
+	} else if u.Port() == "" {
+		host = net.JoinHostPort(u.Hostname(), "80")
+	}
+	d := &net.Dialer{Timeout: 10 * time.Second}
+	var conn net.Conn
+	if u.Scheme == "https" {
+		conn, err = (&tls.Dialer{NetDialer: d}).DialContext(ctx, "tcp", host)
+	} else {
+		conn, err = d.DialContext(ctx, "tcp", host)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("connecting to Signal K: %w", err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	nonce := make([]byte, 16)
+	rand.Read(nonce)
+	key := base64.StdEncoding.EncodeToString(nonce)
+	req := &http.Request{Method: http.MethodGet, URL: u, Host: u.Host, Header: http.Header{
+		"Upgrade":               {"websocket"},
+		"Connection":            {"Upgrade"},
+		"Sec-WebSocket-Key":     {key},
+		"Sec-WebSocket-Version": {"13"},
+	}}
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("opening Signal K stream: %w", err)
+	}
+	resp, err := http.ReadResponse(bufio.NewReader(conn), req)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("opening Signal K stream: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusSwitchingProtocols || resp.Header.Get("Sec-WebSocket-Accept") != webSocketAccept(key) {
+		conn.Close()
+		return nil, fmt.Errorf("the Signal K server refused the stream: %s", resp.Status)
+	}
+	return conn, nil
+}
+
+const (
+	wsOpText  = 0x1
+	wsOpClose = 0x8
+)
+
+// webSocketAccept is the Sec-WebSocket-Accept value a server must answer for key.
+func webSocketAccept(key string) string {
+	h := sha1.Sum([]byte(key + "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"))
+	return base64.StdEncoding.EncodeToString(h[:])
+}
+
+// writeWebSocketFrame writes a single masked frame, as clients must.
+func writeWebSocketFrame(w io.Writer, opcode byte, payload []byte) error {
+	frame := []byte{0x80 | opcode}
+	switch n := len(payload); {
+	case n < 126:
+		frame = append(frame, 0x80|byte(n))
+	case n <= math.MaxUint16:
+		frame = binary.BigEndian.AppendUint16(append(frame, 0x80|126), uint16(n))
+	default:
+		frame = binary.BigEndian.AppendUint64(append(frame, 0x80|127), uint64(n))
+	}
+	mask := make([]byte, 4)
+	rand.Read(mask)
+	frame = append(frame, mask...)
+	for i, b := range payload {
+		frame = append(frame, b^mask[i%4])
+	}
+	_, err := w.Write(frame)
+	return err
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const signalKSelfJSON = `{
+  "name": "Nomad",
+  "navigation": {
+    "position": {"value": {"latitude": 43.508, "longitude": 16.44}, "timestamp": "2026-03-15T08:18:36Z"},
+    "speedOverGround": {"value": 2.572},
+    "courseOverGroundTrue": {"value": 3.7577}
+  },
+  "environment": {
+    "wind": {
+      "speedTrue": {"value": 5.144},
+      "angleTrueWater": {"value": -1.0734},
+      "directionTrue": {"value": 5.4454}
+    },
+    "outside": {"pressure": {"value": 101620}, "temperature": {"value": 291.55}},
+    "water": {"temperature": {"value": 290.05}},
+    "depth": {"belowTransducer": {"value": 7.3}}
+  }
+}`
+
+// signalKMock serves vessels/self and records the deltas sent over the stream.
+func signalKMock(t *testing.T) (*httptest.Server, chan []byte) {
+	t.Helper()
+	deltas := make(chan []byte, 1)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/signalk/v1/api/vessels/self", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, signalKSelfJSON)
+	})
+	mux.HandleFunc("/signalk/v1/stream", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Upgrade") != "websocket" || r.URL.Query().Get("subscribe") != "none" {
+			http.Error(w, "expected a websocket upgrade without subscriptions", http.StatusBadRequest)
+			return
+		}
+		conn, rw, err := http.NewResponseController(w).Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+		rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n" +
+			"Sec-WebSocket-Accept: " + webSocketAccept(r.Header.Get("Sec-WebSocket-Key")) + "\r\n\r\n")
+		rw.Flush()
+		for {
+			op, payload, err := readWebSocketFrame(rw.Reader)
+			if err != nil || op == wsOpClose {
+				return
+			}
+			deltas <- payload
+		}
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv, deltas
+}
+
+// readWebSocketFrame reads a single frame and unmasks it.
+func readWebSocketFrame(r *bufio.Reader) (byte, []byte, error) {
+	head := make([]byte, 2)
+	if _, err := io.ReadFull(r, head); err != nil {
+		return 0, nil, err
+	}
+	n := uint64(head[1] & 0x7f)
+	switch n {
+	case 126:
+		ext := make([]byte, 2)
+		if _, err := io.ReadFull(r, ext); err != nil {
+			return 0, nil, err
+		}
+		n = uint64(binary.BigEndian.Uint16(ext))
+	case 127:
+		return 0, nil, errors.New("frame too large for the test")
+	}
+	if head[1]&0x80 == 0 {
+		return 0, nil, errors.New("client frame not masked")
+	}
+	mask := make([]byte, 4)
+	if _, err := io.ReadFull(r, mask); err != nil {
+		return 0, nil, err
+	}
+	payload := make([]byte, n)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return 0, nil, err
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return head[0] & 0x0f, payload, nil
+}
+
+func TestSignalKSelf(t *testing.T) {
+	srv, _ := signalKMock(t)
+	vessel, err := NewSignalKClient(srv.URL+"/", "secret").Self(context.Background())
+	if err != nil {
+		t.Fatalf("Self: %v", err)
+	}
+	if vessel.Name != "Nomad" {
+		t.Errorf("Name = %q, want Nomad", vessel.Name)
+	}
+	o := vessel.Observations
+	if o.Fix == nil || o.Fix.Lat != 43.508 || o.Fix.Lon != 16.44 || !o.Fix.Time.Equal(time.Date(2026, 3, 15, 8, 18, 36, 0, time.UTC)) {
+		t.Fatalf("Fix = %+v", o.Fix)
+	}
+
+	checks := []struct {
+		name string
+		got  *float64
+		want float64
+	}{
+		{"SOG", o.Fix.SOG, 5.0},
+		{"COG", o.Fix.COG, 215.3},
+		{"TrueWindSpeed", o.TrueWindSpeed, 10.0},
+		{"TrueWindAngle", o.TrueWindAngle, 61.5},
+		{"TrueWindDirection", o.TrueWindDirection, 312.0},
+		{"Pressure", o.Pressure, 1016.2},
+		{"AirTemperature", o.AirTemperature, 18.4},
+		{"WaterTemperature", o.WaterTemperature, 16.9},
+		{"Depth", o.Depth, 7.3},
+	}
+	for _, c := range checks {
+		if c.got == nil {
+			t.Errorf("%s not read", c.name)
+		} else if math.Abs(*c.got-c.want) > 0.05 {
+			t.Errorf("%s = %.3f, want %.1f", c.name, *c.got, c.want)
+		}
+	}
+	if o.ApparentWindSpeed != nil {
+		t.Errorf("ApparentWindSpeed = %v, want nil for a path the server does not have", *o.ApparentWindSpeed)
+	}
+
+	if _, err := NewSignalKClient(srv.URL, "wrong").Self(context.Background()); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("Self with a wrong token: err = %v", err)
+	}
+}
+
+func TestSignalKPublishHazards(t *testing.T) {
+	srv, deltas := signalKMock(t)
+	hazards := []Hazard{
+		{Start: "2026-03-15T13:00", End: "2026-03-15T16:00", Parameter: HazardGusts, Value: 55, Unit: "km/h", Severity: SeverityWarning},
+		{Start: "2026-03-16T02:00", End: "2026-03-16T04:00", Parameter: HazardGusts, Value: 75, Unit: "km/h", Severity: SeverityDanger},
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := NewSignalKClient(srv.URL, "secret").PublishHazards(ctx, hazards, UnitsMetric); err != nil {
+		t.Fatalf("PublishHazards: %v", err)
+	}
+
+	var delta struct {
+		Context string `json:"context"`
+		Updates []struct {
+			Values []struct {
+				Path  string              `json:"path"`
+				Value signalKNotification `json:"value"`
+			} `json:"values"`
+		} `json:"updates"`
+	}
+	select {
+	case payload := <-deltas:
+		if err := json.Unmarshal(payload, &delta); err != nil {
+			t.Fatalf("delta is not JSON: %v\n%s", err, payload)
+		}
+	case <-ctx.Done():
+		t.Fatal("no delta received")
+	}
+	if delta.Context != "vessels.self" || len(delta.Updates) != 1 {
+		t.Fatalf("delta = %+v", delta)
+	}
+
+	notes := make(map[string]signalKNotification)
+	for _, v := range delta.Updates[0].Values {
+		notes[v.Path] = v.Value
+	}
+	if len(notes) != len(hazardParameters) {
+		t.Errorf("delta has %d paths, want one per hazard parameter (%d)", len(notes), len(hazardParameters))
+	}
+	gusts := notes["notifications.weather.gusts"]
+	if gusts.State != "alarm" || len(gusts.Method) != 2 {
+		t.Errorf("gusts notification = %+v, want an alarm with sound", gusts)
+	}
+	if !strings.Contains(gusts.Message, "2026-03-15T13:00–16:00: gusts") || !strings.Contains(gusts.Message, "; 2026-03-16T02:00–04:00: gusts") {
+		t.Errorf("gusts message = %q", gusts.Message)
+	}
+	if wind := notes["notifications.weather.wind"]; wind.State != "normal" {
+		t.Errorf("wind notification = %+v, want normal", wind)
+	}
+}
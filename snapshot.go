@@ -0,0 +1,66 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Snapshot is the last successfully fetched location and weather. It is written
+// on every online run so that an --offline run can still produce a briefing.
+type Snapshot struct {
+	FetchedAt time.Time
+	Location  Location
+	Weather   WeatherData
+}
+
+const snapshotFile = "last-snapshot.json"
+
+// defaultCacheDir returns the per-user cache directory for the briefing, or a
+// directory next to the working directory if the OS does not provide one.
+func defaultCacheDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ".briefing-cache"
+	}
+	return filepath.Join(dir, "sailingnomads-briefing")
+}
+
+func saveSnapshot(dir string, s Snapshot) error {
+	return saveSnapshotAs(dir, snapshotFile, s)
+}
+
+func loadSnapshot(dir string) (Snapshot, error) {
+	return loadSnapshotFrom(dir, snapshotFile)
+}
+
+func saveSnapshotAs(dir, name string, s Snapshot) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("creating cache directory: %w", err)
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding snapshot: %w", err)
+	}
+
+	// Write to a temp file first so an interrupted run never leaves a truncated snapshot.
+	tmp := filepath.Join(dir, name+".tmp")
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("writing snapshot: %w", err)
+	}
+	return os.Rename(tmp, filepath.Join(dir, name))
+}
+
+func loadSnapshotFrom(dir, name string) (Snapshot, error) {
+	data, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		return Snapshot{}, fmt.Errorf("reading snapshot: %w", err)
+	}
+	var s Snapshot
+	if err := json.Unmarshal(data, &s); err != nil {
+		return Snapshot{}, fmt.Errorf("decoding snapshot: %w", err)
+	}
+	return s, nil
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// JSONSchema constrains the model's answer to JSON matching Schema.
+type JSONSchema struct {
+	Name   string
+	Schema map[string]any
+}
+
+// StructuredBriefing is the briefing as structured data, rendered by Go templates
+// instead of trusting the model with the Logseq block format.
+type StructuredBriefing struct {
+	Header   BriefingHeader    `json:"header"`
+	Warnings []string          `json:"warnings"`
+	Sections []BriefingSection `json:"sections"`
+	Events   BriefingEvents    `json:"events"`
+	Sights   BriefingSights    `json:"sights"`
+}
+
+// BriefingHeader is the position and place of the briefing.
+type BriefingHeader struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	Location  string  `json:"location"` // "place, country"
+}
+
+// BriefingSection is a titled list of short points.
+type BriefingSection struct {
+	Title string   `json:"title"`
+	Items []string `json:"items"`
+}
+
+// BriefingEvents lists events and activities nearby.
+type BriefingEvents struct {
+	Title string          `json:"title"`
+	Items []BriefingEvent `json:"items"`
+}
+
+// BriefingEvent is an event with its date, place and link.
+type BriefingEvent struct {
+	Title string `json:"title"`
+	Date  string `json:"date"` // YYYY-MM-DD, or YYYY-MM-DD/YYYY-MM-DD for several days
+	Place string `json:"place"`
+	Link  string `json:"link"` // empty if unknown
+}
+
+// BriefingSights lists sights and excursions.
+type BriefingSights struct {
+	Title string          `json:"title"`
+	Items []BriefingSight `json:"items"`
+}
+
+// BriefingSight is a sight or excursion.
+type BriefingSight struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Link        string `json:"link"` // empty if unknown
+}
+
+// structuredInstructions is appended to prompt.md in structured mode.
+const structuredInstructions = `
+
+## Strukturierte Ausgabe
+
+Die Vorgaben zum Logseq-Format oben gelten hier nicht: Antworte ausschliesslich mit einem JSON-Objekt nach dem vorgegebenen Schema, ohne Text oder Markdown davor oder danach. Das Programm formatiert das Briefing selbst.
+- header: Koordinaten und "Ort, Land"
+- warnings: die Warnungen vor gefährlichen Bedingungen, je ein kurzer Satz, leer wenn es keine gibt
+- sections: die Sektionen Standort, Wetter und Seegang, Nachrichten und aktuelle Themen, jeweils mit Titel und Stichpunkten
+- events: Veranstaltungen und Aktivitäten mit Datum (YYYY-MM-DD), Ort und Link (leer wenn keiner bekannt)
+- sights: Sehenswürdigkeiten und Ausflüge mit kurzer Beschreibung und Link (leer wenn keiner bekannt)
+`
+
+// briefingSchema is the JSON schema of StructuredBriefing in the strict subset
+// supported by structured outputs: every property required, no extra properties.
+var briefingSchema = JSONSchema{
+	Name: "briefing",
+	Schema: object(map[string]any{
+		"header": object(map[string]any{
+			"latitude":  map[string]any{"type": "number"},
+			"longitude": map[string]any{"type": "number"},
+			"location":  map[string]any{"type": "string"},
+		}),
+		"warnings": array(map[string]any{"type": "string"}),
+		"sections": array(object(map[string]any{
+			"title": map[string]any{"type": "string"},
+			"items": array(map[string]any{"type": "string"}),
+		})),
+		"events": object(map[string]any{
+			"title": map[string]any{"type": "string"},
+			"items": array(object(map[string]any{
+				"title": map[string]any{"type": "string"},
+				"date":  map[string]any{"type": "string", "description": "YYYY-MM-DD or YYYY-MM-DD/YYYY-MM-DD"},
+				"place": map[string]any{"type": "string"},
+				"link":  map[string]any{"type": "string", "description": "http(s) URL, empty if unknown"},
+			})),
+		}),
+		"sights": object(map[string]any{
+			"title": map[string]any{"type": "string"},
+			"items": array(object(map[string]any{
+				"name":        map[string]any{"type": "string"},
+				"description": map[string]any{"type": "string"},
+				"link":        map[string]any{"type": "string", "description": "http(s) URL, empty if unknown"},
+			})),
+		}),
+	}),
+}
+
+func object(properties map[string]any) map[string]any {
+	required := make([]string, 0, len(properties))
+	for name := range properties {
+		required = append(required, name)
+	}
+	slices.Sort(required)
+	return map[string]any{
+		"type":                 "object",
+		"properties":           properties,
+		"required":             required,
+		"additionalProperties": false,
+	}
+}
+
+func array(items map[string]any) map[string]any {
+	return map[string]any{"type": "array", "items": items}
+}
+
+// ParseStructuredBriefing decodes the model's answer. Models without native
+// structured outputs sometimes wrap the JSON in a code fence or add a sentence,
+// so only the outermost JSON object is decoded.
+func ParseStructuredBriefing(text string) (StructuredBriefing, error) {
+	var doc StructuredBriefing
+	start, end := strings.Index(text, "{"), strings.LastIndex(text, "}")
+	if start < 0 || end < start {
+		return doc, errors.New("no JSON object in the model's answer")
+	}
+	dec := json.NewDecoder(strings.NewReader(text[start : end+1]))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&doc); err != nil {
+		return doc, fmt.Errorf("decoding structured briefing: %w", err)
+	}
+	return doc, nil
+}
+
+// Validate checks what the schema cannot: required text is present, dates are
+// dates and links are web links.
+func (d StructuredBriefing) Validate() error {
+	var errs []error
+	problem := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	if strings.TrimSpace(d.Header.Location) == "" {
+		problem("header: location is empty")
+	}
+	if d.Header.Latitude < -90 || d.Header.Latitude > 90 || d.Header.Longitude < -180 || d.Header.Longitude > 180 {
+		problem("header: position %.5f, %.5f out of range", d.Header.Latitude, d.Header.Longitude)
+	}
+	if len(d.Sections) == 0 {
+		problem("sections: none")
+	}
+	for i, s := range d.Sections {
+		if strings.TrimSpace(s.Title) == "" {
+			problem("sections[%d]: title is empty", i)
+		}
+		if len(s.Items) == 0 {
+			problem("sections[%d] %q: no items", i, s.Title)
+		}
+	}
+	for i, e := range d.Events.Items {
+		if strings.TrimSpace(e.Title) == "" {
+			problem("events[%d]: title is empty", i)
+		}
+		if !validEventDate(e.Date) {
+			problem("events[%d] %q: date %q is not YYYY-MM-DD", i, e.Title, e.Date)
+		}
+		if !validLink(e.Link) {
+			problem("events[%d] %q: link %q is not a web link", i, e.Title, e.Link)
+		}
+	}
+	for i, s := range d.Sights.Items {
+		if strings.TrimSpace(s.Name) == "" {
+			problem("sights[%d]: name is empty", i)
+		}
+		if !validLink(s.Link) {
+			problem("sights[%d] %q: link %q is not a web link", i, s.Name, s.Link)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func validEventDate(s string) bool {
+	for _, part := range strings.Split(s, "/") {
+		if _, err := time.Parse("2006-01-02", strings.TrimSpace(part)); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+func validLink(s string) bool {
+	if s == "" {
+		return true
+	}
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// GenerateStructuredBriefing asks the model for the briefing as JSON constrained by
+// briefingSchema and validates it. The header position is always our own.
+func GenerateStructuredBriefing(model BriefingModel, in BriefingInput, promptText string) (StructuredBriefing, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	userMessage := buildUserMessage(in)
+	fmt.Fprintf(os.Stderr, "User Message:\n%s", userMessage)
+
+	text, err := model.Generate(ctx, BriefingRequest{
+		Instructions: promptText + structuredInstructions,
+		UserMessage:  userMessage,
+		Location:     in.Location,
+		Schema:       &briefingSchema,
+	})
+	if err != nil {
+		return StructuredBriefing{}, err
+	}
+	fmt.Fprintf(os.Stderr, "%s Response:\n%s\n", model.Name(), text)
+
+	doc, err := ParseStructuredBriefing(text)
+	if err != nil {
+		return doc, err
+	}
+	doc.Header.Latitude, doc.Header.Longitude = in.Location.Latitude, in.Location.Longitude
+	if err := doc.Validate(); err != nil {
+		return doc, fmt.Errorf("invalid structured briefing:\n%w", err)
+	}
+	return doc, nil
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+var sampleStructured = StructuredBriefing{
+	Header:   BriefingHeader{Latitude: 43.508, Longitude: 16.44, Location: "Split, Kroatien"},
+	Warnings: []string{"Bora mit Böen bis 45 kn\nam Nachmittag"},
+	Sections: []BriefingSection{
+		{Title: "Standort", Items: []string{"Altstadt im Diokletianpalast"}},
+		{Title: "Wetter und Seegang", Items: []string{"Sonnig, 22°C", "Bora ab Mittag"}},
+	},
+	Events: BriefingEvents{Title: "Veranstaltungen", Items: []BriefingEvent{
+		{Title: "Fischmarkt", Date: "2026-03-15", Place: "Peskarija", Link: "https://example.com/market"},
+	}},
+	Sights: BriefingSights{Title: "Sehenswürdigkeiten", Items: []BriefingSight{
+		{Name: "Marjan", Description: "Hügel mit Aussicht"},
+	}},
+}
+
+func TestParseStructuredBriefing(t *testing.T) {
+	text := "Hier ist das Briefing:\n```json\n" +
+		`{"header":{"latitude":0,"longitude":0,"location":"Split, Kroatien"},"warnings":[],` +
+		`"sections":[{"title":"Standort","items":["Split"]}],"events":{"title":"Events","items":[]},"sights":{"title":"Sights","items":[]}}` +
+		"\n```"
+	doc, err := ParseStructuredBriefing(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.Header.Location != "Split, Kroatien" || len(doc.Sections) != 1 {
+		t.Errorf("ParseStructuredBriefing = %+v", doc)
+	}
+
+	if _, err := ParseStructuredBriefing(`{"header":{},"extra":1}`); err == nil {
+		t.Error("unknown field accepted")
+	}
+	if _, err := ParseStructuredBriefing("- [[Tagesbriefing]]"); err == nil {
+		t.Error("text without JSON accepted")
+	}
+}
+
+func TestStructuredBriefingValidate(t *testing.T) {
+	if err := sampleStructured.Validate(); err != nil {
+		t.Errorf("valid briefing rejected: %v", err)
+	}
+
+	bad := sampleStructured
+	bad.Header.Location = " "
+	bad.Sections = []BriefingSection{{Title: "Standort"}}
+	bad.Events.Items = []BriefingEvent{{Title: "Konzert", Date: "Samstag", Link: "javascript:alert(1)"}}
+	err := bad.Validate()
+	if err == nil {
+		t.Fatal("invalid briefing accepted")
+	}
+	for _, want := range []string{"location is empty", `"Standort": no items`, `date "Samstag"`, "not a web link"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("validation error missing %q: %v", want, err)
+		}
+	}
+}
+
+func TestBriefingSchemaStrict(t *testing.T) {
+	var check func(path string, s map[string]any)
+	check = func(path string, s map[string]any) {
+		switch s["type"] {
+		case "object":
+			props := s["properties"].(map[string]any)
+			if s["additionalProperties"] != false || len(s["required"].([]string)) != len(props) {
+				t.Errorf("%s: not strict: %v", path, s)
+			}
+			for name, p := range props {
+				check(path+"."+name, p.(map[string]any))
+			}
+		case "array":
+			check(path+"[]", s["items"].(map[string]any))
+		}
+	}
+	check("briefing", briefingSchema.Schema)
+}
+
+func TestRenderStructuredBriefing(t *testing.T) {
+	got, err := RenderStructuredBriefing(sampleStructured)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "- [[Tagesbriefing]]\n" +
+		"\t- position:: 43.50800, 16.44000\n" +
+		"\t  location:: Split, Kroatien\n" +
+		"\t- ⚠️ Bora mit Böen bis 45 kn am Nachmittag\n" +
+		"\t- Standort\n" +
+		"\t\t- Altstadt im Diokletianpalast\n" +
+		"\t- Wetter und Seegang\n" +
+		"\t\t- Sonnig, 22°C\n" +
+		"\t\t- Bora ab Mittag\n" +
+		"\t- Veranstaltungen\n" +
+		"\t\t- Fischmarkt (2026-03-15, Peskarija) [Link](https://example.com/market)\n" +
+		"\t- Sehenswürdigkeiten\n" +
+		"\t\t- **Marjan**: Hügel mit Aussicht\n"
+	if got != want {
+		t.Errorf("Logseq =\n%s\nwant\n%s", got, want)
+	}
+}
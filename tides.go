@@ -0,0 +1,155 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	minTidalRangeM              = 0.1 // below this the sea level series is too flat for tide times
+	currentAgainstWindKn        = 0.5 // weaker currents do not steepen the sea noticeably
+	currentAgainstWindMinWindKn = 10  // knots; in lighter wind the steep sea does not build
+	currentAgainstWindMaxAngle  = 60  // degrees between the current and the wind's origin
+)
+
+// TideExtreme is a high or low water.
+type TideExtreme struct {
+	Time   string // local time, e.g. "2026-03-15T06:40"
+	Height float64
+	High   bool
+}
+
+// FindTideExtremes finds high and low water in the hourly sea level series. The time
+// and height of each extreme are refined by fitting a parabola through the hour of
+// the extreme and its neighbours. A series with less than minTidalRangeM range yields
+// no extremes.
+func FindTideExtremes(hourly []HourlyMarine) []TideExtreme {
+	var levels []float64
+	for _, m := range hourly {
+		if m.SeaLevel != nil {
+			levels = append(levels, *m.SeaLevel)
+		}
+	}
+	if len(levels) < 3 || slices.Max(levels)-slices.Min(levels) < minTidalRangeM {
+		return nil
+	}
+
+	var extremes []TideExtreme
+	for i := 1; i < len(hourly)-1; i++ {
+		if hourly[i-1].SeaLevel == nil || hourly[i].SeaLevel == nil || hourly[i+1].SeaLevel == nil {
+			continue // an extreme next to a gap cannot be told apart from a slope
+		}
+		a, b, c := *hourly[i-1].SeaLevel, *hourly[i].SeaLevel, *hourly[i+1].SeaLevel
+		high := b > a && b >= c
+		low := b < a && b <= c
+		if !high && !low {
+			continue
+		}
+
+		// Vertex of the parabola through (-1, a), (0, b), (1, c).
+		offset, height := 0.0, b
+		if d := a - 2*b + c; d != 0 {
+			offset = 0.5 * (a - c) / d
+			height = b - 0.25*(a-c)*offset
+		}
+
+		t := hourly[i].Time
+		if parsed, err := time.Parse(openMeteoTime, t); err == nil {
+			t = parsed.Add(time.Duration(offset * float64(time.Hour))).Round(time.Minute).Format(openMeteoTime)
+		}
+		extremes = append(extremes, TideExtreme{Time: t, Height: height, High: high})
+	}
+	return extremes
+}
+
+// CurrentAgainstWindHours lists the hours in which a noticeable current runs against
+// a fresh wind, which makes the sea short and steep.
+func CurrentAgainstWindHours(w WeatherData) []string {
+	wind := make(map[string]HourlyForecast, len(w.Hourly))
+	for _, h := range w.Hourly {
+		wind[h.Time] = h
+	}
+
+	var hours []string
+	for _, m := range w.HourlyMarine {
+		h, ok := wind[m.Time]
+		if !ok || m.CurrentVelocity == nil || m.CurrentDirection == nil || h.WindSpeed == nil || h.WindDirection == nil {
+			continue
+		}
+		if currentAgainstWind(*m.CurrentVelocity, *m.CurrentDirection, w.Units.ToKnots(*h.WindSpeed), *h.WindDirection) {
+			hours = append(hours, m.Time)
+		}
+	}
+	return hours
+}
+
+// currentAgainstWind reports whether a current (km/h, flowing towards currentTo)
+// opposes a wind (knots, blowing from windFrom).
+func currentAgainstWind(currentKmh, currentTo, windKn, windFrom float64) bool {
+	if currentKmh/kmhPerKnot < currentAgainstWindKn || windKn < currentAgainstWindMinWindKn {
+		return false
+	}
+	// The current flows into the wind when it runs towards where the wind comes from.
+	return trueWindAngle(currentTo, windFrom) <= currentAgainstWindMaxAngle
+}
+
+// FormatTides renders high and low water and the hours of current against wind.
+func FormatTides(w WeatherData) string {
+	var b strings.Builder
+	u := w.Units
+
+	b.WriteString("\n=== TIDES AND CURRENTS ===\n")
+	b.WriteString(fmt.Sprintf("Sea level now: %s MSL, current %s towards %s, sea temperature %s\n",
+		optf("%+.2f m", w.Marine.SeaLevel), optCurrent(w.Marine.CurrentVelocity), optCompass(w.Marine.CurrentDirection), optf("%.1f°C", w.Marine.SeaSurfaceTemp)))
+
+	extremes := FindTideExtremes(w.HourlyMarine)
+	if len(extremes) == 0 {
+		b.WriteString(fmt.Sprintf("Tidal range below %.1f m, no significant tide.\n", minTidalRangeM))
+	}
+	for _, e := range extremes {
+		kind := "Low water"
+		if e.High {
+			kind = "High water"
+		}
+		b.WriteString(fmt.Sprintf("%s: %s, %+.2f m\n", kind, e.Time, e.Height))
+	}
+
+	if hours := CurrentAgainstWindHours(w); len(hours) > 0 {
+		b.WriteString(fmt.Sprintf("Current against wind (%.1f kn or more against %.0f %s or more, steep seas): %s\n",
+			currentAgainstWindKn, u.FromKmh(currentAgainstWindMinWindKn*kmhPerKnot), u.WindUnit(), formatHourRanges(hours)))
+	}
+	return b.String()
+}
+
+// formatCurrent shows a current speed from km/h in knots, as on every chart.
+func formatCurrent(kmh float64) string {
+	return fmt.Sprintf("%.1f kn", kmh/kmhPerKnot)
+}
+
+// optCurrent renders an optional current speed like formatCurrent, or "n/a".
+func optCurrent(kmh *float64) string {
+	if kmh == nil {
+		return notAvailable
+	}
+	return formatCurrent(*kmh)
+}
+
+// formatHourRanges merges consecutive hours into ranges like "2026-03-15T13:00–16:00".
+func formatHourRanges(hours []string) string {
+	var ranges []string
+	start := 0
+	for i := 1; i <= len(hours); i++ {
+		if i < len(hours) && nextHour(hours[i-1], hours[i]) {
+			continue
+		}
+		r := hours[start]
+		if i-1 > start {
+			r += "–" + hourOf(hours[i-1])
+		}
+		ranges = append(ranges, r)
+		start = i
+	}
+	return strings.Join(ranges, ", ")
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+// semidiurnalTide is a 1 m tide with high water at 03:00 and a 12 h 25 min period.
+func semidiurnalTide(hours int) []HourlyMarine {
+	start := time.Date(2026, 3, 15, 0, 0, 0, 0, time.UTC)
+	period := 12*time.Hour + 25*time.Minute
+	var hourly []HourlyMarine
+	for i := 0; i < hours; i++ {
+		t := start.Add(time.Duration(i) * time.Hour)
+		phase := 2 * math.Pi * float64(t.Sub(start)-3*time.Hour) / float64(period)
+		hourly = append(hourly, HourlyMarine{Time: t.Format(openMeteoTime), SeaLevel: ptr(0.5 * math.Cos(phase))})
+	}
+	return hourly
+}
+
+func TestFindTideExtremes(t *testing.T) {
+	got := FindTideExtremes(semidiurnalTide(24))
+	want := []struct {
+		time string
+		high bool
+	}{
+		{"2026-03-15T03:00", true},
+		{"2026-03-15T09:12", false},
+		{"2026-03-15T15:25", true},
+		{"2026-03-15T21:37", false},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("FindTideExtremes = %+v", got)
+	}
+	for i, w := range want {
+		e := got[i]
+		gotTime, _ := time.Parse(openMeteoTime, e.Time)
+		wantTime, _ := time.Parse(openMeteoTime, w.time)
+		// The parabola fit is accurate to a few minutes.
+		if e.High != w.high || gotTime.Sub(wantTime).Abs() > 10*time.Minute || math.Abs(math.Abs(e.Height)-0.5) > 0.02 {
+			t.Errorf("extreme %d = %+v, want high=%v at %s", i, e, w.high, w.time)
+		}
+	}
+
+	flat := semidiurnalTide(24)
+	for i := range flat {
+		*flat[i].SeaLevel *= 0.05
+	}
+	if got := FindTideExtremes(flat); got != nil {
+		t.Errorf("FindTideExtremes on a 5 cm tide = %+v, want none", got)
+	}
+}
+
+func TestCurrentAgainstWind(t *testing.T) {
+	data := WeatherData{
+		Units: UnitsNautical,
+		Hourly: []HourlyForecast{
+			{Time: "2026-03-15T12:00", WindSpeed: ptr(18.0), WindDirection: ptr(0.0)},
+			{Time: "2026-03-15T13:00", WindSpeed: ptr(18.0), WindDirection: ptr(0.0)},
+			{Time: "2026-03-15T14:00", WindSpeed: ptr(18.0), WindDirection: ptr(0.0)},
+			{Time: "2026-03-15T15:00", WindSpeed: ptr(6.0), WindDirection: ptr(0.0)},
+		},
+		HourlyMarine: []HourlyMarine{
+			{Time: "2026-03-15T12:00", CurrentVelocity: ptr(2.0), CurrentDirection: ptr(20.0)},  // north-going into a northerly
+			{Time: "2026-03-15T13:00", CurrentVelocity: ptr(2.0), CurrentDirection: ptr(350.0)}, // still against
+			{Time: "2026-03-15T14:00", CurrentVelocity: ptr(2.0), CurrentDirection: ptr(180.0)}, // with the wind
+			{Time: "2026-03-15T15:00", CurrentVelocity: ptr(2.0), CurrentDirection: ptr(0.0)},   // wind too light
+		},
+	}
+	if got := formatHourRanges(CurrentAgainstWindHours(data)); got != "2026-03-15T12:00–13:00" {
+		t.Errorf("current against wind = %q", got)
+	}
+
+	data.Marine.SeaLevel = ptr(0.3)
+	out := FormatTides(data)
+	for _, want := range []string{"Sea level now: +0.30 m MSL", "no significant tide", "Current against wind (0.5 kn or more against 10 kn or more, steep seas): 2026-03-15T12:00–13:00"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("FormatTides missing %q in:\n%s", want, out)
+		}
+	}
+}
@@ -11,69 +11,117 @@ type Location struct {
 	DisplayName string
 }
 
-// CurrentWeather holds the current weather conditions.
+// CurrentWeather holds the current weather conditions. A nil value was not reported
+// by the weather model, which is not the same as 0.
 type CurrentWeather struct {
-	Temperature   float64 // °C
-	WindSpeed     float64 // km/h
-	WindDirection float64 // degrees
-	WeatherCode   int
-	Humidity      int     // %
-	Pressure      float64 // hPa
-	CloudCover    int     // %
-	Precipitation float64 // mm
+	Temperature   *float64 // °C
+	WindSpeed     *float64 // WeatherData.Units
+	WindDirection *float64 // degrees
+	WindGusts     *float64 // WeatherData.Units
+	WeatherCode   *int
+	Humidity      *int     // %
+	Pressure      *float64 // hPa
+	CloudCover    *int     // %
+	Precipitation *float64 // mm
+	Visibility    *float64 // meters
 }
 
 // DailyForecast holds a single day's forecast.
 type DailyForecast struct {
 	Date              string
-	TempMax           float64
-	TempMin           float64
-	PrecipitationSum  float64
-	PrecipitationProb int
-	WindSpeedMax      float64
-	WindDirection     float64
-	WeatherCode       int
+	TempMax           *float64
+	TempMin           *float64
+	PrecipitationSum  *float64
+	PrecipitationProb *int
+	WindSpeedMax      *float64
+	WindGustsMax      *float64
+	WindDirection     *float64
+	WeatherCode       *int
+	Sunrise           string // local time, e.g. "2026-03-15T06:12"
+	Sunset            string
 }
 
 // HourlyForecast holds a single hour's forecast.
 type HourlyForecast struct {
 	Time          string
-	Temperature   float64
-	WindSpeed     float64
-	WindDirection float64
-	Precipitation float64
-	WeatherCode   int
+	Temperature   *float64
+	WindSpeed     *float64
+	WindGusts     *float64
+	WindDirection *float64
+	Precipitation *float64
+	WeatherCode   *int
+	Visibility    *float64 // meters
+	CAPE          *float64 // J/kg, convective available potential energy
+	LiftedIndex   *float64 // K, negative means unstable air
+	Pressure      *float64 // hPa, mean sea level
 }
 
-// MarineData holds marine/wave conditions.
+// MarineData holds marine/wave conditions. All values are nil where the wave model
+// does not cover the position, e.g. in a harbour or on a lake.
 type MarineData struct {
-	WaveHeight       float64 // meters
-	WaveDirection    float64 // degrees
-	WavePeriod       float64 // seconds
-	WindWaveHeight   float64
-	SwellWaveHeight  float64
-	SwellWaveDir     float64
-	SwellWavePeriod  float64
+	WaveHeight       *float64 // meters
+	WaveDirection    *float64 // degrees
+	WavePeriod       *float64 // seconds
+	WindWaveHeight   *float64
+	SwellWaveHeight  *float64
+	SwellWaveDir     *float64
+	SwellWavePeriod  *float64
+	SeaLevel         *float64 // meters above mean sea level, tide and surge
+	CurrentVelocity  *float64 // km/h
+	CurrentDirection *float64 // degrees the current flows towards
+	SeaSurfaceTemp   *float64 // °C
 }
 
 // HourlyMarine holds hourly marine forecast data.
 type HourlyMarine struct {
 	Time             string
-	WaveHeight       float64
-	WaveDirection    float64
-	WavePeriod       float64
-	WindWaveHeight   float64
-	SwellWaveHeight  float64
-	SwellWaveDir     float64
-	SwellWavePeriod  float64
+	WaveHeight       *float64
+	WaveDirection    *float64
+	WavePeriod       *float64
+	WindWaveHeight   *float64
+	SwellWaveHeight  *float64
+	SwellWaveDir     *float64
+	SwellWavePeriod  *float64
+	SeaLevel         *float64
+	CurrentVelocity  *float64
+	CurrentDirection *float64
+	SeaSurfaceTemp   *float64
+}
+
+// PressureReading is the mean sea level pressure in one hour.
+type PressureReading struct {
+	Time     string
+	Pressure float64 // hPa
+}
+
+// Spread is the range of one value across forecast models or ensemble members.
+type Spread struct {
+	Min     float64
+	Max     float64
+	Mean    float64
+	Members int // models or members that reported the value
+}
+
+// HourlySpread holds the model spread of a single hour. A nil spread had fewer
+// than two members reporting.
+type HourlySpread struct {
+	Time          string
+	Wind          *Spread // WeatherData.Units
+	Gusts         *Spread // WeatherData.Units
+	Precipitation *Spread // mm
 }
 
 // WeatherData holds all weather information for a location.
 type WeatherData struct {
-	Current       CurrentWeather
-	Daily         []DailyForecast
-	Hourly        []HourlyForecast
-	Marine        MarineData
-	HourlyMarine  []HourlyMarine
-	Timezone      string
+	Current         CurrentWeather
+	Daily           []DailyForecast
+	Hourly          []HourlyForecast
+	PressureHistory []PressureReading // the reported hours before the current one, oldest first
+	Marine          MarineData
+	HourlyMarine    []HourlyMarine
+	Spread          []HourlySpread // only with WeatherOptions.Models or Ensemble
+	SpreadSources   []string       // the models and ensembles the spread is computed from
+	Sources         []string       // which provider each part and filled-in value came from
+	Timezone        string
+	Units           UnitSystem // unit of all wind speeds; wave heights are always meters
 }
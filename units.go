@@ -0,0 +1,140 @@
+package main
+
+import (
+	"fmt"
+	"math"
+)
+
+// UnitSystem selects the units for wind speed and wave height in the weather output.
+// Temperatures, pressure and precipitation are always metric.
+type UnitSystem string
+
+const (
+	UnitsMetric   UnitSystem = "metric"   // km/h, meters
+	UnitsNautical UnitSystem = "nautical" // knots, meters
+	UnitsImperial UnitSystem = "imperial" // mph, feet
+)
+
+const (
+	kmhPerKnot   = 1.852
+	kmhPerMph    = 1.609344
+	feetPerMeter = 3.28084
+)
+
+// ParseUnitSystem validates a --units value.
+func ParseUnitSystem(s string) (UnitSystem, error) {
+	switch u := UnitSystem(s); u {
+	case UnitsMetric, UnitsNautical, UnitsImperial:
+		return u, nil
+	}
+	return "", fmt.Errorf("unknown unit system %q (expected metric, nautical or imperial)", s)
+}
+
+// windSpeedParam is the Open-Meteo wind_speed_unit request parameter.
+// WeatherData without a unit system (e.g. an old snapshot) is metric.
+func (u UnitSystem) windSpeedParam() string {
+	switch u {
+	case UnitsNautical:
+		return "kn"
+	case UnitsImperial:
+		return "mph"
+	}
+	return "kmh"
+}
+
+// WindUnit is the label for wind speeds in this unit system.
+func (u UnitSystem) WindUnit() string {
+	switch u {
+	case UnitsNautical:
+		return "kn"
+	case UnitsImperial:
+		return "mph"
+	}
+	return "km/h"
+}
+
+// ToKmh converts a wind speed in this unit system to km/h.
+func (u UnitSystem) ToKmh(v float64) float64 {
+	switch u {
+	case UnitsNautical:
+		return v * kmhPerKnot
+	case UnitsImperial:
+		return v * kmhPerMph
+	}
+	return v
+}
+
+// ToKnots converts a wind speed in this unit system to knots.
+func (u UnitSystem) ToKnots(v float64) float64 {
+	return u.ToKmh(v) / kmhPerKnot
+}
+
+// FromKmh converts a wind speed in km/h to this unit system.
+func (u UnitSystem) FromKmh(kmh float64) float64 {
+	return kmh / u.ToKmh(1)
+}
+
+// formatWind renders a wind speed with knots and Beaufort force alongside,
+// e.g. "15.0 km/h (8 kn, Bft 3 Gentle breeze)".
+func (u UnitSystem) formatWind(v float64, decimals int) string {
+	kn := u.ToKnots(v)
+	force, desc := beaufort(kn)
+	if u == UnitsNautical {
+		return fmt.Sprintf("%.*f kn (Bft %d %s)", decimals, v, force, desc)
+	}
+	return fmt.Sprintf("%.*f %s (%.0f kn, Bft %d %s)", decimals, v, u.WindUnit(), kn, force, desc)
+}
+
+// optWind renders an optional wind speed like formatWind, or "n/a" if it is missing.
+func (u UnitSystem) optWind(v *float64, decimals int) string {
+	if v == nil {
+		return notAvailable
+	}
+	return u.formatWind(*v, decimals)
+}
+
+// optHeight renders an optional height like formatHeight, or "n/a" if it is missing.
+func (u UnitSystem) optHeight(m *float64) string {
+	if m == nil {
+		return notAvailable
+	}
+	return u.formatHeight(*m)
+}
+
+// formatHeight renders a wave height given in meters, in feet for imperial units.
+func (u UnitSystem) formatHeight(m float64) string {
+	if u == UnitsImperial {
+		return fmt.Sprintf("%.1fft", m*feetPerMeter)
+	}
+	return fmt.Sprintf("%.1fm", m)
+}
+
+// beaufortScale holds the upper limit in knots (exclusive) of each Beaufort force.
+var beaufortScale = []struct {
+	maxKnots float64
+	desc     string
+}{
+	{1, "Calm"},
+	{4, "Light air"},
+	{7, "Light breeze"},
+	{11, "Gentle breeze"},
+	{17, "Moderate breeze"},
+	{22, "Fresh breeze"},
+	{28, "Strong breeze"},
+	{34, "Near gale"},
+	{41, "Gale"},
+	{48, "Strong gale"},
+	{56, "Storm"},
+	{64, "Violent storm"},
+}
+
+// beaufort returns the Beaufort force and its description for a wind speed in knots.
+func beaufort(knots float64) (int, string) {
+	kn := math.Round(knots)
+	for force, b := range beaufortScale {
+		if kn < b.maxKnots {
+			return force, b.desc
+		}
+	}
+	return 12, "Hurricane force"
+}
@@ -0,0 +1,44 @@
+package main
+
+import "testing"
+
+func TestBeaufort(t *testing.T) {
+	tests := []struct {
+		knots float64
+		force int
+		desc  string
+	}{
+		{0, 0, "Calm"},
+		{0.4, 0, "Calm"},
+		{3, 1, "Light air"},
+		{10.4, 3, "Gentle breeze"},
+		{10.6, 4, "Moderate breeze"},
+		{27, 6, "Strong breeze"},
+		{35, 8, "Gale"},
+		{70, 12, "Hurricane force"},
+	}
+	for _, tt := range tests {
+		force, desc := beaufort(tt.knots)
+		if force != tt.force || desc != tt.desc {
+			t.Errorf("beaufort(%v) = %d %q, want %d %q", tt.knots, force, desc, tt.force, tt.desc)
+		}
+	}
+}
+
+func TestUnitSystemFormatting(t *testing.T) {
+	if got := UnitsNautical.formatWind(18, 0); got != "18 kn (Bft 5 Fresh breeze)" {
+		t.Errorf("nautical formatWind = %q", got)
+	}
+	if got := UnitsMetric.formatWind(37, 1); got != "37.0 km/h (20 kn, Bft 5 Fresh breeze)" {
+		t.Errorf("metric formatWind = %q", got)
+	}
+	if got := UnitsImperial.formatHeight(2); got != "6.6ft" {
+		t.Errorf("imperial formatHeight = %q", got)
+	}
+	if got := UnitsNautical.ToKmh(10); got != 18.52 {
+		t.Errorf("nautical ToKmh(10) = %v, want 18.52", got)
+	}
+	if _, err := ParseUnitSystem("furlongs"); err == nil {
+		t.Error("expected error for unknown unit system")
+	}
+}
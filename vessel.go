@@ -0,0 +1,379 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// VesselProfile describes the boat and what the crew is comfortable with.
+type VesselProfile struct {
+	Name           string
+	LOA            float64   // length over all, meters
+	Draft          float64   // meters
+	MaxTrueWindKn  float64   // most true wind the crew is comfortable sailing in
+	MaxWaveM       float64   // highest comfortable significant wave height
+	MinWavePeriodS float64   // waves with a shorter period are steep and uncomfortable
+	ReefKn         []float64 // true wind for the 1st, 2nd, ... reef
+}
+
+// DefaultVesselProfile is a cautious cruising couple on a 12 m monohull.
+var DefaultVesselProfile = VesselProfile{
+	Name:           "",
+	LOA:            12,
+	Draft:          1.9,
+	MaxTrueWindKn:  25,
+	MaxWaveM:       2.5,
+	MinWavePeriodS: 6,
+	ReefKn:         []float64{16, 21, 26},
+}
+
+// Verdict is the go/no-go assessment of one hour.
+type Verdict int
+
+const (
+	VerdictGo Verdict = iota
+	VerdictCaution
+	VerdictNoGo
+)
+
+func (v Verdict) String() string {
+	switch v {
+	case VerdictCaution:
+		return "caution"
+	case VerdictNoGo:
+		return "no-go"
+	}
+	return "go"
+}
+
+// HourAssessment is the verdict for one forecast hour with the reasons behind it.
+type HourAssessment struct {
+	Time    string
+	Verdict Verdict
+	Reasons []Reason
+	Reefs   int // suggested number of reefs
+}
+
+// Reason is a condition behind a verdict, e.g. gusts of 31 kn. It is only turned
+// into text for display, so reasons of the same kind can be compared by value.
+type Reason struct {
+	Kind   string
+	Value  float64 // unused for kinds without a value, a weather code for thunderstorms
+	Unit   string  // empty for kinds without a value
+	Period float64 // wave period in seconds of steep waves
+}
+
+// Kinds of reasons.
+const (
+	ReasonNoWind       = "no wind forecast"
+	ReasonNoGusts      = "no gust forecast"
+	ReasonWind         = "wind"
+	ReasonLightWind    = "light wind"
+	ReasonGusts        = "gusts"
+	ReasonThunderstorm = "thunderstorm"
+	ReasonVisibility   = "visibility"
+	ReasonWaves        = "waves"
+	ReasonSteepWaves   = "steep waves"
+	ReasonBeating      = "beating, TWA down to"
+	ReasonDark         = "arrival in the dark"
+)
+
+// lowerIsWorse lists the kinds whose worst value is the lowest one.
+var lowerIsWorse = map[string]bool{ReasonLightWind: true, ReasonVisibility: true, ReasonBeating: true}
+
+func (r Reason) String() string {
+	switch {
+	case r.Kind == ReasonThunderstorm:
+		return strings.ToLower(weatherCodeToText(int(r.Value)))
+	case r.Unit == "":
+		return r.Kind
+	case r.Kind == ReasonSteepWaves:
+		return fmt.Sprintf("%s %.1f %s / %.0f s", r.Kind, r.Value, r.Unit, r.Period)
+	case r.Kind == ReasonWaves:
+		return fmt.Sprintf("%s %.1f %s", r.Kind, r.Value, r.Unit)
+	case r.Unit == "°":
+		return fmt.Sprintf("%s %.0f°", r.Kind, r.Value)
+	}
+	return fmt.Sprintf("%s %.0f %s", r.Kind, r.Value, r.Unit)
+}
+
+// worseThan reports whether r is worse than other of the same kind.
+func (r Reason) worseThan(other Reason) bool {
+	if lowerIsWorse[r.Kind] {
+		return r.Value < other.Value
+	}
+	return r.Value > other.Value
+}
+
+// formatReasons renders reasons as a comma-separated list.
+func formatReasons(reasons []Reason) string {
+	parts := make([]string, len(reasons))
+	for i, r := range reasons {
+		parts[i] = r.String()
+	}
+	return strings.Join(parts, ", ")
+}
+
+// SailingWindow is a run of consecutive hours with the same verdict.
+type SailingWindow struct {
+	Start   string
+	End     string
+	Verdict Verdict
+	Reasons []Reason // the worst reason of each kind over the window
+	Reefs   int      // most reefs suggested in the window
+}
+
+// SailingDay groups the windows of one date.
+type SailingDay struct {
+	Date    string
+	Windows []SailingWindow
+}
+
+// Limits relative to the vessel profile.
+const (
+	gustNoGoFactor    = 1.3  // gusts this far beyond the max wind mean no-go
+	cautionWindFactor = 0.8  // mean wind above this share of the max is caution
+	cautionWaveFactor = 0.75 // waves above this share of the max are caution
+	steepWaveFactor   = 0.5  // short-period waves only matter from this share of the max
+	lightWindKn       = 5    // below this we motor
+	poorVisibilityM   = 1000
+)
+
+// AssessHour scores one hour of the forecast for the vessel. The marine hour may be
+// nil when there is no marine data for that time. An hour without a wind or gust
+// forecast is at best caution.
+func AssessHour(h HourlyForecast, m *HourlyMarine, units UnitSystem, p VesselProfile) HourAssessment {
+	a := HourAssessment{Time: h.Time}
+	limit := func(v Verdict, r Reason) {
+		a.Verdict = max(a.Verdict, v)
+		a.Reasons = append(a.Reasons, r)
+	}
+
+	// Without a wind or gust forecast the hour cannot be called safe; the other
+	// checks still run, so they can make it no-go.
+	var wind, gust float64
+	if h.WindSpeed == nil {
+		limit(VerdictCaution, Reason{Kind: ReasonNoWind})
+	} else {
+		wind = units.ToKnots(*h.WindSpeed)
+		switch {
+		case wind > p.MaxTrueWindKn:
+			limit(VerdictNoGo, Reason{Kind: ReasonWind, Value: wind, Unit: "kn"})
+		case wind > p.MaxTrueWindKn*cautionWindFactor:
+			limit(VerdictCaution, Reason{Kind: ReasonWind, Value: wind, Unit: "kn"})
+		case wind < lightWindKn:
+			a.Reasons = append(a.Reasons, Reason{Kind: ReasonLightWind, Value: wind, Unit: "kn"})
+		}
+	}
+	if h.WindGusts == nil {
+		limit(VerdictCaution, Reason{Kind: ReasonNoGusts})
+	} else {
+		gust = units.ToKnots(*h.WindGusts)
+		switch {
+		case gust > p.MaxTrueWindKn*gustNoGoFactor:
+			limit(VerdictNoGo, Reason{Kind: ReasonGusts, Value: gust, Unit: "kn"})
+		case gust > p.MaxTrueWindKn:
+			limit(VerdictCaution, Reason{Kind: ReasonGusts, Value: gust, Unit: "kn"})
+		}
+	}
+
+	if h.WeatherCode != nil && isThunderstormCode(*h.WeatherCode) {
+		limit(VerdictNoGo, Reason{Kind: ReasonThunderstorm, Value: float64(*h.WeatherCode)})
+	}
+	if h.Visibility != nil && *h.Visibility < poorVisibilityM {
+		limit(VerdictCaution, Reason{Kind: ReasonVisibility, Value: *h.Visibility, Unit: "m"})
+	}
+
+	if m != nil && m.WaveHeight != nil {
+		wave := *m.WaveHeight
+		switch {
+		case wave > p.MaxWaveM:
+			limit(VerdictNoGo, Reason{Kind: ReasonWaves, Value: wave, Unit: "m"})
+		case wave > p.MaxWaveM*cautionWaveFactor:
+			limit(VerdictCaution, Reason{Kind: ReasonWaves, Value: wave, Unit: "m"})
+		}
+		if m.WavePeriod != nil && *m.WavePeriod < p.MinWavePeriodS && wave >= p.MaxWaveM*steepWaveFactor {
+			limit(VerdictCaution, Reason{Kind: ReasonSteepWaves, Value: wave, Unit: "m", Period: *m.WavePeriod})
+		}
+	}
+
+	for _, reefAt := range p.ReefKn {
+		if max(wind, gust/gustNoGoFactor) >= reefAt {
+			a.Reefs++
+		}
+	}
+	return a
+}
+
+// AssessSailing scores every hour of the hourly forecast, matched with the hourly
+// marine forecast by time.
+func AssessSailing(w WeatherData, p VesselProfile) []HourAssessment {
+	marine := make(map[string]*HourlyMarine, len(w.HourlyMarine))
+	for i := range w.HourlyMarine {
+		marine[w.HourlyMarine[i].Time] = &w.HourlyMarine[i]
+	}
+
+	assessments := make([]HourAssessment, 0, len(w.Hourly))
+	for _, h := range w.Hourly {
+		assessments = append(assessments, AssessHour(h, marine[h.Time], w.Units, p))
+	}
+	return assessments
+}
+
+// SummarizeSailing merges the hourly assessments into windows of equal verdict per day.
+func SummarizeSailing(hours []HourAssessment) []SailingDay {
+	var days []SailingDay
+	for _, a := range hours {
+		date := dateOf(a.Time)
+		if len(days) == 0 || days[len(days)-1].Date != date {
+			days = append(days, SailingDay{Date: date})
+		}
+		day := &days[len(days)-1]
+
+		if n := len(day.Windows); n > 0 && day.Windows[n-1].Verdict == a.Verdict {
+			win := &day.Windows[n-1]
+			win.End = a.Time
+			win.Reasons = appendDistinctKinds(win.Reasons, a.Reasons)
+			win.Reefs = max(win.Reefs, a.Reefs)
+			continue
+		}
+		day.Windows = append(day.Windows, SailingWindow{
+			Start:   a.Time,
+			End:     a.Time,
+			Verdict: a.Verdict,
+			Reasons: appendDistinctKinds(nil, a.Reasons),
+			Reefs:   a.Reefs,
+		})
+	}
+	return days
+}
+
+// appendDistinctKinds adds reasons whose kind is not listed yet and replaces listed
+// ones with worse values, so a window spells out each limiting factor once with its
+// worst value.
+func appendDistinctKinds(reasons, more []Reason) []Reason {
+	for _, r := range more {
+		i := slices.IndexFunc(reasons, func(existing Reason) bool { return existing.Kind == r.Kind })
+		switch {
+		case i < 0:
+			reasons = append(reasons, r)
+		case r.worseThan(reasons[i]):
+			reasons[i] = r
+		}
+	}
+	return reasons
+}
+
+// FormatVesselProfile renders the profile for the user message.
+func FormatVesselProfile(p VesselProfile) string {
+	name := p.Name
+	if name == "" {
+		name = "our boat"
+	}
+	reefs := make([]string, len(p.ReefKn))
+	for i, kn := range p.ReefKn {
+		reefs[i] = fmt.Sprintf("%.0f", kn)
+	}
+	return fmt.Sprintf("Vessel: %s, LOA %.1f m, draft %.1f m, max comfortable true wind %.0f kn, max waves %.1f m, min wave period %.0f s, reefs at %s kn\n",
+		name, p.LOA, p.Draft, p.MaxTrueWindKn, p.MaxWaveM, p.MinWavePeriodS, strings.Join(reefs, "/"))
+}
+
+// FormatSailingAssessment renders the daily sailing windows for the user message.
+func FormatSailingAssessment(days []SailingDay, p VesselProfile) string {
+	var b strings.Builder
+	b.WriteString("=== SAILING ASSESSMENT (computed for our vessel) ===\n")
+	b.WriteString(FormatVesselProfile(p))
+	for _, d := range days {
+		b.WriteString(fmt.Sprintf("%s: %s\n", d.Date, formatWindows(d.Windows)))
+	}
+	return b.String()
+}
+
+func formatWindows(windows []SailingWindow) string {
+	parts := make([]string, 0, len(windows))
+	for _, win := range windows {
+		part := fmt.Sprintf("%s %s–%s", win.Verdict, hourOf(win.Start), hourOf(win.End))
+		details := formatReasons(win.Reasons)
+		if win.Reefs > 0 && win.Verdict != VerdictNoGo {
+			if details != "" {
+				details += ", "
+			}
+			details += fmt.Sprintf("reef %d", win.Reefs)
+		}
+		if details != "" {
+			part += " (" + details + ")"
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, "; ")
+}
+
+var sailingBlockTitle = map[string]string{
+	"de": "⛵ Segelfenster",
+	"en": "⛵ Sailing windows",
+}
+
+// InsertSailingBlock adds the sailing windows as a Logseq block after the briefing's header.
+func InsertSailingBlock(briefing string, days []SailingDay, lang string) string {
+	if len(days) == 0 {
+		return briefing
+	}
+	title, ok := sailingBlockTitle[lang]
+	if !ok {
+		title = sailingBlockTitle["en"]
+	}
+	return insertAfterHeader(briefing, func(indent string) string {
+		var b strings.Builder
+		b.WriteString(fmt.Sprintf("%s- %s\n", indent, title))
+		for _, d := range days {
+			b.WriteString(fmt.Sprintf("%s\t- %s: %s\n", indent, d.Date, formatWindows(d.Windows)))
+		}
+		return b.String()
+	})
+}
+
+// floatList is a flag.Value for comma-separated numbers such as "16,21,26".
+type floatList []float64
+
+func (l *floatList) String() string {
+	parts := make([]string, len(*l))
+	for i, v := range *l {
+		parts[i] = strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return strings.Join(parts, ",")
+}
+
+func (l *floatList) Set(s string) error {
+	var values []float64
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		v, err := strconv.ParseFloat(part, 64)
+		if err != nil {
+			return err
+		}
+		values = append(values, v)
+	}
+	*l = values
+	return nil
+}
+
+// vesselFlags registers the vessel profile flags on fs. They can be set in the
+// config file as VESSEL_NAME, VESSEL_LOA, etc.
+func vesselFlags(fs *flag.FlagSet) *VesselProfile {
+	p := DefaultVesselProfile
+	p.ReefKn = append([]float64(nil), DefaultVesselProfile.ReefKn...)
+	fs.StringVar(&p.Name, "vessel-name", p.Name, "Name of the boat")
+	fs.Float64Var(&p.LOA, "vessel-loa", p.LOA, "Length over all (m)")
+	fs.Float64Var(&p.Draft, "vessel-draft", p.Draft, "Draft (m)")
+	fs.Float64Var(&p.MaxTrueWindKn, "vessel-max-wind", p.MaxTrueWindKn, "Maximum comfortable true wind (kn)")
+	fs.Float64Var(&p.MaxWaveM, "vessel-max-wave", p.MaxWaveM, "Maximum comfortable wave height (m)")
+	fs.Float64Var(&p.MinWavePeriodS, "vessel-min-wave-period", p.MinWavePeriodS, "Shortest comfortable wave period (s)")
+	fs.Var((*floatList)(&p.ReefKn), "vessel-reefs", "True wind for each reef, comma-separated (kn)")
+	return &p
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"flag"
+	"testing"
+)
+
+func TestAssessSailing(t *testing.T) {
+	data := WeatherData{
+		Units: UnitsNautical,
+		Hourly: []HourlyForecast{
+			{Time: "2026-03-15T08:00", WindSpeed: ptr(12.0), WindGusts: ptr(16.0)},
+			{Time: "2026-03-15T09:00", WindSpeed: ptr(14.0), WindGusts: ptr(18.0)},
+			{Time: "2026-03-15T10:00", WindSpeed: ptr(21.0), WindGusts: ptr(27.0)},
+			{Time: "2026-03-15T11:00", WindSpeed: ptr(24.0), WindGusts: ptr(36.0)},
+			{Time: "2026-03-16T08:00", WindSpeed: ptr(3.0), WindGusts: ptr(5.0), WeatherCode: ptr(95)},
+		},
+		HourlyMarine: []HourlyMarine{
+			{Time: "2026-03-15T09:00", WaveHeight: ptr(1.4), WavePeriod: ptr(4.0)},
+		},
+	}
+
+	hours := AssessSailing(data, DefaultVesselProfile)
+	wantVerdicts := []Verdict{VerdictGo, VerdictCaution, VerdictCaution, VerdictNoGo, VerdictNoGo}
+	for i, want := range wantVerdicts {
+		if hours[i].Verdict != want {
+			t.Errorf("hour %s verdict = %s, want %s (%v)", hours[i].Time, hours[i].Verdict, want, hours[i].Reasons)
+		}
+	}
+	if hours[2].Reefs != 2 {
+		t.Errorf("reefs at 21 kn gusting 27 = %d, want 2", hours[2].Reefs)
+	}
+
+	days := SummarizeSailing(hours)
+	if len(days) != 2 {
+		t.Fatalf("SummarizeSailing returned %d days, want 2", len(days))
+	}
+	got := formatWindows(days[0].Windows)
+	want := "go 08:00–08:00; caution 09:00–10:00 (steep waves 1.4 m / 4 s, wind 21 kn, gusts 27 kn, reef 2); no-go 11:00–11:00 (wind 24 kn, gusts 36 kn)"
+	if got != want {
+		t.Errorf("formatWindows =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestAssessHourMissingGusts(t *testing.T) {
+	p := DefaultVesselProfile
+	tests := []struct {
+		name string
+		h    HourlyForecast
+		m    *HourlyMarine
+		want Verdict
+		why  string
+	}{
+		{"strong wind", HourlyForecast{WindSpeed: ptr(28.0)}, nil, VerdictNoGo, "wind 28 kn, no gust forecast"},
+		{"thunderstorm", HourlyForecast{WindSpeed: ptr(10.0), WeatherCode: ptr(95)}, nil, VerdictNoGo, "no gust forecast, thunderstorm"},
+		{"high waves", HourlyForecast{WindSpeed: ptr(10.0)}, &HourlyMarine{WaveHeight: ptr(3.0)}, VerdictNoGo, "no gust forecast, waves 3.0 m"},
+		{"no wind", HourlyForecast{WindGusts: ptr(12.0)}, nil, VerdictCaution, "no wind forecast"},
+	}
+	for _, tt := range tests {
+		a := AssessHour(tt.h, tt.m, UnitsNautical, p)
+		if got := formatReasons(a.Reasons); a.Verdict != tt.want || got != tt.why {
+			t.Errorf("%s: %s (%s), want %s (%s)", tt.name, a.Verdict, got, tt.want, tt.why)
+		}
+	}
+}
+
+func TestAppendDistinctKinds(t *testing.T) {
+	wind := func(kn float64) Reason { return Reason{Kind: ReasonWind, Value: kn, Unit: "kn"} }
+	gusts := func(kn float64) Reason { return Reason{Kind: ReasonGusts, Value: kn, Unit: "kn"} }
+	visibility := func(m float64) Reason { return Reason{Kind: ReasonVisibility, Value: m, Unit: "m"} }
+	waves := func(m float64) Reason { return Reason{Kind: ReasonWaves, Value: m, Unit: "m"} }
+	storm := func(code float64) Reason { return Reason{Kind: ReasonThunderstorm, Value: code} }
+
+	reasons := appendDistinctKinds(nil, []Reason{wind(21), gusts(22), visibility(800), storm(95)})
+	reasons = appendDistinctKinds(reasons, []Reason{gusts(31), visibility(300), waves(1.5), {Kind: ReasonNoGusts}})
+	reasons = appendDistinctKinds(reasons, []Reason{wind(19), waves(2.5), storm(99), {Kind: ReasonNoGusts}})
+	got := formatReasons(reasons)
+	if want := "wind 21 kn, gusts 31 kn, visibility 300 m, thunderstorm with heavy hail, waves 2.5 m, no gust forecast"; got != want {
+		t.Errorf("appendDistinctKinds = %q, want %q", got, want)
+	}
+}
+
+func TestVesselFlags(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	p := vesselFlags(fs)
+	if err := fs.Parse([]string{"--vessel-max-wind", "20", "--vessel-reefs", "12, 17"}); err != nil {
+		t.Fatal(err)
+	}
+	if p.MaxTrueWindKn != 20 || len(p.ReefKn) != 2 || p.ReefKn[1] != 17 {
+		t.Errorf("vessel profile = %+v", p)
+	}
+	if len(DefaultVesselProfile.ReefKn) != 3 {
+		t.Errorf("setting --vessel-reefs changed the default profile: %v", DefaultVesselProfile.ReefKn)
+	}
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"sailingnomads-briefing/logseq"
+)
+
+// watchBaselineFile holds the forecast of the last change alert, so that an alert is
+// only repeated when the forecast worsens again.
+const watchBaselineFile = "watch-baseline.json"
+
+// ChangeLimits are what counts as a significant worsening of the forecast.
+type ChangeLimits struct {
+	GustIncreaseKmh float64 // gusts up by this much, where they reach the gust warning limit
+	PressureDropHPa float64 // a fall of this much within pressureTendencyHours signals a front
+	Hours           int     // how far ahead to compare
+}
+
+// DefaultChangeLimits are the defaults of the watch flags.
+var DefaultChangeLimits = ChangeLimits{
+	GustIncreaseKmh: 15,
+	PressureDropHPa: 3,
+	Hours:           48,
+}
+
+// ChangeAlert is a significant worsening of the forecast compared to a baseline.
+type ChangeAlert struct {
+	Parameter string
+	Hours     []string // affected forecast hours
+	Text      string
+}
+
+// CompareForecasts compares the upcoming hours of the current forecast with the same
+// hours of the baseline and reports what got significantly worse: gusts, new
+// thunderstorms, waves crossing the warning limit and fronts announced by a pressure
+// fall. Hours the baseline does not cover are not compared.
+func CompareForecasts(base, cur WeatherData, th HazardThresholds, lim ChangeLimits, now time.Time) []ChangeAlert {
+	u := cur.Units
+	window := trimPastForecast(cur, now)
+	if len(window.Hourly) > lim.Hours {
+		window.Hourly = window.Hourly[:lim.Hours]
+	}
+	if len(window.Hourly) == 0 {
+		return nil
+	}
+	start, end := window.Hourly[0].Time, window.Hourly[len(window.Hourly)-1].Time
+
+	baseHourly := make(map[string]HourlyForecast, len(base.Hourly))
+	for _, h := range base.Hourly {
+		baseHourly[h.Time] = h
+	}
+
+	var alerts []ChangeAlert
+
+	var gustHours []string
+	var maxGust, maxIncrease float64
+	for _, h := range window.Hourly {
+		b, ok := baseHourly[h.Time]
+		if !ok || h.WindGusts == nil || b.WindGusts == nil {
+			continue
+		}
+		gust := u.ToKmh(*h.WindGusts)
+		increase := gust - base.Units.ToKmh(*b.WindGusts)
+		if increase >= lim.GustIncreaseKmh && gust >= th.GustKmh {
+			gustHours = append(gustHours, h.Time)
+			maxGust, maxIncrease = max(maxGust, gust), max(maxIncrease, increase)
+		}
+	}
+	if len(gustHours) > 0 {
+		alerts = append(alerts, ChangeAlert{HazardGusts, gustHours, fmt.Sprintf("gusts up to %s, up to %.0f %s more than forecast before",
+			u.formatWind(u.FromKmh(maxGust), 0), u.FromKmh(maxIncrease), u.WindUnit())})
+	}
+
+	var stormHours []string
+	worstCode := 0
+	for _, h := range window.Hourly {
+		if h.WeatherCode == nil || !isThunderstormCode(*h.WeatherCode) {
+			continue
+		}
+		if b, ok := baseHourly[h.Time]; ok && b.WeatherCode != nil && !isThunderstormCode(*b.WeatherCode) {
+			stormHours = append(stormHours, h.Time)
+			worstCode = max(worstCode, *h.WeatherCode)
+		}
+	}
+	if len(stormHours) > 0 {
+		alerts = append(alerts, ChangeAlert{HazardThunderstorm, stormHours, "new in the forecast: " + weatherCodeToText(worstCode)})
+	}
+
+	baseMarine := make(map[string]HourlyMarine, len(base.HourlyMarine))
+	for _, m := range base.HourlyMarine {
+		baseMarine[m.Time] = m
+	}
+	var waveHours []string
+	var maxWave float64
+	for _, m := range cur.HourlyMarine {
+		if m.Time < start || m.Time > end || m.WaveHeight == nil || *m.WaveHeight < th.WaveM {
+			continue
+		}
+		if b, ok := baseMarine[m.Time]; ok && b.WaveHeight != nil && *b.WaveHeight < th.WaveM {
+			waveHours = append(waveHours, m.Time)
+			maxWave = max(maxWave, *m.WaveHeight)
+		}
+	}
+	if len(waveHours) > 0 {
+		alerts = append(alerts, ChangeAlert{HazardWaveHeight, waveHours, fmt.Sprintf("wave height up to %s, above the %s limit",
+			u.formatHeight(maxWave), u.formatHeight(th.WaveM))})
+	}
+
+	curFall, baseFall := pressureFalls(pressureSeries(cur)), pressureFalls(pressureSeries(base))
+	var frontHours []string
+	var maxFall float64
+	for _, h := range window.Hourly {
+		fall, ok := curFall[h.Time]
+		before, known := baseFall[h.Time]
+		if ok && known && fall >= lim.PressureDropHPa && before < lim.PressureDropHPa {
+			frontHours = append(frontHours, h.Time)
+			maxFall = max(maxFall, fall)
+		}
+	}
+	if len(frontHours) > 0 {
+		alerts = append(alerts, ChangeAlert{"pressure", frontHours, fmt.Sprintf("pressure falling up to %.1f hPa in %d hours, a front is coming through",
+			maxFall, pressureTendencyHours)})
+	}
+
+	return alerts
+}
+
+// FormatChangeAlerts renders the alerts as a short text.
+func FormatChangeAlerts(alerts []ChangeAlert, place string, since time.Time) string {
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("⚠️ Forecast for %s worse than at %s:\n", place, since.Local().Format("2006-01-02 15:04")))
+	for _, a := range alerts {
+		b.WriteString(fmt.Sprintf("- %s: %s\n", formatHourRanges(a.Hours), a.Text))
+	}
+	return b.String()
+}
+
+// runWatch implements the watch subcommand: it re-fetches the forecast periodically
+// and alerts, without the language model, when it got significantly worse than the
+// forecast of the last briefing or the last alert.
+func runWatch(args []string) {
+	fs := flag.NewFlagSet("watch", flag.ExitOnError)
+	lat := fs.Float64("lat", 0, "Latitude of the current position")
+	lon := fs.Float64("lon", 0, "Longitude of the current position")
+	saillog := fs.String("saillog", "", "Logseq graph directory to read the current position from instead of --lat/--lon")
+	interval := fs.Duration("interval", time.Hour, "Time between checks; 0 checks once and exits")
+	lim := DefaultChangeLimits
+	fs.Float64Var(&lim.GustIncreaseKmh, "alert-gust-increase", lim.GustIncreaseKmh, "Alert when gusts rise by this much (km/h) to at least the gust warning limit")
+	fs.Float64Var(&lim.PressureDropHPa, "alert-pressure-drop", lim.PressureDropHPa, "Alert when a pressure fall of this much (hPa) in 3 hours appears in the forecast")
+	fs.IntVar(&lim.Hours, "watch-hours", lim.Hours, "How many hours ahead to compare")
+	notifyPath := fs.String("notify", "", "JSON file with the channels to send alerts to")
+	th := hazardFlags(fs)
+	common := registerCommonFlags(fs)
+	common.parse(fs, args)
+
+	if *saillog == "" && *lat == 0 && *lon == 0 {
+		fmt.Fprintln(os.Stderr, "Error: --lat and --lon or --saillog are required")
+		fmt.Fprintln(os.Stderr, "Usage: briefing watch --lat <latitude> --lon <longitude> [--interval 1h] [--notify <notify.json>]")
+		fmt.Fprintln(os.Stderr, "       briefing watch --saillog <dir> [--interval 1h] [--notify <notify.json>]")
+		os.Exit(1)
+	}
+	notify := loadNotify(*notifyPath, common.cacheDir)
+
+	for {
+		if *saillog != "" {
+			journal, err := logseq.Open(*saillog)
+			if err == nil {
+				var pos logseq.Position
+				if pos, err = journal.LatestPosition(time.Now(), positionSearchDays); err == nil {
+					*lat, *lon = pos.Lat, pos.Lon
+				}
+			}
+			if err != nil {
+				if *lat == 0 && *lon == 0 {
+					fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+					os.Exit(1)
+				}
+				fmt.Fprintf(os.Stderr, "Warning: %v, staying at %.5f, %.5f\n", err, *lat, *lon)
+			}
+		}
+
+		if err := watchOnce(*lat, *lon, common, *th, lim, notify, time.Now()); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			if *interval <= 0 {
+				os.Exit(1)
+			}
+		}
+		if *interval <= 0 {
+			return
+		}
+		time.Sleep(*interval)
+	}
+}
+
+// watchOnce fetches the forecast and compares it with the baseline: the newer of the
+// last briefing's snapshot and the last alert. An alert is printed, sent to the
+// notifier channels and becomes the new baseline.
+func watchOnce(lat, lon float64, common *commonFlags, th HazardThresholds, lim ChangeLimits, notify NotifyConfig, now time.Time) error {
+	base, err := loadSnapshot(common.cacheDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+	if alerted, err := loadSnapshotFrom(common.cacheDir, watchBaselineFile); err == nil && alerted.FetchedAt.After(base.FetchedAt) {
+		base = alerted
+	}
+
+	fmt.Fprintln(os.Stderr, "Fetching weather data...")
+	weather, err := FetchWeather(lat, lon, WeatherOptions{Units: common.units, Providers: common.weather})
+	if err != nil {
+		return fmt.Errorf("fetching weather: %w", err)
+	}
+	cur := Snapshot{FetchedAt: now, Location: base.Location, Weather: weather}
+
+	if base.FetchedAt.IsZero() || distanceKm(lat, lon, base.Location.Latitude, base.Location.Longitude) > offlineLocationRadiusKm {
+		fmt.Fprintln(os.Stderr, "No forecast of a briefing for this position yet, keeping this one to compare with")
+		cur.Location = Location{Latitude: lat, Longitude: lon}
+		return saveSnapshotAs(common.cacheDir, watchBaselineFile, cur)
+	}
+
+	alerts := CompareForecasts(base.Weather, weather, th, lim, now)
+	if len(alerts) == 0 {
+		fmt.Fprintf(os.Stderr, "No significant change since %s\n", base.FetchedAt.Local().Format("2006-01-02 15:04"))
+		return nil
+	}
+
+	place := offlineLocationName(lat, lon, base, "")
+	text := FormatChangeAlerts(alerts, place, base.FetchedAt)
+	fmt.Print(text)
+	if len(notify.Channels) > 0 {
+		msg := Message{Subject: "⚠️ Forecast worse: " + place, Text: text}
+		if err := DeliverAlert(context.Background(), notify, msg); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: delivery failed, see %s:\n%v\n", notify.DeadLetterDir, err)
+		}
+	}
+	return saveSnapshotAs(common.cacheDir, watchBaselineFile, cur)
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// watchForecast builds 12 hours of forecast from 2026-03-15T06:00 UTC with calm
+// conditions; change modifies the hours.
+func watchForecast(change func(i int, h *HourlyForecast, m *HourlyMarine)) WeatherData {
+	w := WeatherData{Timezone: "UTC", Units: UnitsMetric}
+	for i := 0; i < 12; i++ {
+		t := fmt.Sprintf("2026-03-15T%02d:00", 6+i)
+		h := HourlyForecast{Time: t, WindSpeed: ptr(15.0), WindGusts: ptr(25.0), WeatherCode: ptr(2), Pressure: ptr(1015.0)}
+		m := HourlyMarine{Time: t, WaveHeight: ptr(0.8)}
+		if change != nil {
+			change(i, &h, &m)
+		}
+		w.Hourly = append(w.Hourly, h)
+		w.HourlyMarine = append(w.HourlyMarine, m)
+	}
+	return w
+}
+
+func TestCompareForecasts(t *testing.T) {
+	now := time.Date(2026, 3, 15, 7, 30, 0, 0, time.UTC)
+	base := watchForecast(nil)
+
+	if alerts := CompareForecasts(base, watchForecast(nil), DefaultHazardThresholds, DefaultChangeLimits, now); len(alerts) != 0 {
+		t.Errorf("unchanged forecast: %+v", alerts)
+	}
+
+	// Small changes and gusts that stay below the gust warning limit are no alert,
+	// even when they rise above the mean wind limit.
+	minor := watchForecast(func(i int, h *HourlyForecast, m *HourlyMarine) {
+		h.WindGusts = ptr(29.0)
+		if i == 5 {
+			h.WindGusts = ptr(42.0)
+		}
+		m.WaveHeight = ptr(1.5)
+		h.Pressure = ptr(1015 - float64(i)*0.5)
+	})
+	if alerts := CompareForecasts(base, minor, DefaultHazardThresholds, DefaultChangeLimits, now); len(alerts) != 0 {
+		t.Errorf("minor change: %+v", alerts)
+	}
+
+	worse := watchForecast(func(i int, h *HourlyForecast, m *HourlyMarine) {
+		switch {
+		case i == 0:
+			h.WindGusts = ptr(80.0) // in the past, ignored
+		case i >= 4 && i <= 6:
+			h.WindGusts = ptr(50.0)
+		}
+		if i == 8 {
+			h.WeatherCode = ptr(95)
+		}
+		if i >= 9 {
+			m.WaveHeight = ptr(2.4)
+		}
+		if i >= 6 {
+			h.Pressure = ptr(1015 - float64(i-5)*1.5) // 3 hPa in 3 hours at 13:00, 4.5 from 14:00
+		}
+	})
+	alerts := CompareForecasts(base, worse, DefaultHazardThresholds, DefaultChangeLimits, now)
+	got := FormatChangeAlerts(alerts, "Split, Croatia", now)
+	for _, want := range []string{
+		"- 2026-03-15T10:00–12:00: gusts up to 50 km/h (27 kn, Bft 6 Strong breeze), up to 25 km/h more than forecast before\n",
+		"- 2026-03-15T14:00: new in the forecast: Thunderstorm",
+		"- 2026-03-15T15:00–17:00: wave height up to 2.4m, above the 2.0m limit\n",
+		"- 2026-03-15T13:00–17:00: pressure falling up to 4.5 hPa in 3 hours, a front is coming through\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("alerts lack %q:\n%s", want, got)
+		}
+	}
+	if len(alerts) != 4 {
+		t.Errorf("got %d alerts, want 4", len(alerts))
+	}
+
+	// Only the hours the baseline covers and within the window are compared.
+	short := DefaultChangeLimits
+	short.Hours = 2
+	if alerts := CompareForecasts(base, worse, DefaultHazardThresholds, short, now); len(alerts) != 0 {
+		t.Errorf("2-hour window: %+v", alerts)
+	}
+	if alerts := CompareForecasts(WeatherData{Units: UnitsMetric}, worse, DefaultHazardThresholds, DefaultChangeLimits, now); len(alerts) != 0 {
+		t.Errorf("empty baseline: %+v", alerts)
+	}
+}
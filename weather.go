@@ -1,94 +1,220 @@
 package main
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
+	"net/url"
 	"os"
 	"strings"
 	"time"
 )
 
-// FetchWeather retrieves current conditions and forecasts from Open-Meteo.
-func FetchWeather(lat, lon float64) (WeatherData, error) {
+// WeatherOptions controls what FetchWeather requests.
+type WeatherOptions struct {
+	Units         UnitSystem
+	ForecastHours int               // length of the hourly forecast; 0 means 48
+	Timezone      string            // IANA timezone for all times; empty means the position's own
+	Models        []string          // Open-Meteo models to compare, e.g. icon_seamless, ecmwf_ifs025, gfs_seamless
+	Ensemble      string            // Open-Meteo ensemble model for the member spread, e.g. ecmwf_ifs025
+	Providers     []WeatherProvider // in order of preference; empty means Open-Meteo
+}
+
+func (o WeatherOptions) forecastHours() int {
+	if o.ForecastHours <= 0 {
+		return 48
+	}
+	return o.ForecastHours
+}
+
+func (o WeatherOptions) timezone() string {
+	if o.Timezone == "" {
+		return "auto"
+	}
+	return url.QueryEscape(o.Timezone)
+}
+
+// location is the timezone of opts for providers that answer in UTC, which is also
+// what they use when it is empty.
+func (o WeatherOptions) location() (*time.Location, error) {
+	if o.Timezone == "" {
+		return time.UTC, nil
+	}
+	return time.LoadLocation(o.Timezone)
+}
+
+func (o WeatherOptions) providers() []WeatherProvider {
+	if len(o.Providers) == 0 {
+		return []WeatherProvider{NewOpenMeteo("")}
+	}
+	return o.Providers
+}
+
+// openMeteo is the Open-Meteo provider of opts, for the model comparison and the
+// passage forecast, which only Open-Meteo has.
+func (o WeatherOptions) openMeteo() *OpenMeteo {
+	for _, p := range o.Providers {
+		if om, ok := p.(*OpenMeteo); ok {
+			return om
+		}
+	}
+	return NewOpenMeteo("")
+}
+
+// FetchWeather retrieves current conditions and forecasts from the providers of opts
+// and merges them, see mergeWeather.
+func FetchWeather(lat, lon float64, opts WeatherOptions) (WeatherData, error) {
+	data, err := mergeWeather(lat, lon, opts)
+	if err != nil {
+		return WeatherData{}, err
+	}
+
+	if len(opts.Models) > 0 || opts.Ensemble != "" {
+		spread, sources, err := fetchSpread(lat, lon, opts)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: could not fetch the model comparison: %v\n", err)
+		} else {
+			data.Spread, data.SpreadSources = spread, sources
+		}
+	}
+
+	return data, nil
+}
+
+// Default hosts of the Open-Meteo APIs.
+const (
+	openMeteoForecastURL = "https://api.open-meteo.com"
+	openMeteoMarineURL   = "https://marine-api.open-meteo.com"
+	openMeteoEnsembleURL = "https://ensemble-api.open-meteo.com"
+)
+
+// OpenMeteo is the Open-Meteo API. Its URLs are the hosts of the forecast, marine
+// and ensemble APIs, without the /v1 path.
+type OpenMeteo struct {
+	ForecastURL, MarineURL, EnsembleURL string
+}
+
+// NewOpenMeteo returns the Open-Meteo provider at baseURL, e.g. a self-hosted
+// instance serving all APIs, or at the public hosts if baseURL is empty.
+func NewOpenMeteo(baseURL string) *OpenMeteo {
+	if baseURL == "" {
+		return &OpenMeteo{openMeteoForecastURL, openMeteoMarineURL, openMeteoEnsembleURL}
+	}
+	baseURL = strings.TrimSuffix(baseURL, "/")
+	return &OpenMeteo{baseURL, baseURL, baseURL}
+}
+
+func (*OpenMeteo) Name() string { return "Open-Meteo" }
+
+// fetchForecast makes the forecast request shared by Forecast and Observations, so
+// that the response cache answers the second one.
+func (o *OpenMeteo) fetchForecast(lat, lon float64, opts WeatherOptions) (openMeteoWeatherResponse, error) {
 	hourlyParams := []string{
 		"temperature_2m", "wind_speed_10m", "wind_direction_10m",
-		"precipitation", "weather_code",
+		"wind_gusts_10m", "precipitation", "weather_code",
+		"visibility", "cape", "lifted_index", "pressure_msl",
 	}
 	dailyParams := []string{
 		"temperature_2m_max", "temperature_2m_min",
 		"precipitation_sum", "precipitation_probability_max",
-		"wind_speed_10m_max", "wind_direction_10m_dominant",
-		"weather_code",
+		"wind_speed_10m_max", "wind_gusts_10m_max", "wind_direction_10m_dominant",
+		"weather_code", "sunrise", "sunset",
 	}
 	currentParams := []string{
 		"temperature_2m", "wind_speed_10m", "wind_direction_10m",
-		"relative_humidity_2m", "surface_pressure", "cloud_cover",
-		"precipitation", "weather_code",
+		"wind_gusts_10m", "relative_humidity_2m", "surface_pressure",
+		"cloud_cover", "precipitation", "weather_code", "visibility",
 	}
 
-	url := fmt.Sprintf(
-		"https://api.open-meteo.com/v1/forecast?latitude=%f&longitude=%f"+
-			"&current=%s&hourly=%s&daily=%s"+
-			"&timezone=auto&forecast_days=7&forecast_hours=48",
-		lat, lon,
+	query := fmt.Sprintf(
+		"&current=%s&hourly=%s&daily=%s"+
+			"&timezone=%s&forecast_days=7&forecast_hours=%d&past_hours=%d&wind_speed_unit=%s",
 		strings.Join(currentParams, ","),
 		strings.Join(hourlyParams, ","),
 		strings.Join(dailyParams, ","),
+		opts.timezone(), opts.forecastHours(), pressureHistoryHours,
+		opts.Units.windSpeedParam(),
 	)
+	url := fmt.Sprintf("%s/v1/forecast?latitude=%f&longitude=%f", o.ForecastURL, lat, lon) + query
+
+	return fetchJSON[openMeteoWeatherResponse](SourceForecast, gridKey(lat, lon, query), url)
+}
 
-	weatherResp, err := fetchJSON[openMeteoWeatherResponse](url)
+// Forecast returns the daily and hourly forecast and the pressure of the past hours.
+func (o *OpenMeteo) Forecast(lat, lon float64, opts WeatherOptions) (WeatherData, error) {
+	weatherResp, err := o.fetchForecast(lat, lon, opts)
 	if err != nil {
-		return WeatherData{}, fmt.Errorf("fetching weather: %w", err)
-	}
-
-	data := WeatherData{
-		Timezone: weatherResp.Timezone,
-		Current: CurrentWeather{
-			Temperature:   weatherResp.Current.Temperature2m,
-			WindSpeed:     weatherResp.Current.WindSpeed10m,
-			WindDirection: weatherResp.Current.WindDirection10m,
-			WeatherCode:   weatherResp.Current.WeatherCode,
-			Humidity:      weatherResp.Current.RelativeHumidity2m,
-			Pressure:      weatherResp.Current.SurfacePressure,
-			CloudCover:    weatherResp.Current.CloudCover,
-			Precipitation: weatherResp.Current.Precipitation,
-		},
+		return WeatherData{}, err
 	}
 
+	data := WeatherData{Timezone: weatherResp.Timezone, Units: opts.Units}
+
 	for i, t := range weatherResp.Daily.Time {
 		data.Daily = append(data.Daily, DailyForecast{
 			Date:              t,
 			TempMax:           safeIndex(weatherResp.Daily.Temperature2mMax, i),
 			TempMin:           safeIndex(weatherResp.Daily.Temperature2mMin, i),
 			PrecipitationSum:  safeIndex(weatherResp.Daily.PrecipitationSum, i),
-			PrecipitationProb: safeIndexInt(weatherResp.Daily.PrecipitationProbMax, i),
+			PrecipitationProb: safeIndex(weatherResp.Daily.PrecipitationProbMax, i),
 			WindSpeedMax:      safeIndex(weatherResp.Daily.WindSpeed10mMax, i),
+			WindGustsMax:      safeIndex(weatherResp.Daily.WindGusts10mMax, i),
 			WindDirection:     safeIndex(weatherResp.Daily.WindDirection10mDom, i),
-			WeatherCode:       safeIndexInt(weatherResp.Daily.WeatherCode, i),
+			WeatherCode:       safeIndex(weatherResp.Daily.WeatherCode, i),
+			Sunrise:           safeIndexString(weatherResp.Daily.Sunrise, i),
+			Sunset:            safeIndexString(weatherResp.Daily.Sunset, i),
 		})
 	}
 
+	// The past hours are only requested for the pressure tendency.
+	currentHour := weatherResp.Current.Time
+	if len(currentHour) >= len("2006-01-02T15") {
+		currentHour = currentHour[:len("2006-01-02T15")] + ":00"
+	}
 	for i, t := range weatherResp.Hourly.Time {
+		if t < currentHour {
+			if p := safeIndex(weatherResp.Hourly.PressureMSL, i); p != nil {
+				data.PressureHistory = append(data.PressureHistory, PressureReading{Time: t, Pressure: *p})
+			}
+			continue
+		}
 		data.Hourly = append(data.Hourly, HourlyForecast{
 			Time:          t,
 			Temperature:   safeIndex(weatherResp.Hourly.Temperature2m, i),
 			WindSpeed:     safeIndex(weatherResp.Hourly.WindSpeed10m, i),
+			WindGusts:     safeIndex(weatherResp.Hourly.WindGusts10m, i),
 			WindDirection: safeIndex(weatherResp.Hourly.WindDirection10m, i),
 			Precipitation: safeIndex(weatherResp.Hourly.Precipitation, i),
-			WeatherCode:   safeIndexInt(weatherResp.Hourly.WeatherCode, i),
+			WeatherCode:   safeIndex(weatherResp.Hourly.WeatherCode, i),
+			Visibility:    safeIndex(weatherResp.Hourly.Visibility, i),
+			CAPE:          safeIndex(weatherResp.Hourly.CAPE, i),
+			LiftedIndex:   safeIndex(weatherResp.Hourly.LiftedIndex, i),
+			Pressure:      safeIndex(weatherResp.Hourly.PressureMSL, i),
 		})
 	}
 
-	marine, err := fetchMarine(lat, lon)
+	return data, nil
+}
+
+// Observations returns the model's current conditions, not measurements.
+func (o *OpenMeteo) Observations(lat, lon float64, opts WeatherOptions) (CurrentWeather, error) {
+	weatherResp, err := o.fetchForecast(lat, lon, opts)
 	if err != nil {
-		fmt.Printf("Warning: could not fetch marine data: %v\n", err)
-	} else {
-		data.Marine = marine.Current
-		data.HourlyMarine = marine.Hourly
+		return CurrentWeather{}, err
 	}
-
-	return data, nil
+	return CurrentWeather{
+		Temperature:   weatherResp.Current.Temperature2m,
+		WindSpeed:     weatherResp.Current.WindSpeed10m,
+		WindDirection: weatherResp.Current.WindDirection10m,
+		WindGusts:     weatherResp.Current.WindGusts10m,
+		WeatherCode:   weatherResp.Current.WeatherCode,
+		Humidity:      weatherResp.Current.RelativeHumidity2m,
+		Pressure:      weatherResp.Current.SurfacePressure,
+		CloudCover:    weatherResp.Current.CloudCover,
+		Precipitation: weatherResp.Current.Precipitation,
+		Visibility:    weatherResp.Current.Visibility,
+	}, nil
 }
 
 type marineResult struct {
@@ -96,53 +222,66 @@ type marineResult struct {
 	Hourly  []HourlyMarine
 }
 
-func fetchMarine(lat, lon float64) (marineResult, error) {
+// Marine returns the current and hourly sea state.
+func (o *OpenMeteo) Marine(lat, lon float64, opts WeatherOptions) (marineResult, error) {
 	hourlyParams := []string{
 		"wave_height", "wave_direction", "wave_period",
 		"wind_wave_height",
 		"swell_wave_height", "swell_wave_direction", "swell_wave_period",
+		"sea_level_height_msl", "ocean_current_velocity", "ocean_current_direction",
+		"sea_surface_temperature",
 	}
 	currentParams := []string{
 		"wave_height", "wave_direction", "wave_period",
 		"wind_wave_height",
 		"swell_wave_height", "swell_wave_direction", "swell_wave_period",
+		"sea_level_height_msl", "ocean_current_velocity", "ocean_current_direction",
+		"sea_surface_temperature",
 	}
 
-	url := fmt.Sprintf(
-		"https://marine-api.open-meteo.com/v1/marine?latitude=%f&longitude=%f"+
-			"&current=%s&hourly=%s&timezone=auto&forecast_hours=48",
-		lat, lon,
+	query := fmt.Sprintf(
+		"&current=%s&hourly=%s&timezone=%s&forecast_hours=%d",
 		strings.Join(currentParams, ","),
 		strings.Join(hourlyParams, ","),
+		opts.timezone(), opts.forecastHours(),
 	)
+	url := fmt.Sprintf("%s/v1/marine?latitude=%f&longitude=%f", o.MarineURL, lat, lon) + query
 
-	resp, err := fetchJSON[openMeteoMarineResponse](url)
+	resp, err := fetchJSON[openMeteoMarineResponse](SourceMarine, gridKey(lat, lon, query), url)
 	if err != nil {
 		return marineResult{}, err
 	}
 
 	result := marineResult{
 		Current: MarineData{
-			WaveHeight:      resp.Current.WaveHeight,
-			WaveDirection:   resp.Current.WaveDirection,
-			WavePeriod:      resp.Current.WavePeriod,
-			WindWaveHeight:  resp.Current.WindWaveHeight,
-			SwellWaveHeight: resp.Current.SwellWaveHeight,
-			SwellWaveDir:    resp.Current.SwellWaveDirection,
-			SwellWavePeriod: resp.Current.SwellWavePeriod,
+			WaveHeight:       resp.Current.WaveHeight,
+			WaveDirection:    resp.Current.WaveDirection,
+			WavePeriod:       resp.Current.WavePeriod,
+			WindWaveHeight:   resp.Current.WindWaveHeight,
+			SwellWaveHeight:  resp.Current.SwellWaveHeight,
+			SwellWaveDir:     resp.Current.SwellWaveDirection,
+			SwellWavePeriod:  resp.Current.SwellWavePeriod,
+			SeaLevel:         resp.Current.SeaLevelHeightMSL,
+			CurrentVelocity:  resp.Current.OceanCurrentVelocity,
+			CurrentDirection: resp.Current.OceanCurrentDirection,
+			SeaSurfaceTemp:   resp.Current.SeaSurfaceTemperature,
 		},
 	}
 
 	for i, t := range resp.Hourly.Time {
 		result.Hourly = append(result.Hourly, HourlyMarine{
-			Time:            t,
-			WaveHeight:      safeIndex(resp.Hourly.WaveHeight, i),
-			WaveDirection:   safeIndex(resp.Hourly.WaveDirection, i),
-			WavePeriod:      safeIndex(resp.Hourly.WavePeriod, i),
-			WindWaveHeight:  safeIndex(resp.Hourly.WindWaveHeight, i),
-			SwellWaveHeight: safeIndex(resp.Hourly.SwellWaveHeight, i),
-			SwellWaveDir:    safeIndex(resp.Hourly.SwellWaveDirection, i),
-			SwellWavePeriod: safeIndex(resp.Hourly.SwellWavePeriod, i),
+			Time:             t,
+			WaveHeight:       safeIndex(resp.Hourly.WaveHeight, i),
+			WaveDirection:    safeIndex(resp.Hourly.WaveDirection, i),
+			WavePeriod:       safeIndex(resp.Hourly.WavePeriod, i),
+			WindWaveHeight:   safeIndex(resp.Hourly.WindWaveHeight, i),
+			SwellWaveHeight:  safeIndex(resp.Hourly.SwellWaveHeight, i),
+			SwellWaveDir:     safeIndex(resp.Hourly.SwellWaveDirection, i),
+			SwellWavePeriod:  safeIndex(resp.Hourly.SwellWavePeriod, i),
+			SeaLevel:         safeIndex(resp.Hourly.SeaLevelHeightMSL, i),
+			CurrentVelocity:  safeIndex(resp.Hourly.OceanCurrentVelocity, i),
+			CurrentDirection: safeIndex(resp.Hourly.OceanCurrentDirection, i),
+			SeaSurfaceTemp:   safeIndex(resp.Hourly.SeaSurfaceTemperature, i),
 		})
 	}
 
@@ -154,8 +293,49 @@ const (
 	fetchTimeout    = 30 * time.Second
 )
 
-func fetchJSON[T any](url string) (T, error) {
-	var zero T
+// userAgent identifies the briefing to the APIs; Nominatim and MET Norway require one.
+const userAgent = "sailingnomads-briefing/1.0"
+
+// fetchJSON fetches and decodes a JSON API response, going through the response
+// cache under the given source and key.
+func fetchJSON[T any](source, key, url string) (T, error) {
+	var result T
+	data, err := cachedFetch(source, key, func() ([]byte, error) {
+		return fetchBody(url)
+	})
+	if err != nil {
+		return result, err
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return result, fmt.Errorf("decoding response: %w", err)
+	}
+	return result, nil
+}
+
+// fetchJSONBatch fetches a multi-coordinate Open-Meteo request. Open-Meteo answers
+// with a list for several coordinates but with a single object for one.
+func fetchJSONBatch[T any](source, key, url string) ([]T, error) {
+	data, err := cachedFetch(source, key, func() ([]byte, error) {
+		return fetchBody(url)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] != '[' {
+		var single T
+		if err := json.Unmarshal(trimmed, &single); err != nil {
+			return nil, fmt.Errorf("decoding response: %w", err)
+		}
+		return []T{single}, nil
+	}
+	var result []T
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("decoding response: %w", err)
+	}
+	return result, nil
+}
+
+func fetchBody(url string) ([]byte, error) {
 	client := &http.Client{Timeout: fetchTimeout}
 
 	var lastErr error
@@ -166,7 +346,12 @@ func fetchJSON[T any](url string) (T, error) {
 			time.Sleep(backoff)
 		}
 
-		resp, err := client.Get(url)
+		req, err := http.NewRequest("GET", url, nil)
+		if err != nil {
+			return nil, fmt.Errorf("creating request: %w", err)
+		}
+		req.Header.Set("User-Agent", userAgent)
+		resp, err := client.Do(req)
 		if err != nil {
 			lastErr = err
 			continue
@@ -178,79 +363,175 @@ func fetchJSON[T any](url string) (T, error) {
 			continue
 		}
 
-		var result T
-		if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-			resp.Body.Close()
-			return zero, fmt.Errorf("decoding response: %w", err)
-		}
+		data, err := io.ReadAll(resp.Body)
 		resp.Body.Close()
-		return result, nil
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		return data, nil
 	}
 
-	return zero, fmt.Errorf("after %d attempts: %w", fetchMaxRetries, lastErr)
+	return nil, fmt.Errorf("after %d attempts: %w", fetchMaxRetries, lastErr)
 }
 
-func safeIndex(s []float64, i int) float64 {
+// safeIndex returns s[i], or nil if the value is null or the series is too short.
+func safeIndex[T any](s []*T, i int) *T {
 	if i < len(s) {
 		return s[i]
 	}
-	return 0
+	return nil
 }
 
-func safeIndexInt(s []int, i int) int {
+func safeIndexString(s []string, i int) string {
 	if i < len(s) {
 		return s[i]
 	}
-	return 0
+	return ""
 }
 
 // FormatWeatherData produces a human-readable summary of all weather data for the LLM prompt.
-func FormatWeatherData(w WeatherData) string {
+// Values the weather models did not report are shown as "n/a", never as 0. The storm
+// warnings of the pressure trend use the pressure fall threshold of th.
+func FormatWeatherData(w WeatherData, th HazardThresholds) string {
 	var b strings.Builder
 
+	c := w.Current
 	b.WriteString(fmt.Sprintf("=== CURRENT WEATHER (Timezone: %s) ===\n", w.Timezone))
-	b.WriteString(fmt.Sprintf("Temperature: %.1f°C\n", w.Current.Temperature))
-	b.WriteString(fmt.Sprintf("Wind: %.1f km/h from %s (%d°)\n", w.Current.WindSpeed, degToCompass(w.Current.WindDirection), int(w.Current.WindDirection)))
-	b.WriteString(fmt.Sprintf("Humidity: %d%%\n", w.Current.Humidity))
-	b.WriteString(fmt.Sprintf("Pressure: %.0f hPa\n", w.Current.Pressure))
-	b.WriteString(fmt.Sprintf("Cloud cover: %d%%\n", w.Current.CloudCover))
-	b.WriteString(fmt.Sprintf("Precipitation: %.1f mm\n", w.Current.Precipitation))
-	b.WriteString(fmt.Sprintf("Conditions: %s\n", weatherCodeToText(w.Current.WeatherCode)))
+	b.WriteString(fmt.Sprintf("Temperature: %s\n", optf("%.1f°C", c.Temperature)))
+	u := w.Units
+	b.WriteString(fmt.Sprintf("Wind: %s from %s, gusts %s\n", u.optWind(c.WindSpeed, 1), optDirection(c.WindDirection), u.optWind(c.WindGusts, 1)))
+	b.WriteString(fmt.Sprintf("Humidity: %s\n", optf("%d%%", c.Humidity)))
+	b.WriteString(fmt.Sprintf("Pressure: %s\n", optf("%.0f hPa", c.Pressure)))
+	b.WriteString(fmt.Sprintf("Cloud cover: %s\n", optf("%d%%", c.CloudCover)))
+	b.WriteString(fmt.Sprintf("Precipitation: %s\n", optf("%.1f mm", c.Precipitation)))
+	b.WriteString(fmt.Sprintf("Visibility: %s\n", optf("%.1f km", scaled(c.Visibility, 0.001))))
+	b.WriteString(fmt.Sprintf("Conditions: %s\n", optCondition(c.WeatherCode)))
+	b.WriteString(FormatPressureTrend(w, th.PressureFallHPa))
 
 	b.WriteString("\n=== 7-DAY FORECAST ===\n")
 	for _, d := range w.Daily {
-		b.WriteString(fmt.Sprintf("%s: %s, %.0f–%.0f°C, wind up to %.0f km/h from %s, precip %.1fmm (prob %d%%)\n",
-			d.Date, weatherCodeToText(d.WeatherCode),
-			d.TempMin, d.TempMax, d.WindSpeedMax,
-			degToCompass(d.WindDirection), d.PrecipitationSum, d.PrecipitationProb))
+		b.WriteString(fmt.Sprintf("%s: %s, %s–%s, wind up to %s (gusts %s) from %s, precip %s (prob %s)",
+			d.Date, optCondition(d.WeatherCode),
+			optf("%.0f", d.TempMin), optf("%.0f°C", d.TempMax), u.optWind(d.WindSpeedMax, 0), u.optWind(d.WindGustsMax, 0),
+			optCompass(d.WindDirection), optf("%.1fmm", d.PrecipitationSum), optf("%d%%", d.PrecipitationProb)))
+		if d.Sunrise != "" {
+			b.WriteString(fmt.Sprintf(", daylight %s–%s", hourOf(d.Sunrise), hourOf(d.Sunset)))
+		}
+		b.WriteString("\n")
 	}
 
 	b.WriteString("\n=== HOURLY FORECAST (next 48h) ===\n")
 	for _, h := range w.Hourly {
-		b.WriteString(fmt.Sprintf("%s: %.1f°C, wind %.0f km/h %s, precip %.1fmm, %s\n",
-			h.Time, h.Temperature, h.WindSpeed,
-			degToCompass(h.WindDirection), h.Precipitation, weatherCodeToText(h.WeatherCode)))
+		b.WriteString(fmt.Sprintf("%s: %s, wind %s gusts %s %s, precip %s, %s, vis %s, CAPE %s, LI %s\n",
+			h.Time, optf("%.1f°C", h.Temperature), u.optWind(h.WindSpeed, 0), u.optWind(h.WindGusts, 0),
+			optCompass(h.WindDirection), optf("%.1fmm", h.Precipitation), optCondition(h.WeatherCode),
+			optf("%.1f km", scaled(h.Visibility, 0.001)), optf("%.0f J/kg", h.CAPE), optf("%.1f", h.LiftedIndex)))
 	}
+	b.WriteString(FormatForecastConfidence(w))
 
-	if w.Marine.WaveHeight > 0 {
+	if hasMarineData(w) {
+		m := w.Marine
 		b.WriteString("\n=== CURRENT MARINE CONDITIONS ===\n")
-		b.WriteString(fmt.Sprintf("Wave height: %.1fm, direction %s (%d°), period %.1fs\n",
-			w.Marine.WaveHeight, degToCompass(w.Marine.WaveDirection), int(w.Marine.WaveDirection), w.Marine.WavePeriod))
-		b.WriteString(fmt.Sprintf("Wind waves: %.1fm\n", w.Marine.WindWaveHeight))
-		b.WriteString(fmt.Sprintf("Swell: %.1fm from %s, period %.1fs\n",
-			w.Marine.SwellWaveHeight, degToCompass(w.Marine.SwellWaveDir), w.Marine.SwellWavePeriod))
+		b.WriteString(fmt.Sprintf("Wave height: %s, direction %s, period %s\n",
+			u.optHeight(m.WaveHeight), optDirection(m.WaveDirection), optf("%.1fs", m.WavePeriod)))
+		b.WriteString(fmt.Sprintf("Wind waves: %s\n", u.optHeight(m.WindWaveHeight)))
+		b.WriteString(fmt.Sprintf("Swell: %s from %s, period %s\n",
+			u.optHeight(m.SwellWaveHeight), optCompass(m.SwellWaveDir), optf("%.1fs", m.SwellWavePeriod)))
 
 		b.WriteString("\n=== HOURLY MARINE FORECAST (next 48h) ===\n")
 		for _, m := range w.HourlyMarine {
-			b.WriteString(fmt.Sprintf("%s: waves %.1fm %s period %.1fs, swell %.1fm %s\n",
-				m.Time, m.WaveHeight, degToCompass(m.WaveDirection), m.WavePeriod,
-				m.SwellWaveHeight, degToCompass(m.SwellWaveDir)))
+			b.WriteString(fmt.Sprintf("%s: waves %s %s period %s, swell %s %s, current %s to %s, sea level %s\n",
+				m.Time, u.optHeight(m.WaveHeight), optCompass(m.WaveDirection), optf("%.1fs", m.WavePeriod),
+				u.optHeight(m.SwellWaveHeight), optCompass(m.SwellWaveDir),
+				optCurrent(m.CurrentVelocity), optCompass(m.CurrentDirection), optf("%+.2f m", m.SeaLevel)))
 		}
+
+		b.WriteString(FormatTides(w))
 	}
 
+	b.WriteString(FormatDataQuality(w))
+
 	return b.String()
 }
 
+// hasMarineData reports whether the wave model covers the position at all. A flat
+// calm is marine data; a position outside the model has only nil values.
+func hasMarineData(w WeatherData) bool {
+	if w.Marine.WaveHeight != nil {
+		return true
+	}
+	for _, m := range w.HourlyMarine {
+		if m.WaveHeight != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// notAvailable replaces a value the weather models did not report.
+const notAvailable = "n/a"
+
+// ptr returns a pointer to v, for setting optional values.
+func ptr[T any](v T) *T {
+	return &v
+}
+
+// optf formats an optional value, or returns "n/a" if it is missing.
+func optf[T any](format string, v *T) string {
+	if v == nil {
+		return notAvailable
+	}
+	return fmt.Sprintf(format, *v)
+}
+
+// scaled returns v times factor, keeping a missing value missing.
+func scaled(v *float64, factor float64) *float64 {
+	if v == nil {
+		return nil
+	}
+	return ptr(*v * factor)
+}
+
+// optCompass renders an optional direction as a compass point.
+func optCompass(deg *float64) string {
+	if deg == nil {
+		return notAvailable
+	}
+	return degToCompass(*deg)
+}
+
+// optDirection renders an optional direction as a compass point and degrees.
+func optDirection(deg *float64) string {
+	if deg == nil {
+		return notAvailable
+	}
+	return fmt.Sprintf("%s (%d°)", degToCompass(*deg), int(*deg))
+}
+
+// optCondition renders an optional WMO weather code.
+func optCondition(code *int) string {
+	if code == nil {
+		return notAvailable
+	}
+	return weatherCodeToText(*code)
+}
+
+// dateOf returns the date part of an Open-Meteo time such as "2026-03-15T14:00".
+func dateOf(t string) string {
+	date, _, _ := strings.Cut(t, "T")
+	return date
+}
+
+// hourOf returns the time part of an Open-Meteo time such as "2026-03-15T14:00".
+func hourOf(t string) string {
+	_, hour, found := strings.Cut(t, "T")
+	if !found {
+		return t
+	}
+	return hour
+}
+
 func degToCompass(deg float64) string {
 	dirs := []string{"N", "NNE", "NE", "ENE", "E", "ESE", "SE", "SSE",
 		"S", "SSW", "SW", "WSW", "W", "WNW", "NW", "NNW"}
@@ -287,53 +568,72 @@ func weatherCodeToText(code int) string {
 type openMeteoWeatherResponse struct {
 	Timezone string `json:"timezone"`
 	Current  struct {
-		Temperature2m      float64 `json:"temperature_2m"`
-		WindSpeed10m       float64 `json:"wind_speed_10m"`
-		WindDirection10m   float64 `json:"wind_direction_10m"`
-		RelativeHumidity2m int     `json:"relative_humidity_2m"`
-		SurfacePressure    float64 `json:"surface_pressure"`
-		CloudCover         int     `json:"cloud_cover"`
-		Precipitation      float64 `json:"precipitation"`
-		WeatherCode        int     `json:"weather_code"`
+		Time               string   `json:"time"`
+		Temperature2m      *float64 `json:"temperature_2m"`
+		WindSpeed10m       *float64 `json:"wind_speed_10m"`
+		WindDirection10m   *float64 `json:"wind_direction_10m"`
+		WindGusts10m       *float64 `json:"wind_gusts_10m"`
+		RelativeHumidity2m *int     `json:"relative_humidity_2m"`
+		SurfacePressure    *float64 `json:"surface_pressure"`
+		CloudCover         *int     `json:"cloud_cover"`
+		Precipitation      *float64 `json:"precipitation"`
+		WeatherCode        *int     `json:"weather_code"`
+		Visibility         *float64 `json:"visibility"`
 	} `json:"current"`
 	Daily struct {
-		Time                 []string  `json:"time"`
-		Temperature2mMax     []float64 `json:"temperature_2m_max"`
-		Temperature2mMin     []float64 `json:"temperature_2m_min"`
-		PrecipitationSum     []float64 `json:"precipitation_sum"`
-		PrecipitationProbMax []int     `json:"precipitation_probability_max"`
-		WindSpeed10mMax      []float64 `json:"wind_speed_10m_max"`
-		WindDirection10mDom  []float64 `json:"wind_direction_10m_dominant"`
-		WeatherCode          []int     `json:"weather_code"`
+		Time                 []string   `json:"time"`
+		Temperature2mMax     []*float64 `json:"temperature_2m_max"`
+		Temperature2mMin     []*float64 `json:"temperature_2m_min"`
+		PrecipitationSum     []*float64 `json:"precipitation_sum"`
+		PrecipitationProbMax []*int     `json:"precipitation_probability_max"`
+		WindSpeed10mMax      []*float64 `json:"wind_speed_10m_max"`
+		WindGusts10mMax      []*float64 `json:"wind_gusts_10m_max"`
+		WindDirection10mDom  []*float64 `json:"wind_direction_10m_dominant"`
+		WeatherCode          []*int     `json:"weather_code"`
+		Sunrise              []string   `json:"sunrise"`
+		Sunset               []string   `json:"sunset"`
 	} `json:"daily"`
 	Hourly struct {
-		Time            []string  `json:"time"`
-		Temperature2m   []float64 `json:"temperature_2m"`
-		WindSpeed10m    []float64 `json:"wind_speed_10m"`
-		WindDirection10m []float64 `json:"wind_direction_10m"`
-		Precipitation   []float64 `json:"precipitation"`
-		WeatherCode     []int     `json:"weather_code"`
+		Time             []string   `json:"time"`
+		Temperature2m    []*float64 `json:"temperature_2m"`
+		WindSpeed10m     []*float64 `json:"wind_speed_10m"`
+		WindDirection10m []*float64 `json:"wind_direction_10m"`
+		WindGusts10m     []*float64 `json:"wind_gusts_10m"`
+		Precipitation    []*float64 `json:"precipitation"`
+		WeatherCode      []*int     `json:"weather_code"`
+		Visibility       []*float64 `json:"visibility"`
+		CAPE             []*float64 `json:"cape"`
+		LiftedIndex      []*float64 `json:"lifted_index"`
+		PressureMSL      []*float64 `json:"pressure_msl"`
 	} `json:"hourly"`
 }
 
 type openMeteoMarineResponse struct {
 	Current struct {
-		WaveHeight         float64 `json:"wave_height"`
-		WaveDirection      float64 `json:"wave_direction"`
-		WavePeriod         float64 `json:"wave_period"`
-		WindWaveHeight     float64 `json:"wind_wave_height"`
-		SwellWaveHeight    float64 `json:"swell_wave_height"`
-		SwellWaveDirection float64 `json:"swell_wave_direction"`
-		SwellWavePeriod    float64 `json:"swell_wave_period"`
+		WaveHeight            *float64 `json:"wave_height"`
+		WaveDirection         *float64 `json:"wave_direction"`
+		WavePeriod            *float64 `json:"wave_period"`
+		WindWaveHeight        *float64 `json:"wind_wave_height"`
+		SwellWaveHeight       *float64 `json:"swell_wave_height"`
+		SwellWaveDirection    *float64 `json:"swell_wave_direction"`
+		SwellWavePeriod       *float64 `json:"swell_wave_period"`
+		SeaLevelHeightMSL     *float64 `json:"sea_level_height_msl"`
+		OceanCurrentVelocity  *float64 `json:"ocean_current_velocity"`
+		OceanCurrentDirection *float64 `json:"ocean_current_direction"`
+		SeaSurfaceTemperature *float64 `json:"sea_surface_temperature"`
 	} `json:"current"`
 	Hourly struct {
-		Time               []string  `json:"time"`
-		WaveHeight         []float64 `json:"wave_height"`
-		WaveDirection      []float64 `json:"wave_direction"`
-		WavePeriod         []float64 `json:"wave_period"`
-		WindWaveHeight     []float64 `json:"wind_wave_height"`
-		SwellWaveHeight    []float64 `json:"swell_wave_height"`
-		SwellWaveDirection []float64 `json:"swell_wave_direction"`
-		SwellWavePeriod    []float64 `json:"swell_wave_period"`
+		Time                  []string   `json:"time"`
+		WaveHeight            []*float64 `json:"wave_height"`
+		WaveDirection         []*float64 `json:"wave_direction"`
+		WavePeriod            []*float64 `json:"wave_period"`
+		WindWaveHeight        []*float64 `json:"wind_wave_height"`
+		SwellWaveHeight       []*float64 `json:"swell_wave_height"`
+		SwellWaveDirection    []*float64 `json:"swell_wave_direction"`
+		SwellWavePeriod       []*float64 `json:"swell_wave_period"`
+		SeaLevelHeightMSL     []*float64 `json:"sea_level_height_msl"`
+		OceanCurrentVelocity  []*float64 `json:"ocean_current_velocity"`
+		OceanCurrentDirection []*float64 `json:"ocean_current_direction"`
+		SeaSurfaceTemperature []*float64 `json:"sea_surface_temperature"`
 	} `json:"hourly"`
 }